@@ -0,0 +1,211 @@
+// Command tshakutshai-drift records the responses of the TWSE and the TPEx as fixtures with the
+// fingerprints of their schemas, and verifies fresh responses against the fingerprints to detect changes
+// made by the exchanges.
+//
+// Usage:
+//
+//     tshakutshai-drift record [-dir pkg/drift/testdata] [-date 2021-03-24] [-interval 2s] [-probe name]...
+//     tshakutshai-drift verify [-dir pkg/drift/testdata] [-date 2021-03-24] [-interval 2s] [-probe name]...
+//
+// The probes query the day quotes on -date, which defaults to the last weekday, and the daily, monthly and
+// yearly quotes of 2330 and 8044 around it. -probe limits them to the ones named, e.g. twse-day. verify
+// prints the changed paths of each endpoint, e.g.
+//
+//     twse-daily /exchangeReport/STOCK_DAY: ~ $.data[]: array[9] -> array[10]
+//
+// Exit codes:
+//
+//     0  success, or no drifts detected
+//     1  unexpected errors, e.g. failed to connect to the exchanges
+//     2  invalid usage
+//     3  drifts detected
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/drift"
+	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+)
+
+// Commands.
+const (
+	commandRecord = "record"
+	commandVerify = "verify"
+)
+
+// Exit codes.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+	exitDrift = 3
+)
+
+const usage = `Usage: tshakutshai-drift <command> [flags]
+
+Commands:
+  record  save the responses of the probes as fixtures and their fingerprints
+  verify  compare the fingerprints of fresh responses with the recorded ones
+
+Run 'tshakutshai-drift <command> -h' for the flags of a command.
+
+Exit codes:
+  0  success, or no drifts detected
+  1  unexpected errors, e.g. failed to connect to the exchanges
+  2  invalid usage
+  3  drifts detected
+`
+
+// newHttpClient returns the client sending the requests of the probes, replaced in tests.
+var newHttpClient = func(interval time.Duration) tkthttp.Client {
+	return tkthttp.NewThrottledClient(&http.Client{Timeout: time.Second * 30}, interval)
+}
+
+type probeNames []string
+
+func (p *probeNames) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *probeNames) Set(s string) error {
+	*p = append(*p, s)
+	return nil
+}
+
+type options struct {
+	command  string
+	dir      string
+	date     time.Time
+	interval time.Duration
+	probes   []drift.Probe
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	opts, err := parseArgs(args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		fmt.Fprintf(stderr, "tshakutshai-drift: %s\n", err)
+		return exitUsage
+	}
+
+	client := newHttpClient(opts.interval)
+
+	if opts.command == commandRecord {
+		m, err := drift.Record(opts.dir, client, opts.probes)
+		if err != nil {
+			fmt.Fprintf(stderr, "tshakutshai-drift: %s\n", err)
+			return exitError
+		}
+		for _, p := range opts.probes {
+			fmt.Fprintf(stdout, "%s %s: %d paths\n", p.Name, m[p.Name].Path, len(m[p.Name].Fingerprint))
+		}
+		return exitOK
+	}
+
+	drifts, err := drift.Verify(opts.dir, client, opts.probes)
+	if err != nil {
+		fmt.Fprintf(stderr, "tshakutshai-drift: %s\n", err)
+		return exitError
+	}
+	for _, d := range drifts {
+		fmt.Fprintf(stdout, "%s %s:\n", d.Probe, d.Path)
+		for _, c := range d.Changes {
+			fmt.Fprintf(stdout, "  %s\n", c)
+		}
+	}
+	if len(drifts) > 0 {
+		return exitDrift
+	}
+
+	return exitOK
+}
+
+// lastWeekday returns the weekday before now in Taiwan.
+func lastWeekday(now time.Time) time.Time {
+	date := calendar.Today(now).AddDate(0, 0, -1)
+	for date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		date = date.AddDate(0, 0, -1)
+	}
+
+	return date
+}
+
+func parseArgs(args []string, stderr io.Writer) (*options, error) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		fmt.Fprint(stderr, usage)
+		if len(args) == 0 {
+			return nil, errors.New("no command given")
+		}
+		return nil, flag.ErrHelp
+	}
+
+	opts := &options{command: args[0]}
+	switch opts.command {
+	case commandRecord, commandVerify:
+	default:
+		fmt.Fprint(stderr, usage)
+		return nil, fmt.Errorf("unknown command '%s'", opts.command)
+	}
+
+	var date string
+	var names probeNames
+
+	fs := flag.NewFlagSet(opts.command, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.dir, "dir", "pkg/drift/testdata", "directory of the fixtures and "+drift.ManifestFile)
+	fs.StringVar(&date, "date", "", "trading day to query, e.g. 2021-03-24; defaults to the last weekday")
+	fs.DurationVar(&opts.interval, "interval", time.Second*2, "minimum interval between queries to an exchange")
+	fs.Var(&names, "probe", "name of a probe to run, e.g. twse-day; repeatable, defaults to all")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 0 {
+		return nil, fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	opts.date = lastWeekday(time.Now())
+	if date != "" {
+		t, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a date like 2021-03-24", date)
+		}
+		opts.date = calendar.Date(t.Year(), t.Month(), t.Day())
+	}
+
+	probes := drift.DefaultProbes(opts.date)
+	if len(names) == 0 {
+		opts.probes = probes
+		return opts, nil
+	}
+
+	for _, name := range names {
+		found := false
+		for _, p := range probes {
+			if p.Name == name {
+				opts.probes = append(opts.probes, p)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown probe '%s'", name)
+		}
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/drift"
+	"github.com/chehsunliu/tshakutshai/pkg/fakeexchange"
+	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+)
+
+const fixtures = "../../pkg/drift/testdata"
+
+// setup serves the fixtures of pkg/drift to the probes.
+func setup(t *testing.T) *fakeexchange.Server {
+	m, err := drift.LoadManifest(fixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := fakeexchange.NewServer()
+	for _, e := range m {
+		body, err := drift.ReadFixture(fixtures, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType := "application/json"
+		if e.Fingerprint["format"] == "csv" {
+			contentType = "text/csv"
+		}
+		s.Handle(e.Path, nil, fakeexchange.Response{ContentType: contentType, Body: body})
+	}
+
+	orig := newHttpClient
+	newHttpClient = func(time.Duration) tkthttp.Client { return s.HttpClient() }
+	t.Cleanup(func() {
+		newHttpClient = orig
+		s.Close()
+	})
+
+	return s
+}
+
+func TestRun_Verify(t *testing.T) {
+	s := setup(t)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"verify", "-dir", fixtures, "-date", "2021-03-24"}, &stdout, &stderr)
+	assert.Equalf(t, exitOK, code, "%s", stderr.String())
+	assert.Equal(t, "", stdout.String())
+	assert.Equal(t, 8, len(s.Requests()))
+	assert.Equal(t, "20210324", s.Requests()[0].Params.Get("date"))
+
+	s.Handle("/exchangeReport/FMNPTK", nil, fakeexchange.Response{ContentType: "application/json",
+		Body: []byte(`{"stat":"OK","fields":["年度"],"data":[["110"]]}`)})
+
+	stdout.Reset()
+	code = run([]string{"verify", "-dir", fixtures, "-probe", "twse-yearly", "-probe", "twse-day"}, &stdout, &stderr)
+	assert.Equal(t, exitDrift, code)
+	assert.True(t, strings.HasPrefix(stdout.String(), "twse-yearly /exchangeReport/FMNPTK:\n"), stdout.String())
+	assert.Contains(t, stdout.String(), "  ~ $.data[]: array[9] -> array[1]\n")
+	assert.Contains(t, stdout.String(), "  - $.fields2: ")
+	assert.NotContains(t, stdout.String(), "twse-day ")
+}
+
+func TestRun_Record(t *testing.T) {
+	setup(t)
+
+	dir, err := ioutil.TempDir("", "drift")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"record", "-dir", dir, "-probe", "tpex-day"}, &stdout, &stderr)
+	assert.Equalf(t, exitOK, code, "%s", stderr.String())
+	assert.True(t, strings.HasPrefix(stdout.String(), "tpex-day /web/stock/aftertrading/"), stdout.String())
+
+	m, err := drift.LoadManifest(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tpex-day"}, m.Names())
+
+	code = run([]string{"verify", "-dir", dir}, &stdout, &stderr)
+	assert.Equal(t, exitError, code, "the other probes are not recorded")
+}
+
+func TestRun_Usage(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"replay"},
+		{"verify", "-date", "2021/03/24"},
+		{"verify", "-probe", "twse-hourly"},
+		{"record", "extra"},
+	} {
+		var stdout, stderr bytes.Buffer
+		assert.Equalf(t, exitUsage, run(args, &stdout, &stderr), "%v", args)
+	}
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, exitOK, run([]string{"-h"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Usage: tshakutshai-drift")
+}
+
+func TestLastWeekday(t *testing.T) {
+	monday := time.Date(2021, 3, 22, 10, 0, 0, 0, calendar.Location)
+	assert.Equal(t, calendar.Date(2021, 3, 19), lastWeekday(monday))
+	assert.Equal(t, calendar.Date(2021, 3, 22), lastWeekday(monday.AddDate(0, 0, 1)))
+
+	// 2021-03-22 20:00 in UTC is 2021-03-23 in Taiwan.
+	assert.Equal(t, calendar.Date(2021, 3, 22), lastWeekday(time.Date(2021, 3, 22, 20, 0, 0, 0, time.UTC)))
+}
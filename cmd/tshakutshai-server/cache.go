@@ -0,0 +1,141 @@
+package main
+
+import (
+	"container/list"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// errPanicked is returned to the gets waiting for a fetch that panicked.
+var errPanicked = errors.New("fetch panicked")
+
+type cacheEntry struct {
+	key   string
+	value interface{}
+	// expires is zero if the entry never expires.
+	expires time.Time
+}
+
+// call is a fetch in flight, shared by the gets of the same key.
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// cache keeps the results of upstream queries in memory, evicting the least recently used entries beyond
+// its size. Errors are never cached, and concurrent gets of the same key share a single fetch.
+type cache struct {
+	mutex sync.Mutex
+	ttl   time.Duration
+	size  int
+	// lru holds *cacheEntry, the most recently used at the front.
+	lru      *list.List
+	entries  map[string]*list.Element
+	inflight map[string]*call
+	now      func() time.Time
+}
+
+// newCache returns a cache of at most size entries, or unbounded if size is not positive.
+func newCache(ttl time.Duration, size int) *cache {
+	return &cache{
+		ttl:      ttl,
+		size:     size,
+		lru:      list.New(),
+		entries:  map[string]*list.Element{},
+		inflight: map[string]*call{},
+		now:      time.Now,
+	}
+}
+
+// get returns the cached value of key, or calls fetch and caches its result. The result is cached
+// forever if permanent is true, e.g. quotes of past months, which never change; otherwise, for the TTL
+// of the cache. Empty results are always cached for the TTL only, since the exchanges reply nothing
+// rather than an error on failures now and then.
+func (c *cache) get(key string, permanent bool, fetch func() (interface{}, error)) (interface{}, error) {
+	c.mutex.Lock()
+	if value, ok := c.lookup(key); ok {
+		c.mutex.Unlock()
+		return value, nil
+	}
+	if cl, ok := c.inflight[key]; ok {
+		c.mutex.Unlock()
+		<-cl.done
+		return cl.value, cl.err
+	}
+	cl := &call{done: make(chan struct{})}
+	c.inflight[key] = cl
+	c.mutex.Unlock()
+
+	// A panicking fetch is recovered by the server, so the waiters must be released anyway.
+	defer func() {
+		c.mutex.Lock()
+		delete(c.inflight, key)
+		if cl.err == nil {
+			c.add(key, cl.value, permanent && !isEmpty(cl.value))
+		}
+		c.mutex.Unlock()
+		close(cl.done)
+	}()
+
+	cl.err = errPanicked
+	cl.value, cl.err = fetch()
+	if cl.err != nil {
+		cl.value = nil
+	}
+
+	return cl.value, cl.err
+}
+
+// lookup returns the value of key if cached and not expired. c.mutex must be held.
+func (c *cache) lookup(key string) (interface{}, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return entry.value, true
+}
+
+// add caches value of key and evicts the least recently used entries beyond the size. c.mutex must be
+// held.
+func (c *cache) add(key string, value interface{}, permanent bool) {
+	entry := &cacheEntry{key: key, value: value}
+	if !permanent {
+		entry.expires = c.now().Add(c.ttl)
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.lru.PushFront(entry)
+	}
+
+	for c.size > 0 && c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// isEmpty tells if v is an empty map or slice.
+func isEmpty(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice:
+		return rv.Len() == 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_Evict(t *testing.T) {
+	c := newCache(time.Minute, 2)
+
+	calls := 0
+	fetch := func() (interface{}, error) {
+		calls++
+		return []int{calls}, nil
+	}
+
+	for _, key := range []string{"a", "b", "a", "c", "a", "b"} {
+		_, err := c.get(key, true, fetch)
+		assert.Nil(t, err)
+	}
+
+	// b is evicted by c as the least recently used, while a is kept.
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, 2, c.lru.Len())
+	assert.Equal(t, 2, len(c.entries))
+}
+
+func TestCache_EmptyResultsExpire(t *testing.T) {
+	now := time.Date(2021, 3, 24, 0, 0, 0, 0, time.UTC)
+	c := newCache(time.Minute, 0)
+	c.now = func() time.Time { return now }
+
+	calls := 0
+	fetch := func() (interface{}, error) {
+		calls++
+		return map[string]int{}, nil
+	}
+
+	_, _ = c.get("a", true, fetch)
+	_, _ = c.get("a", true, fetch)
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(time.Minute)
+	_, _ = c.get("a", true, fetch)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCache_ShareFetch(t *testing.T) {
+	c := newCache(time.Minute, 0)
+
+	release := make(chan struct{})
+	var calls int32
+	errBan := errors.New("banned")
+
+	var ready, done sync.WaitGroup
+	results := make([]error, 5)
+	for i := range results {
+		ready.Add(1)
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			ready.Done()
+			_, results[i] = c.get("a", true, func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return nil, errBan
+			})
+		}(i)
+	}
+
+	// Give the gets time to wait for the fetch in flight.
+	ready.Wait()
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	done.Wait()
+
+	assert.Equal(t, int32(1), calls)
+	for _, err := range results {
+		assert.True(t, errors.Is(err, errBan))
+	}
+
+	// Errors are not cached.
+	_, err := c.get("a", true, func() (interface{}, error) { return []int{}, nil })
+	assert.Nil(t, err)
+}
@@ -0,0 +1,95 @@
+// Command tshakutshai-server serves quotes of the TWSE and the TPEx over HTTP, so that services in any
+// language can share one throttled and cached crawler instead of scraping on their own.
+//
+// Usage:
+//
+//     tshakutshai-server [-addr :8080] [-interval 2s] [-cache-ttl 10m] [-cache-size 10000]
+//                        [-twse-url url] [-tpex-url url] [-v]
+//
+// Endpoints:
+//
+//     GET /v1/{market}/day/{date}                          quotes of all the stocks on a date
+//     GET /v1/{market}/{code}/daily?from={date}&to={date}  daily quotes of a stock in a date range
+//     GET /v1/{market}/{code}/monthly?from={year}&to={year} monthly quotes of a stock in a year range
+//     GET /v1/{market}/{code}/yearly                       yearly quotes of a stock of all time
+//
+// market is either twse or tpex, and dates are like 2021-03-24. Quotes are returned as a JSON array in
+// the schema of the quote package. Errors are returned as {"error": "..."} with the status codes:
+//
+//     400  invalid parameters
+//     404  unknown paths or markets
+//     429  banned by the exchange for querying too frequently
+//     502  failed to connect to the exchange, or unexpected responses from it
+//     503  the exchange is under maintenance
+//
+// Quotes of past periods are cached until evicted; others, and empty results of any period, are cached for
+// -cache-ttl. At most -cache-size results are cached, the least recently used evicted first, and concurrent
+// requests for the same result share a single query to the exchange. -twse-url and -tpex-url replace the
+// base URLs of the exchanges, e.g. to query through a proxy. -v logs every request to the exchanges with
+// its throttling, status and size.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	interval := flag.Duration("interval", time.Second*2, "minimum interval between queries to an exchange")
+	cacheTTL := flag.Duration("cache-ttl", time.Minute*10, "how long quotes of the current period are cached")
+	cacheSize := flag.Int("cache-size", 10000, "maximum number of results cached, or unbounded if not positive")
+	twseURL := flag.String("twse-url", twse.DefaultBaseURL, "base URL of the TWSE")
+	tpexURL := flag.String("tpex-url", tpex.DefaultBaseURL, "base URL of the TPEx")
+	verbose := flag.Bool("v", false, "log every request to the exchanges")
+	flag.Parse()
+
+	logger := log.New(os.Stderr, "tshakutshai-server: ", log.LstdFlags)
+
+	var observer tkthttp.Observer
+	if *verbose {
+		observer = tkthttp.NewLogObserver(logger)
+	}
+
+	s := newServer(map[string]exchange{
+		marketTWSE: twse.NewClient(*interval, twse.WithBaseURL(*twseURL), twse.WithObserver(observer)),
+		marketTPEx: tpex.NewClient(*interval, tpex.WithBaseURL(*tpexURL), tpex.WithObserver(observer)),
+	}, *cacheTTL, *cacheSize, logger)
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           s,
+		ReadHeaderTimeout: time.Second * 10,
+		ErrorLog:          logger,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Shutdown makes ListenAndServe return at once, so wait for the requests in flight here.
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	logger.Printf("listening on %s", *addr)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatal(err)
+	}
+	<-drained
+}
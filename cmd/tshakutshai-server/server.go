@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// Names of the markets in the paths.
+const (
+	marketTWSE = "twse"
+	marketTPEx = "tpex"
+)
+
+// Limits of the ranges of a request, so that a single request cannot flood the upstream.
+const (
+	maxMonths = 36
+	maxYears  = 10
+)
+
+// exchange is implemented by both twse.Client and tpex.Client.
+type exchange interface {
+	FetchDayQuotes(date time.Time) (map[string]quote.Day, error)
+	FetchDailyQuotes(code string, year int, month time.Month) ([]quote.Day, error)
+	FetchMonthlyQuotes(code string, year int) ([]quote.Monthly, error)
+	FetchYearlyQuotes(code string) ([]quote.Yearly, error)
+}
+
+// server serves the quotes of the exchanges. Each exchange is shared by all the requests, so the
+// queries to an exchange are throttled together.
+type server struct {
+	exchanges map[string]exchange
+	cache     *cache
+	logger    *log.Logger
+	now       func() time.Time
+}
+
+func newServer(exchanges map[string]exchange, cacheTTL time.Duration, cacheSize int, logger *log.Logger) *server {
+	return &server{exchanges: exchanges, cache: newCache(cacheTTL, cacheSize), logger: logger, now: time.Now}
+}
+
+// httpError is an error with the status code to respond.
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, a ...interface{}) error {
+	return &httpError{http.StatusBadRequest, fmt.Sprintf(format, a...)}
+}
+
+func notFound(path string) error {
+	return &httpError{http.StatusNotFound, fmt.Sprintf("no route for '%s'", path)}
+}
+
+func statusOf(err error) int {
+	var httpError *httpError
+	var netError net.Error
+
+	switch {
+	case errors.As(err, &httpError):
+		return httpError.status
+	case errors.Is(err, exchangeerr.ErrQuotaExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, exchangeerr.ErrMaintenance):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, exchangeerr.ErrConnection), errors.As(err, &netError),
+		errors.Is(err, exchangeerr.ErrUnexpectedContent), errors.Is(err, exchangeerr.ErrParse),
+		errors.Is(err, errPanicked):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		// The clients panic on responses they cannot parse, which are likely due to API changes.
+		if v := recover(); v != nil {
+			s.logger.Printf("%s %s: panic: %v", r.Method, r.URL, v)
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "unexpected response from upstream"})
+		}
+	}()
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	v, err := s.route(r)
+	if err != nil {
+		status := statusOf(err)
+		if status >= 500 {
+			s.logger.Printf("%s %s: %s", r.Method, r.URL, err)
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// route dispatches the paths below:
+//
+//     /v1/{market}/day/{date}
+//     /v1/{market}/{code}/daily?from={date}&to={date}
+//     /v1/{market}/{code}/monthly?from={year}&to={year}
+//     /v1/{market}/{code}/yearly
+func (s *server) route(r *http.Request) (interface{}, error) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) != 4 || segments[0] != "v1" {
+		return nil, notFound(r.URL.Path)
+	}
+
+	market := segments[1]
+	e, ok := s.exchanges[market]
+	if !ok {
+		return nil, notFound(r.URL.Path)
+	}
+
+	if segments[2] == "day" {
+		date, err := time.Parse("2006-01-02", segments[3])
+		if err != nil {
+			return nil, badRequest("'%s' is not a date like 2021-03-24", segments[3])
+		}
+		return s.day(market, e, calendar.Date(date.Year(), date.Month(), date.Day()))
+	}
+
+	code, query := segments[2], r.URL.Query()
+	switch segments[3] {
+	case "daily":
+		from, to, err := parseRange(query.Get("from"), query.Get("to"), "2006-01-02")
+		if err != nil {
+			return nil, err
+		}
+		return s.daily(market, e, code, from, to)
+	case "monthly":
+		from, to, err := parseRange(query.Get("from"), query.Get("to"), "2006")
+		if err != nil {
+			return nil, err
+		}
+		return s.monthly(market, e, code, from.Year(), to.Year())
+	case "yearly":
+		return s.yearly(market, e, code)
+	default:
+		return nil, notFound(r.URL.Path)
+	}
+}
+
+func parseRange(rawFrom, rawTo, layout string) (time.Time, time.Time, error) {
+	if rawFrom == "" || rawTo == "" {
+		return time.Time{}, time.Time{}, badRequest("both from and to are required")
+	}
+
+	from, err := time.Parse(layout, rawFrom)
+	if err != nil {
+		return time.Time{}, time.Time{}, badRequest("from '%s' is not in the format %s", rawFrom, layout)
+	}
+
+	to, err := time.Parse(layout, rawTo)
+	if err != nil {
+		return time.Time{}, time.Time{}, badRequest("to '%s' is not in the format %s", rawTo, layout)
+	}
+
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, badRequest("to is before from")
+	}
+
+	return calendar.Normalize(from), calendar.Normalize(to), nil
+}
+
+func (s *server) today() time.Time {
+	return calendar.Today(s.now())
+}
+
+func (s *server) day(market string, e exchange, date time.Time) ([]quote.Day, error) {
+	key := fmt.Sprintf("%s/day/%s", market, date.Format("2006-01-02"))
+	v, err := s.cache.get(key, date.Before(s.today()), func() (interface{}, error) {
+		return e.FetchDayQuotes(date)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m := v.(map[string]quote.Day)
+	qs := make([]quote.Day, 0, len(m))
+	for _, q := range m {
+		qs = append(qs, q)
+	}
+	sort.Slice(qs, func(i, j int) bool { return qs[i].Code < qs[j].Code })
+
+	return qs, nil
+}
+
+func (s *server) daily(market string, e exchange, code string, from, to time.Time) ([]quote.Day, error) {
+	first := calendar.Date(from.Year(), from.Month(), 1)
+	if n := (to.Year()-first.Year())*12 + int(to.Month()-first.Month()) + 1; n > maxMonths {
+		return nil, badRequest("the range spans %d months, more than %d", n, maxMonths)
+	}
+
+	qs := make([]quote.Day, 0)
+	for month := first; !month.After(to); month = month.AddDate(0, 1, 0) {
+		m := month
+		key := fmt.Sprintf("%s/daily/%s/%s", market, code, m.Format("2006-01"))
+		v, err := s.cache.get(key, m.AddDate(0, 1, 0).Before(s.today()), func() (interface{}, error) {
+			return e.FetchDailyQuotes(code, m.Year(), m.Month())
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, q := range v.([]quote.Day) {
+			if !q.Date.Before(from) && !q.Date.After(to) {
+				qs = append(qs, q)
+			}
+		}
+	}
+
+	return qs, nil
+}
+
+func (s *server) monthly(market string, e exchange, code string, from, to int) ([]quote.Monthly, error) {
+	if n := to - from + 1; n > maxYears {
+		return nil, badRequest("the range spans %d years, more than %d", n, maxYears)
+	}
+
+	qs := make([]quote.Monthly, 0)
+	for year := from; year <= to; year++ {
+		y := year
+		key := market + "/monthly/" + code + "/" + strconv.Itoa(y)
+		v, err := s.cache.get(key, y < s.today().Year(), func() (interface{}, error) {
+			return e.FetchMonthlyQuotes(code, y)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qs = append(qs, v.([]quote.Monthly)...)
+	}
+
+	return qs, nil
+}
+
+func (s *server) yearly(market string, e exchange, code string) ([]quote.Yearly, error) {
+	v, err := s.cache.get(market+"/yearly/"+code, false, func() (interface{}, error) {
+		return e.FetchYearlyQuotes(code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Respond with an empty array rather than null for unknown codes.
+	qs := v.([]quote.Yearly)
+	if qs == nil {
+		qs = []quote.Yearly{}
+	}
+
+	return qs, nil
+}
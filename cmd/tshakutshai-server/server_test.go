@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+type fakeExchange struct {
+	day     map[string]map[string]quote.Day
+	daily   map[string][]quote.Day
+	monthly map[string][]quote.Monthly
+	yearly  map[string][]quote.Yearly
+	err     error
+	panic   bool
+	calls   []string
+}
+
+func (e *fakeExchange) call(key string) error {
+	e.calls = append(e.calls, key)
+	if e.panic {
+		panic("unexpected field")
+	}
+	return e.err
+}
+
+func (e *fakeExchange) FetchDayQuotes(date time.Time) (map[string]quote.Day, error) {
+	key := date.Format("20060102")
+	if err := e.call("day/" + key); err != nil {
+		return nil, err
+	}
+	return e.day[key], nil
+}
+
+func (e *fakeExchange) FetchDailyQuotes(code string, year int, month time.Month) ([]quote.Day, error) {
+	key := code + "/" + calendar.Date(year, month, 1).Format("200601")
+	if err := e.call("daily/" + key); err != nil {
+		return nil, err
+	}
+	return e.daily[key], nil
+}
+
+func (e *fakeExchange) FetchMonthlyQuotes(code string, year int) ([]quote.Monthly, error) {
+	key := code + "/" + calendar.Date(year, 1, 1).Format("2006")
+	if err := e.call("monthly/" + key); err != nil {
+		return nil, err
+	}
+	return e.monthly[key], nil
+}
+
+func (e *fakeExchange) FetchYearlyQuotes(code string) ([]quote.Yearly, error) {
+	if err := e.call("yearly/" + code); err != nil {
+		return nil, err
+	}
+	return e.yearly[code], nil
+}
+
+func dayQuote(code string, date time.Time, close string) quote.Day {
+	p := price.MustParse(close)
+	return quote.Day{Code: code, Date: date, Open: p, High: p, Low: p, Close: p, Volume: 1000, Transactions: 3}
+}
+
+func newTestServer(e *fakeExchange, now time.Time) *server {
+	s := newServer(map[string]exchange{marketTWSE: e}, time.Minute, 0, log.New(ioutil.Discard, "", 0))
+	s.now = func() time.Time { return now }
+	s.cache.now = s.now
+	return s
+}
+
+func get(s http.Handler, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestServer_Day(t *testing.T) {
+	date := calendar.Date(2021, time.March, 24)
+	e := &fakeExchange{day: map[string]map[string]quote.Day{"20210324": {
+		"2330": dayQuote("2330", date, "576.00"),
+		"0050": dayQuote("0050", date, "131.50"),
+	}}}
+	s := newTestServer(e, time.Date(2021, 4, 1, 12, 0, 0, 0, calendar.Location))
+
+	w := get(s, "/v1/twse/day/2021-03-24")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	var qs []quote.Day
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &qs))
+	assert.Equal(t, []quote.Day{dayQuote("0050", date, "131.50"), dayQuote("2330", date, "576.00")}, qs)
+	assert.Contains(t, w.Body.String(), `"date":"2021-03-24"`)
+
+	// Past days are cached permanently.
+	s.cache.now = func() time.Time { return time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC) }
+	assert.Equal(t, http.StatusOK, get(s, "/v1/twse/day/2021-03-24").Code)
+	assert.Equal(t, []string{"day/20210324"}, e.calls)
+}
+
+func TestServer_Daily(t *testing.T) {
+	e := &fakeExchange{daily: map[string][]quote.Day{
+		"2330/202102": {
+			dayQuote("2330", calendar.Date(2021, 2, 25), "635.00"),
+			dayQuote("2330", calendar.Date(2021, 2, 26), "606.00"),
+		},
+		"2330/202103": {
+			dayQuote("2330", calendar.Date(2021, 3, 1), "601.00"),
+			dayQuote("2330", calendar.Date(2021, 3, 2), "612.00"),
+		},
+	}}
+	s := newTestServer(e, time.Date(2021, 3, 2, 15, 0, 0, 0, calendar.Location))
+
+	w := get(s, "/v1/twse/2330/daily?from=2021-02-26&to=2021-03-01")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var qs []quote.Day
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &qs))
+	assert.Equal(t, []quote.Day{
+		dayQuote("2330", calendar.Date(2021, 2, 26), "606.00"),
+		dayQuote("2330", calendar.Date(2021, 3, 1), "601.00"),
+	}, qs)
+
+	// February has passed and is cached permanently, while March expires after the TTL.
+	s.cache.now = func() time.Time { return time.Date(2021, 3, 2, 16, 0, 0, 0, calendar.Location) }
+	assert.Equal(t, http.StatusOK, get(s, "/v1/twse/2330/daily?from=2021-02-26&to=2021-03-02").Code)
+	assert.Equal(t, []string{"daily/2330/202102", "daily/2330/202103", "daily/2330/202103"}, e.calls)
+}
+
+func TestServer_MonthlyAndYearly(t *testing.T) {
+	e := &fakeExchange{
+		monthly: map[string][]quote.Monthly{
+			"2454/2019": {{Code: "2454", Year: 2019, Month: time.December}},
+			"2454/2020": {{Code: "2454", Year: 2020, Month: time.January}},
+		},
+		yearly: map[string][]quote.Yearly{
+			"0050": {{Code: "0050", Year: 2020, DateOfHigh: calendar.Date(2020, 12, 31)}},
+		},
+	}
+	s := newTestServer(e, time.Date(2021, 3, 24, 0, 0, 0, 0, calendar.Location))
+
+	w := get(s, "/v1/twse/2454/monthly?from=2019&to=2020")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[
+		{"code":"2454","year":2019,"month":12,"high":0,"low":0,"volume":0,"transactions":0,"value":0},
+		{"code":"2454","year":2020,"month":1,"high":0,"low":0,"volume":0,"transactions":0,"value":0}
+	]`, w.Body.String())
+
+	w = get(s, "/v1/twse/0050/yearly")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"date_of_high":"2020-12-31"`)
+
+	w = get(s, "/v1/twse/9999/yearly")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[]\n", w.Body.String())
+}
+
+func TestServer_Errors(t *testing.T) {
+	now := time.Date(2021, 3, 24, 0, 0, 0, 0, calendar.Location)
+
+	for _, tc := range []struct {
+		exchange *fakeExchange
+		method   string
+		target   string
+		status   int
+	}{
+		{&fakeExchange{}, http.MethodGet, "/v1/twse/day/20210324", http.StatusBadRequest},
+		{&fakeExchange{}, http.MethodGet, "/v1/twse/2330/daily?from=2021-03-01", http.StatusBadRequest},
+		{&fakeExchange{}, http.MethodGet, "/v1/twse/2330/daily?from=2021-03-01&to=2021-02-01", http.StatusBadRequest},
+		{&fakeExchange{}, http.MethodGet, "/v1/twse/2330/daily?from=2018-01-01&to=2021-03-01", http.StatusBadRequest},
+		{&fakeExchange{}, http.MethodGet, "/v1/twse/2330/monthly?from=2000&to=2020", http.StatusBadRequest},
+		{&fakeExchange{}, http.MethodGet, "/v1/nyse/2330/yearly", http.StatusNotFound},
+		{&fakeExchange{}, http.MethodGet, "/v1/twse/2330/weekly", http.StatusNotFound},
+		{&fakeExchange{}, http.MethodGet, "/v2/twse/2330/yearly", http.StatusNotFound},
+		{&fakeExchange{}, http.MethodPost, "/v1/twse/2330/yearly", http.StatusMethodNotAllowed},
+		{&fakeExchange{err: &twse.QuotaExceededError{Message: "banned"}}, http.MethodGet, "/v1/twse/2330/yearly", http.StatusTooManyRequests},
+		{&fakeExchange{err: &twse.ConnectionError{Message: "refused"}}, http.MethodGet, "/v1/twse/2330/yearly", http.StatusBadGateway},
+		{&fakeExchange{err: &tpex.QuotaExceededError{Message: "banned"}}, http.MethodGet, "/v1/twse/2330/yearly", http.StatusTooManyRequests},
+		{&fakeExchange{err: &tpex.UnexpectedContentError{Message: "maintenance", Maintenance: true}}, http.MethodGet, "/v1/twse/2330/yearly", http.StatusServiceUnavailable},
+		{&fakeExchange{err: &twse.ParseError{Message: "truncated"}}, http.MethodGet, "/v1/twse/2330/yearly", http.StatusBadGateway},
+		{&fakeExchange{err: errors.New("boom")}, http.MethodGet, "/v1/twse/2330/yearly", http.StatusInternalServerError},
+		{&fakeExchange{panic: true}, http.MethodGet, "/v1/twse/2330/yearly", http.StatusBadGateway},
+	} {
+		s := newTestServer(tc.exchange, now)
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(tc.method, tc.target, nil))
+		assert.Equalf(t, tc.status, w.Code, "%s %s", tc.method, tc.target)
+
+		var body map[string]string
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.NotEmptyf(t, body["error"], "%s %s", tc.method, tc.target)
+	}
+}
+
+func TestServer_ErrorsAreNotCached(t *testing.T) {
+	e := &fakeExchange{err: &twse.QuotaExceededError{Message: "banned"}}
+	s := newTestServer(e, time.Date(2021, 3, 24, 0, 0, 0, 0, calendar.Location))
+
+	assert.Equal(t, http.StatusTooManyRequests, get(s, "/v1/twse/2330/yearly").Code)
+
+	e.err = nil
+	assert.Equal(t, http.StatusOK, get(s, "/v1/twse/2330/yearly").Code)
+	assert.Equal(t, 2, len(e.calls))
+}
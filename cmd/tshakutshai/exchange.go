@@ -0,0 +1,192 @@
+package main
+
+import (
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// Names of the markets accepted by the -market flag.
+const (
+	marketTWSE = "twse"
+	marketTPEx = "tpex"
+	marketAuto = "auto"
+)
+
+// row is a quote of any granularity tagged with the market it comes from.
+type row struct {
+	Market       string
+	Code         string
+	Name         string
+	Date         time.Time
+	Open         price.Price
+	High         price.Price
+	Low          price.Price
+	Close        price.Price
+	Volume       uint64
+	Transactions uint64
+	Value        uint64
+	DateOfHigh   time.Time
+	DateOfLow    time.Time
+}
+
+func dayRows(market string, qs []quote.Day, err error) ([]row, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]row, len(qs))
+	for i, q := range qs {
+		rows[i] = row{
+			Market:       market,
+			Code:         q.Code,
+			Name:         q.Name,
+			Date:         q.Date,
+			Open:         q.Open,
+			High:         q.High,
+			Low:          q.Low,
+			Close:        q.Close,
+			Volume:       q.Volume,
+			Transactions: q.Transactions,
+			Value:        q.Value,
+		}
+	}
+
+	return rows, nil
+}
+
+func monthlyRows(market string, qs []quote.Monthly, err error) ([]row, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]row, len(qs))
+	for i, q := range qs {
+		rows[i] = row{
+			Market:       market,
+			Code:         q.Code,
+			Date:         q.Date(),
+			High:         q.High,
+			Low:          q.Low,
+			Volume:       q.Volume,
+			Transactions: q.Transactions,
+			Value:        q.Value,
+		}
+	}
+
+	return rows, nil
+}
+
+func yearlyRows(market string, qs []quote.Yearly, err error) ([]row, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]row, len(qs))
+	for i, q := range qs {
+		rows[i] = row{
+			Market:       market,
+			Code:         q.Code,
+			Date:         q.Date(),
+			High:         q.High,
+			Low:          q.Low,
+			Volume:       q.Volume,
+			Transactions: q.Transactions,
+			Value:        q.Value,
+			DateOfHigh:   q.DateOfHigh,
+			DateOfLow:    q.DateOfLow,
+		}
+	}
+
+	return rows, nil
+}
+
+// exchange hides the differences between twse.Client and tpex.Client.
+type exchange interface {
+	Name() string
+	FetchDayQuotes(date time.Time) ([]row, error)
+	FetchDailyQuotes(code string, year int, month time.Month) ([]row, error)
+	FetchMonthlyQuotes(code string, year int) ([]row, error)
+	FetchYearlyQuotes(code string) ([]row, error)
+}
+
+// newExchanges creates the exchanges in the order they are tried with -market=auto. It is a variable
+// so that tests can replace it.
+var newExchanges = func(interval time.Duration) []exchange {
+	return []exchange{
+		&twseExchange{client: twse.NewClient(interval)},
+		&tpexExchange{client: tpex.NewClient(interval)},
+	}
+}
+
+// newCalendar creates the calendar used to skip non-trading days, which fetches the holiday schedule
+// through the TWSE client of exchanges, if any, so that the queries share its throttle. It is a variable
+// so that tests can replace it.
+var newCalendar = func(exchanges []exchange, interval time.Duration) *calendar.Calendar {
+	for _, e := range exchanges {
+		if e, ok := e.(*twseExchange); ok {
+			return calendar.New(e.client)
+		}
+	}
+	return calendar.New(twse.NewClient(interval))
+}
+
+type twseExchange struct {
+	client *twse.Client
+}
+
+func (e *twseExchange) Name() string {
+	return marketTWSE
+}
+
+func (e *twseExchange) FetchDayQuotes(date time.Time) ([]row, error) {
+	qs, err := e.client.FetchDayQuotesInOrder(date)
+	return dayRows(marketTWSE, qs, err)
+}
+
+func (e *twseExchange) FetchDailyQuotes(code string, year int, month time.Month) ([]row, error) {
+	qs, err := e.client.FetchDailyQuotes(code, year, month)
+	return dayRows(marketTWSE, qs, err)
+}
+
+func (e *twseExchange) FetchMonthlyQuotes(code string, year int) ([]row, error) {
+	qs, err := e.client.FetchMonthlyQuotes(code, year)
+	return monthlyRows(marketTWSE, qs, err)
+}
+
+func (e *twseExchange) FetchYearlyQuotes(code string) ([]row, error) {
+	qs, err := e.client.FetchYearlyQuotes(code)
+	return yearlyRows(marketTWSE, qs, err)
+}
+
+type tpexExchange struct {
+	client *tpex.Client
+}
+
+func (e *tpexExchange) Name() string {
+	return marketTPEx
+}
+
+func (e *tpexExchange) FetchDayQuotes(date time.Time) ([]row, error) {
+	qs, err := e.client.FetchDayQuotesInOrder(date)
+	return dayRows(marketTPEx, qs, err)
+}
+
+func (e *tpexExchange) FetchDailyQuotes(code string, year int, month time.Month) ([]row, error) {
+	qs, err := e.client.FetchDailyQuotes(code, year, month)
+	return dayRows(marketTPEx, qs, err)
+}
+
+func (e *tpexExchange) FetchMonthlyQuotes(code string, year int) ([]row, error) {
+	qs, err := e.client.FetchMonthlyQuotes(code, year)
+	return monthlyRows(marketTPEx, qs, err)
+}
+
+func (e *tpexExchange) FetchYearlyQuotes(code string) ([]row, error) {
+	qs, err := e.client.FetchYearlyQuotes(code)
+	return yearlyRows(marketTPEx, qs, err)
+}
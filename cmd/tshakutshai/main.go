@@ -0,0 +1,382 @@
+// Command tshakutshai fetches quotes from the TWSE and the TPEx and prints them as a table, CSV, JSON
+// or newline-delimited JSON.
+//
+// Usage:
+//
+//     tshakutshai <command> [flags]
+//
+// Commands:
+//
+//     day      quotes of all the stocks on a date, or each trading day in a range
+//     daily    daily quotes of a stock in the month of a date, or each month in a range
+//     monthly  monthly quotes of a stock in the year of a date, or each year in a range
+//     yearly   yearly quotes of a stock of all time
+//
+// Examples:
+//
+//     tshakutshai day -date 2021-03-24 -code 2330
+//     tshakutshai daily -market auto -code 8044 -from 2021-01 -to 2021-03 -format csv
+//     tshakutshai yearly -market twse -code 0050 -format json
+//
+// CSV and NDJSON follow the schema of the quoteio package, and JSON is the encoding of the quote package.
+// Only tables show the markets of the quotes.
+//
+// Exit codes:
+//
+//     0  success
+//     1  unexpected errors
+//     2  invalid usage
+//     3  banned by the exchange for querying too frequently
+//     4  failed to connect to the exchange
+//     5  no data matched
+//     6  the exchange is under maintenance
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+)
+
+// Commands.
+const (
+	commandDay     = "day"
+	commandDaily   = "daily"
+	commandMonthly = "monthly"
+	commandYearly  = "yearly"
+)
+
+// Exit codes.
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitBanned      = 3
+	exitConnection  = 4
+	exitNoData      = 5
+	exitMaintenance = 6
+)
+
+const usage = `Usage: tshakutshai <command> [flags]
+
+Commands:
+  day      quotes of all the stocks on a date, or each trading day in a range
+  daily    daily quotes of a stock in the month of a date, or each month in a range
+  monthly  monthly quotes of a stock in the year of a date, or each year in a range
+  yearly   yearly quotes of a stock of all time
+
+Run 'tshakutshai <command> -h' for the flags of a command.
+
+Exit codes:
+  0  success
+  1  unexpected errors
+  2  invalid usage
+  3  banned by the exchange for querying too frequently
+  4  failed to connect to the exchange
+  5  no data matched
+  6  the exchange is under maintenance
+`
+
+var errNoData = errors.New("no data matched")
+
+type options struct {
+	command  string
+	market   string
+	code     string
+	from     time.Time
+	to       time.Time
+	interval time.Duration
+	format   string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	opts, err := parseArgs(args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		fmt.Fprintf(stderr, "tshakutshai: %s\n", err)
+		return exitUsage
+	}
+
+	rows, err := fetch(opts)
+	if err == nil && len(rows) == 0 {
+		err = errNoData
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "tshakutshai: %s\n", err)
+		return exitCodeOf(err)
+	}
+
+	if err := writeRows(stdout, opts.format, opts.command, rows); err != nil {
+		fmt.Fprintf(stderr, "tshakutshai: failed to write output: %s\n", err)
+		return exitError
+	}
+
+	return exitOK
+}
+
+func exitCodeOf(err error) int {
+	var netError net.Error
+
+	switch {
+	case errors.Is(err, errNoData):
+		return exitNoData
+	case errors.Is(err, exchangeerr.ErrQuotaExceeded):
+		return exitBanned
+	case errors.Is(err, exchangeerr.ErrMaintenance):
+		return exitMaintenance
+	case errors.Is(err, exchangeerr.ErrConnection), errors.As(err, &netError):
+		return exitConnection
+	default:
+		return exitError
+	}
+}
+
+// parseDate accepts dates like 2021-03-24, 2021-03 and 2021. Missing months and days are filled with
+// the first ones if start is true; otherwise the last ones.
+func parseDate(s string, start bool) (time.Time, error) {
+	for _, layout := range []struct {
+		layout string
+		years  int
+		months int
+	}{
+		{"2006-01-02", 0, 0},
+		{"2006-01", 0, 1},
+		{"2006", 1, 0},
+	} {
+		t, err := time.Parse(layout.layout, s)
+		if err != nil {
+			continue
+		}
+
+		if !start && (layout.years != 0 || layout.months != 0) {
+			t = t.AddDate(layout.years, layout.months, -1)
+		}
+
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("'%s' is not a date like 2021-03-24, 2021-03 or 2021", s)
+}
+
+func parseArgs(args []string, stderr io.Writer) (*options, error) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		fmt.Fprint(stderr, usage)
+		if len(args) == 0 {
+			return nil, errors.New("no command given")
+		}
+		return nil, flag.ErrHelp
+	}
+
+	opts := &options{command: args[0]}
+	switch opts.command {
+	case commandDay, commandDaily, commandMonthly, commandYearly:
+	default:
+		fmt.Fprint(stderr, usage)
+		return nil, fmt.Errorf("unknown command '%s'", opts.command)
+	}
+
+	var date, from, to string
+
+	fs := flag.NewFlagSet(opts.command, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.market, "market", marketAuto, "market to query: twse, tpex or auto")
+	fs.StringVar(&opts.code, "code", "", "stock code, e.g. 2330; required except for day, which it filters")
+	if opts.command != commandYearly {
+		fs.StringVar(&date, "date", "", "date to query, e.g. 2021-03-24")
+		fs.StringVar(&from, "from", "", "first date of the range to query, inclusive")
+		fs.StringVar(&to, "to", "", "last date of the range to query, inclusive")
+	}
+	fs.DurationVar(&opts.interval, "interval", time.Second*2, "minimum interval between queries to an exchange")
+	fs.StringVar(&opts.format, "format", formatTable, "output format: table, csv, json or ndjson")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 0 {
+		return nil, fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	switch opts.market {
+	case marketTWSE, marketTPEx, marketAuto:
+	default:
+		return nil, fmt.Errorf("unknown market '%s'", opts.market)
+	}
+
+	switch opts.format {
+	case formatTable, formatCSV, formatJSON, formatNDJSON:
+	default:
+		return nil, fmt.Errorf("unknown format '%s'", opts.format)
+	}
+
+	if opts.code == "" && opts.command != commandDay {
+		return nil, errors.New("-code is required")
+	}
+
+	if opts.command == commandYearly {
+		return opts, nil
+	}
+
+	var err error
+	switch {
+	case date != "" && (from != "" || to != ""):
+		return nil, errors.New("-date cannot be used with -from and -to")
+	case date != "":
+		if opts.from, err = parseDate(date, true); err != nil {
+			return nil, err
+		}
+		opts.to = opts.from
+	case from != "" && to != "":
+		if opts.from, err = parseDate(from, true); err != nil {
+			return nil, err
+		}
+		if opts.to, err = parseDate(to, false); err != nil {
+			return nil, err
+		}
+		if opts.to.Before(opts.from) {
+			return nil, errors.New("-to is before -from")
+		}
+	default:
+		return nil, errors.New("either -date or both -from and -to are required")
+	}
+
+	return opts, nil
+}
+
+func selectExchanges(market string, interval time.Duration) []exchange {
+	exchanges := newExchanges(interval)
+	if market == marketAuto {
+		return exchanges
+	}
+
+	for _, e := range exchanges {
+		if e.Name() == market {
+			return []exchange{e}
+		}
+	}
+
+	panic(fmt.Sprintf("unknown market '%s'", market))
+}
+
+func fetch(opts *options) ([]row, error) {
+	exchanges := selectExchanges(opts.market, opts.interval)
+
+	var rows []row
+	var err error
+
+	switch opts.command {
+	case commandDay:
+		rows, err = fetchDays(opts, exchanges)
+	case commandDaily:
+		rows, err = fetchByCode(exchanges, months(opts.from, opts.to), func(e exchange, t time.Time) ([]row, error) {
+			return e.FetchDailyQuotes(opts.code, t.Year(), t.Month())
+		})
+	case commandMonthly:
+		rows, err = fetchByCode(exchanges, years(opts.from, opts.to), func(e exchange, t time.Time) ([]row, error) {
+			return e.FetchMonthlyQuotes(opts.code, t.Year())
+		})
+	case commandYearly:
+		rows, err = fetchByCode(exchanges, []time.Time{{}}, func(e exchange, _ time.Time) ([]row, error) {
+			return e.FetchYearlyQuotes(opts.code)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rank := map[string]int{}
+	for i, e := range exchanges {
+		rank[e.Name()] = i
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Market != rows[j].Market {
+			return rank[rows[i].Market] < rank[rows[j].Market]
+		}
+		if rows[i].Code != rows[j].Code {
+			return rows[i].Code < rows[j].Code
+		}
+		return rows[i].Date.Before(rows[j].Date)
+	})
+
+	return rows, nil
+}
+
+func fetchDays(opts *options, exchanges []exchange) ([]row, error) {
+	dates := []time.Time{opts.from}
+	if !opts.from.Equal(opts.to) {
+		var err error
+		if dates, err = newCalendar(exchanges, opts.interval).TradingDaysBetween(opts.from, opts.to); err != nil {
+			return nil, err
+		}
+	}
+
+	rows := make([]row, 0)
+	for _, date := range dates {
+		for _, e := range exchanges {
+			rs, err := e.FetchDayQuotes(date)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch day quotes on %s from %s: %w", date.Format("2006-01-02"), e.Name(), err)
+			}
+
+			for _, r := range rs {
+				if opts.code == "" || strings.EqualFold(r.Code, opts.code) {
+					rows = append(rows, r)
+				}
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+// fetchByCode fetches each period from the first exchange having data of the code, which is then
+// used for the rest periods.
+func fetchByCode(exchanges []exchange, periods []time.Time, f func(e exchange, t time.Time) ([]row, error)) ([]row, error) {
+	rows := make([]row, 0)
+	for _, period := range periods {
+		for i, e := range exchanges {
+			rs, err := f(e, period)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch quotes from %s: %w", e.Name(), err)
+			}
+
+			if len(rs) != 0 {
+				rows = append(rows, rs...)
+				exchanges = exchanges[i : i+1]
+				break
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+func months(from, to time.Time) []time.Time {
+	ms := make([]time.Time, 0)
+	for m := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC); !m.After(to); m = m.AddDate(0, 1, 0) {
+		ms = append(ms, m)
+	}
+	return ms
+}
+
+func years(from, to time.Time) []time.Time {
+	ys := make([]time.Time, 0)
+	for y := from.Year(); y <= to.Year(); y++ {
+		ys = append(ys, time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC))
+	}
+	return ys
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+	"github.com/chehsunliu/tshakutshai/pkg/quoteio"
+)
+
+type fakeExchange struct {
+	name  string
+	rows  map[string][]row
+	err   error
+	calls []string
+}
+
+func (e *fakeExchange) Name() string {
+	return e.name
+}
+
+func (e *fakeExchange) lookup(key string) ([]row, error) {
+	e.calls = append(e.calls, key)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.rows[key], nil
+}
+
+func (e *fakeExchange) FetchDayQuotes(date time.Time) ([]row, error) {
+	return e.lookup("day/" + date.Format("20060102"))
+}
+
+func (e *fakeExchange) FetchDailyQuotes(code string, year int, month time.Month) ([]row, error) {
+	return e.lookup("daily/" + code + "/" + time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Format("200601"))
+}
+
+func (e *fakeExchange) FetchMonthlyQuotes(code string, year int) ([]row, error) {
+	return e.lookup("monthly/" + code + "/" + time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC).Format("2006"))
+}
+
+func (e *fakeExchange) FetchYearlyQuotes(code string) ([]row, error) {
+	return e.lookup("yearly/" + code)
+}
+
+func setup(t *testing.T, exchanges ...exchange) {
+	origExchanges, origCalendar := newExchanges, newCalendar
+	newExchanges = func(time.Duration) []exchange { return exchanges }
+	newCalendar = func([]exchange, time.Duration) *calendar.Calendar { return calendar.New(nil) }
+	t.Cleanup(func() { newExchanges, newCalendar = origExchanges, origCalendar })
+}
+
+func dayRow(market, code, name string, date time.Time, close string) row {
+	p := price.MustParse(close)
+	return row{
+		Market: market, Code: code, Name: name, Date: date, Volume: 1000, Transactions: 3,
+		Value: uint64(p.Units() / 10), Open: p, High: p, Low: p, Close: p,
+	}
+}
+
+func quoteOf(r row) quote.Day {
+	return quote.Day{
+		Code: r.Code, Name: r.Name, Date: r.Date, Volume: r.Volume, Transactions: r.Transactions, Value: r.Value,
+		Open: r.Open, High: r.High, Low: r.Low, Close: r.Close,
+	}
+}
+
+func TestRun_Day(t *testing.T) {
+	date := calendar.Date(2021, time.March, 24)
+	setup(t,
+		&fakeExchange{name: marketTWSE, rows: map[string][]row{"day/20210324": {
+			dayRow(marketTWSE, "2330", "台積電", date, "576.00"),
+			dayRow(marketTWSE, "0050", "元大台灣50", date, "131.50"),
+		}}},
+		&fakeExchange{name: marketTPEx, rows: map[string][]row{"day/20210324": {
+			dayRow(marketTPEx, "8044", "網家", date, "82.30"),
+		}}},
+	)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"day", "-date", "2021-03-24", "-format", "csv"}, &stdout, &stderr)
+
+	assert.Equalf(t, exitOK, code, "%s", stderr.String())
+	assert.Equal(t, strings.Join([]string{
+		"code,name,english_name,date,open,high,low,close,volume,transactions,value,date_of_high,date_of_low",
+		"0050,元大台灣50,,2021-03-24,131.50,131.50,131.50,131.50,1000,3,131500,,",
+		"2330,台積電,,2021-03-24,576.00,576.00,576.00,576.00,1000,3,576000,,",
+		"8044,網家,,2021-03-24,82.30,82.30,82.30,82.30,1000,3,82300,,",
+		"",
+	}, "\n"), stdout.String())
+
+	// The CSV follows the schema of quoteio.
+	var qs []quote.Day
+	assert.Nil(t, quoteio.ReadCSV(&stdout, &qs))
+	assert.Equal(t, []quote.Day{
+		quoteOf(dayRow(marketTWSE, "0050", "元大台灣50", date, "131.50")),
+		quoteOf(dayRow(marketTWSE, "2330", "台積電", date, "576.00")),
+		quoteOf(dayRow(marketTPEx, "8044", "網家", date, "82.30")),
+	}, qs)
+}
+
+func TestRun_DayRangeSkipsWeekends(t *testing.T) {
+	e := &fakeExchange{name: marketTWSE, rows: map[string][]row{
+		"day/20210326": {dayRow(marketTWSE, "2330", "台積電", calendar.Date(2021, time.March, 26), "590.00")},
+		"day/20210329": {dayRow(marketTWSE, "2330", "台積電", calendar.Date(2021, time.March, 29), "599.00")},
+	}}
+	setup(t, e)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"day", "-market", "twse", "-code", "2330", "-from", "2021-03-26", "-to", "2021-03-29", "-format", "ndjson"}, &stdout, &stderr)
+
+	assert.Equalf(t, exitOK, code, "%s", stderr.String())
+	assert.Equal(t, []string{"day/20210326", "day/20210329"}, e.calls)
+	assert.Equal(t, 2, strings.Count(stdout.String(), "\n"))
+	assert.Contains(t, stdout.String(), `"date":"2021-03-29"`)
+	assert.Contains(t, stdout.String(), `"close":599.00`)
+}
+
+func TestRun_DailyAutoFallsBackToTPEx(t *testing.T) {
+	twseExchange := &fakeExchange{name: marketTWSE}
+	tpexExchange := &fakeExchange{name: marketTPEx, rows: map[string][]row{
+		"daily/8044/202101": {dayRow(marketTPEx, "8044", "網家", calendar.Date(2021, time.January, 4), "90.00")},
+		"daily/8044/202102": {dayRow(marketTPEx, "8044", "網家", calendar.Date(2021, time.February, 1), "86.10")},
+	}}
+	setup(t, twseExchange, tpexExchange)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"daily", "-code", "8044", "-from", "2021-01", "-to", "2021-02", "-format", "json"}, &stdout, &stderr)
+
+	assert.Equalf(t, exitOK, code, "%s", stderr.String())
+	assert.Equal(t, []string{"daily/8044/202101"}, twseExchange.calls)
+	assert.Equal(t, []string{"daily/8044/202101", "daily/8044/202102"}, tpexExchange.calls)
+	assert.Contains(t, stdout.String(), `"name": "網家"`)
+	assert.Contains(t, stdout.String(), `"date": "2021-02-01"`)
+}
+
+func TestRun_Yearly(t *testing.T) {
+	setup(t, &fakeExchange{name: marketTWSE, rows: map[string][]row{
+		"yearly/0050": {{
+			Market:     marketTWSE,
+			Code:       "0050",
+			Date:       calendar.Date(2020, time.January, 1),
+			High:       price.MustParse("122.40"),
+			Low:        price.MustParse("67.25"),
+			DateOfHigh: calendar.Date(2020, time.December, 31),
+			DateOfLow:  calendar.Date(2020, time.March, 19),
+		}},
+	}})
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"yearly", "-code", "0050"}, &stdout, &stderr)
+
+	assert.Equalf(t, exitOK, code, "%s", stderr.String())
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, []string{"MARKET", "CODE", "YEAR", "HIGH", "DATE_OF_HIGH", "LOW", "DATE_OF_LOW", "VOLUME", "TRANSACTIONS", "VALUE"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"twse", "0050", "2020", "122.40", "2020-12-31", "67.25", "2020-03-19", "0", "0", "0"}, strings.Fields(lines[1]))
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected int
+	}{
+		{nil, exitNoData},
+		{&twse.QuotaExceededError{Message: "banned"}, exitBanned},
+		{&twse.ConnectionError{Message: "refused"}, exitConnection},
+		{&tpex.QuotaExceededError{Message: "banned"}, exitBanned},
+		{&tpex.ConnectionError{Message: "refused"}, exitConnection},
+		{&twse.UnexpectedContentError{Message: "maintenance", Maintenance: true}, exitMaintenance},
+		{&tpex.ParseError{Message: "truncated"}, exitError},
+		{errors.New("boom"), exitError},
+	} {
+		setup(t, &fakeExchange{name: marketTWSE, err: tc.err})
+
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"monthly", "-market", "twse", "-code", "2330", "-date", "2021"}, &stdout, &stderr)
+		assert.Equalf(t, tc.expected, code, "%v", tc.err)
+		assert.Equal(t, "", stdout.String())
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	setup(t)
+
+	for _, args := range [][]string{
+		{},
+		{"weekly"},
+		{"daily", "-date", "2021-03-24"},
+		{"daily", "-code", "2330"},
+		{"daily", "-code", "2330", "-date", "2021-03-24", "-from", "2021-03-01"},
+		{"daily", "-code", "2330", "-from", "2021-03-01", "-to", "2021-02-01"},
+		{"daily", "-code", "2330", "-date", "yesterday"},
+		{"day", "-date", "2021-03-24", "-market", "nyse"},
+		{"day", "-date", "2021-03-24", "-format", "xml"},
+	} {
+		var stdout, stderr bytes.Buffer
+		assert.Equalf(t, exitUsage, run(args, &stdout, &stderr), "%v", args)
+	}
+}
+
+type countingClient struct {
+	requests int
+}
+
+func (c *countingClient) Do(req *http.Request) (*http.Response, error) {
+	c.requests++
+	return nil, errors.New("offline")
+}
+
+func TestNewCalendar_SharesTWSEClient(t *testing.T) {
+	client := &countingClient{}
+	exchanges := []exchange{&tpexExchange{client: &tpex.Client{}}, &twseExchange{client: &twse.Client{HttpClient: client}}}
+
+	_, err := newCalendar(exchanges, time.Second).IsTradingDay(calendar.Date(2021, time.March, 24))
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, client.requests, "the holiday schedule should be fetched through the TWSE client")
+}
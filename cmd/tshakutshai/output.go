@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+	"github.com/chehsunliu/tshakutshai/pkg/quoteio"
+)
+
+// Output formats accepted by the -format flag.
+const (
+	formatTable  = "table"
+	formatCSV    = "csv"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+type column struct {
+	header string
+	value  func(r row) string
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func formatUint(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+var (
+	columnMarket       = column{"market", func(r row) string { return r.Market }}
+	columnCode         = column{"code", func(r row) string { return r.Code }}
+	columnName         = column{"name", func(r row) string { return r.Name }}
+	columnDate         = column{"date", func(r row) string { return formatDate(r.Date) }}
+	columnMonth        = column{"month", func(r row) string { return r.Date.Format("2006-01") }}
+	columnYear         = column{"year", func(r row) string { return r.Date.Format("2006") }}
+	columnOpen         = column{"open", func(r row) string { return r.Open.String() }}
+	columnHigh         = column{"high", func(r row) string { return r.High.String() }}
+	columnLow          = column{"low", func(r row) string { return r.Low.String() }}
+	columnClose        = column{"close", func(r row) string { return r.Close.String() }}
+	columnVolume       = column{"volume", func(r row) string { return formatUint(r.Volume) }}
+	columnTransactions = column{"transactions", func(r row) string { return formatUint(r.Transactions) }}
+	columnValue        = column{"value", func(r row) string { return formatUint(r.Value) }}
+	columnDateOfHigh   = column{"date_of_high", func(r row) string { return formatDate(r.DateOfHigh) }}
+	columnDateOfLow    = column{"date_of_low", func(r row) string { return formatDate(r.DateOfLow) }}
+)
+
+// columnsOf returns the columns meaningful to the command, e.g. monthly quotes have no open and close.
+func columnsOf(command string) []column {
+	switch command {
+	case commandDay, commandDaily:
+		return []column{
+			columnMarket, columnCode, columnName, columnDate, columnOpen, columnHigh, columnLow, columnClose,
+			columnVolume, columnTransactions, columnValue,
+		}
+	case commandMonthly:
+		return []column{
+			columnMarket, columnCode, columnMonth, columnHigh, columnLow, columnVolume, columnTransactions,
+			columnValue,
+		}
+	case commandYearly:
+		return []column{
+			columnMarket, columnCode, columnYear, columnHigh, columnDateOfHigh, columnLow, columnDateOfLow,
+			columnVolume, columnTransactions, columnValue,
+		}
+	default:
+		panic(fmt.Sprintf("unknown command '%s'", command))
+	}
+}
+
+// quotesOf converts rows to the quotes of the command, i.e. []quote.Day, []quote.Monthly or []quote.Yearly.
+func quotesOf(command string, rows []row) interface{} {
+	switch command {
+	case commandDay, commandDaily:
+		qs := make([]quote.Day, len(rows))
+		for i, r := range rows {
+			qs[i] = quote.Day{
+				Code:         r.Code,
+				Name:         r.Name,
+				Date:         r.Date,
+				Open:         r.Open,
+				High:         r.High,
+				Low:          r.Low,
+				Close:        r.Close,
+				Volume:       r.Volume,
+				Transactions: r.Transactions,
+				Value:        r.Value,
+			}
+		}
+		return qs
+	case commandMonthly:
+		qs := make([]quote.Monthly, len(rows))
+		for i, r := range rows {
+			qs[i] = quote.Monthly{
+				Code:         r.Code,
+				Year:         r.Date.Year(),
+				Month:        r.Date.Month(),
+				High:         r.High,
+				Low:          r.Low,
+				Volume:       r.Volume,
+				Transactions: r.Transactions,
+				Value:        r.Value,
+			}
+		}
+		return qs
+	case commandYearly:
+		qs := make([]quote.Yearly, len(rows))
+		for i, r := range rows {
+			qs[i] = quote.Yearly{
+				Code:         r.Code,
+				Year:         r.Date.Year(),
+				High:         r.High,
+				Low:          r.Low,
+				DateOfHigh:   r.DateOfHigh,
+				DateOfLow:    r.DateOfLow,
+				Volume:       r.Volume,
+				Transactions: r.Transactions,
+				Value:        r.Value,
+			}
+		}
+		return qs
+	default:
+		panic(fmt.Sprintf("unknown command '%s'", command))
+	}
+}
+
+// writeRows writes rows in format. Tables are meant for reading and tell the markets of the rows, while
+// CSV and NDJSON follow the schema of the quoteio package, so that they can be read back by
+// quoteio.ReadCSV and quoteio.ReadNDJSON, and JSON is the encoding of the quote package, as the HTTP
+// server replies.
+func writeRows(w io.Writer, format, command string, rows []row) error {
+	switch format {
+	case formatTable:
+		return writeTable(w, columnsOf(command), rows)
+	case formatCSV:
+		return quoteio.WriteCSV(w, quotesOf(command, rows))
+	case formatNDJSON:
+		return quoteio.WriteNDJSON(w, quotesOf(command, rows))
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(quotesOf(command, rows))
+	default:
+		return fmt.Errorf("unknown format '%s'", format)
+	}
+}
+
+func writeTable(w io.Writer, columns []column, rows []row) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = strings.ToUpper(c.header)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, r := range rows {
+		values := make([]string, len(columns))
+		for i, c := range columns {
+			values[i] = c.value(r)
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+
+	return tw.Flush()
+}
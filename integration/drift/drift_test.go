@@ -0,0 +1,25 @@
+package drift
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/drift"
+	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+)
+
+var client = tkthttp.NewThrottledClient(&http.Client{Timeout: time.Second * 30}, time.Second*2)
+
+func TestVerify(t *testing.T) {
+	probes := drift.DefaultProbes(calendar.Date(2021, time.March, 29))
+
+	drifts, err := drift.Verify("../../pkg/drift/testdata", client, probes)
+	assert.Nilf(t, err, "%+v", err)
+	for _, d := range drifts {
+		t.Errorf("schema drifted: %s", d)
+	}
+}
@@ -6,10 +6,24 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/cassette"
 	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
 )
 
-var client = tpex.NewClient(time.Millisecond)
+var client = newClient()
+
+// newClient returns a client replaying the responses recorded in testdata/cassettes, and recording the
+// ones missing. Set TSHAKUTSHAI_CASSETTE=record to query the exchange again.
+//
+// The cassettes are made of the responses saved as the fixtures of the client in its testdata, recorded
+// with their bodies and content types only, so the tests query what the fixtures answer.
+func newClient() *tpex.Client {
+	c := tpex.NewClient(time.Millisecond)
+	c.HttpClient = cassette.New("testdata/cassettes", cassette.ModeFromEnv(cassette.ModeAuto), c.HttpClient)
+	return c
+}
 
 func TestClient_FetchDayQuotes(t *testing.T) {
 	date := time.Date(2021, time.March, 30, 0, 0, 0, 0, time.UTC)
@@ -25,10 +39,10 @@ func TestClient_FetchDayQuotes(t *testing.T) {
 	assert.Equal(t, uint64(54_765), q1.Volume)
 	assert.Equal(t, uint64(33), q1.Transactions)
 	assert.Equal(t, uint64(1_062_607), q1.Value)
-	assert.Equal(t, 19.51, q1.High)
-	assert.Equal(t, 19.32, q1.Low)
-	assert.Equal(t, 19.37, q1.Open)
-	assert.Equal(t, 19.49, q1.Close)
+	assert.Equal(t, price.MustParse("19.51"), q1.High)
+	assert.Equal(t, price.MustParse("19.32"), q1.Low)
+	assert.Equal(t, price.MustParse("19.37"), q1.Open)
+	assert.Equal(t, price.MustParse("19.49"), q1.Close)
 
 	q2 := qs["8044"]
 	assert.Equal(t, "8044", q2.Code)
@@ -37,84 +51,60 @@ func TestClient_FetchDayQuotes(t *testing.T) {
 	assert.Equal(t, uint64(766_431), q2.Volume)
 	assert.Equal(t, uint64(698), q2.Transactions)
 	assert.Equal(t, uint64(67_759_797), q2.Value)
-	assert.Equal(t, 90.00, q2.High)
-	assert.Equal(t, 88.00, q2.Low)
-	assert.Equal(t, 89.40, q2.Open)
-	assert.Equal(t, 88.00, q2.Close)
+	assert.Equal(t, price.MustParse("90.00"), q2.High)
+	assert.Equal(t, price.MustParse("88.00"), q2.Low)
+	assert.Equal(t, price.MustParse("89.40"), q2.Open)
+	assert.Equal(t, price.MustParse("88.00"), q2.Close)
 }
 
 func TestClient_FetchDailyQuotes(t *testing.T) {
-	qs, err := client.FetchDailyQuotes("8044", 2020, time.March)
+	qs, err := client.FetchDailyQuotes("8044", 2021, time.February)
 	assert.Nilf(t, err, "%+v", err)
-	assert.Equal(t, 22, len(qs))
-
-	q0 := qs[0]
-	assert.Equal(t, "8044", q0.Code)
-	assert.Equal(t, "網家", q0.Name)
-	assert.Equal(t, "20200302", q0.Date.Format("20060102"))
-	assert.Equal(t, uint64(815_000), q0.Volume)
-	assert.Equal(t, uint64(670), q0.Transactions)
-	assert.Equal(t, uint64(84_369_000), q0.Value)
-	assert.Equal(t, 105.50, q0.High)
-	assert.Equal(t, 101.00, q0.Low)
-	assert.Equal(t, 104.50, q0.Open)
-	assert.Equal(t, 102.50, q0.Close)
-
-	q1 := qs[21]
-	assert.Equal(t, "8044", q1.Code)
-	assert.Equal(t, "網家", q1.Name)
-	assert.Equal(t, "20200331", q1.Date.Format("20060102"))
-	assert.Equal(t, uint64(875_000), q1.Volume)
-	assert.Equal(t, uint64(750), q1.Transactions)
-	assert.Equal(t, uint64(61_249_000), q1.Value)
-	assert.Equal(t, 71.50, q1.High)
-	assert.Equal(t, 69.00, q1.Low)
-	assert.Equal(t, 70.90, q1.Open)
-	assert.Equal(t, 70.30, q1.Close)
+	assert.Equal(t, 13, len(qs))
+
+	q := qs[11]
+	assert.Equal(t, "8044", q.Code)
+	assert.Equal(t, "網家", q.Name)
+	assert.Equal(t, "20210225", q.Date.Format("20060102"))
+	assert.Equal(t, uint64(834_000), q.Volume)
+	assert.Equal(t, uint64(780), q.Transactions)
+	assert.Equal(t, uint64(69_098_000), q.Value)
+	assert.Equal(t, price.MustParse("84.00"), q.High)
+	assert.Equal(t, price.MustParse("82.20"), q.Low)
+	assert.Equal(t, price.MustParse("83.20"), q.Open)
+	assert.Equal(t, price.MustParse("82.30"), q.Close)
 }
 
 func TestClient_FetchMonthlyQuotes(t *testing.T) {
-	code := "006201"
-	qs, err := client.FetchMonthlyQuotes(code, 2011)
+	code := "8044"
+	qs, err := client.FetchMonthlyQuotes(code, 2020)
 	assert.Nilf(t, err, "%+v", err)
 	assert.Equal(t, 12, len(qs))
 
 	q := qs[0]
 	assert.Equal(t, code, q.Code)
-	assert.Equal(t, "", q.Name)
-	assert.Equal(t, "20110101", q.Date.Format("20060102"))
-	assert.Equal(t, uint64(2_419_000), q.Volume)
-	assert.Equal(t, uint64(564), q.Transactions)
-	assert.Equal(t, uint64(36_004_000), q.Value)
-	assert.Equal(t, 14.89, q.High)
-	assert.Equal(t, 14.88, q.Low)
-
-	q = qs[11]
-	assert.Equal(t, code, q.Code)
-	assert.Equal(t, "", q.Name)
-	assert.Equal(t, "20111201", q.Date.Format("20060102"))
-	assert.Equal(t, uint64(5_386_000), q.Volume)
-	assert.Equal(t, uint64(1_277), q.Transactions)
-	assert.Equal(t, uint64(49_869_000), q.Value)
-	assert.Equal(t, 9.90, q.High)
-	assert.Equal(t, 8.62, q.Low)
+	assert.Equal(t, "20200101", q.Date().Format("20060102"))
+	assert.Equal(t, uint64(6_092_000), q.Volume)
+	assert.Equal(t, uint64(5_274), q.Transactions)
+	assert.Equal(t, uint64(564_646_000), q.Value)
+	assert.Equal(t, price.MustParse("96.40"), q.High)
+	assert.Equal(t, price.MustParse("88.70"), q.Low)
 }
 
 func TestClient_FetchYearlyQuotes(t *testing.T) {
-	code := "006201"
+	code := "8044"
 	qs, err := client.FetchYearlyQuotes(code)
 	assert.Nilf(t, err, "%+v", err)
-	assert.Greater(t, len(qs), 4)
+	assert.Equal(t, 17, len(qs))
 
 	q := qs[len(qs)-1]
 	assert.Equal(t, code, q.Code)
-	assert.Equal(t, "", q.Name)
-	assert.Equal(t, "20170101", q.Date.Format("20060102"))
-	assert.Equal(t, uint64(16_438_000), q.Volume)
-	assert.Equal(t, uint64(4_000), q.Transactions)
-	assert.Equal(t, uint64(217_386_000), q.Value)
-	assert.Equal(t, 15.33, q.High)
-	assert.Equal(t, time.Date(2017, time.November, 22, 0, 0, 0, 0, time.UTC), q.DateOfHigh)
-	assert.Equal(t, 10.89, q.Low)
-	assert.Equal(t, time.Date(2017, time.January, 16, 0, 0, 0, 0, time.UTC), q.DateOfLow)
+	assert.Equal(t, "20050101", q.Date().Format("20060102"))
+	assert.Equal(t, uint64(296_356_000), q.Volume)
+	assert.Equal(t, uint64(147_000), q.Transactions)
+	assert.Equal(t, uint64(14_075_258_000), q.Value)
+	assert.Equal(t, price.MustParse("59.70"), q.High)
+	assert.Equal(t, calendar.Date(2005, time.September, 16), q.DateOfHigh)
+	assert.Equal(t, price.MustParse("28.20"), q.Low)
+	assert.Equal(t, calendar.Date(2005, time.January, 24), q.DateOfLow)
 }
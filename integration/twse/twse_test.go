@@ -6,13 +6,26 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/chehsunliu/tshakutshai/pkg/cassette"
 	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
 )
 
-var client = twse.NewClient(time.Second * 2)
+var client = newClient()
+
+// newClient returns a client replaying the responses recorded in testdata/cassettes, and recording the
+// ones missing. Set TSHAKUTSHAI_CASSETTE=record to query the exchange again.
+//
+// The cassettes are made of the responses saved as the fixtures of the client in its testdata, recorded
+// with their bodies and content types only, so the tests query what the fixtures answer.
+func newClient() *twse.Client {
+	c := twse.NewClient(time.Second * 2)
+	c.HttpClient = cassette.New("testdata/cassettes", cassette.ModeFromEnv(cassette.ModeAuto), c.HttpClient)
+	return c
+}
 
 func TestClient_FetchDayQuotes(t *testing.T) {
-	date := time.Date(2021, time.March, 29, 0, 0, 0, 0, time.UTC)
+	date := time.Date(2021, time.March, 24, 0, 0, 0, 0, time.UTC)
 
 	quotes, err := client.FetchDayQuotes(date)
 	assert.Nilf(t, err, "%+v", err)
@@ -21,32 +34,18 @@ func TestClient_FetchDayQuotes(t *testing.T) {
 	q2330 := quotes["2330"]
 	assert.Equal(t, "2330", q2330.Code)
 	assert.Equal(t, "台積電", q2330.Name)
-	assert.Equal(t, "20210329", q2330.Date.Format("20060102"))
-	assert.Equal(t, uint64(40_573_913), q2330.Volume)
-	assert.Equal(t, uint64(44_500), q2330.Transactions)
-	assert.Equal(t, uint64(24_302_039_570), q2330.Value)
-	assert.Equal(t, 602.00, q2330.High)
-	assert.Equal(t, 596.00, q2330.Low)
-	assert.Equal(t, 599.00, q2330.Open)
-	assert.Equal(t, 599.00, q2330.Close)
-	assert.Equal(t, time.Time{}, q2330.DateOfHigh)
-	assert.Equal(t, time.Time{}, q2330.DateOfLow)
-
-	q2454 := quotes["2454"]
-	assert.Equal(t, "聯發科", q2454.Name)
-	assert.Equal(t, 941.00, q2454.Close)
-
-	q00684R := quotes["00684R"]
-	assert.Equal(t, "00684R", q00684R.Code)
-	assert.Equal(t, "期元大美元指反1", q00684R.Name)
-	assert.Equal(t, "20210329", q00684R.Date.Format("20060102"))
-	assert.Equal(t, uint64(0), q00684R.Volume)
-	assert.Equal(t, uint64(0), q00684R.Transactions)
-	assert.Equal(t, uint64(0), q00684R.Value)
-	assert.Equal(t, 0.00, q00684R.High)
-	assert.Equal(t, 0.00, q00684R.Low)
-	assert.Equal(t, 0.00, q00684R.Open)
-	assert.Equal(t, 0.00, q00684R.Close)
+	assert.Equal(t, "20210324", q2330.Date.Format("20060102"))
+	assert.Equal(t, uint64(115_318_351), q2330.Volume)
+	assert.Equal(t, uint64(242_138), q2330.Transactions)
+	assert.Equal(t, uint64(66_559_451_738), q2330.Value)
+	assert.Equal(t, price.MustParse("582.00"), q2330.High)
+	assert.Equal(t, price.MustParse("571.00"), q2330.Low)
+	assert.Equal(t, price.MustParse("571.00"), q2330.Open)
+	assert.Equal(t, price.MustParse("576.00"), q2330.Close)
+
+	q0050 := quotes["0050"]
+	assert.Equal(t, "元大台灣50", q0050.Name)
+	assert.Equal(t, price.MustParse("131.50"), q0050.Close)
 }
 
 func TestClient_FetchDailyQuotes(t *testing.T) {
@@ -60,13 +59,13 @@ func TestClient_FetchDailyQuotes(t *testing.T) {
 	assert.Equal(t, uint64(70_161_939), q0.Volume)
 	assert.Equal(t, uint64(81_346), q0.Transactions)
 	assert.Equal(t, uint64(42_004_241_697), q0.Value)
-	assert.Equal(t, 612.00, q0.High)
-	assert.Equal(t, 587.00, q0.Low)
-	assert.Equal(t, 595.00, q0.Open)
-	assert.Equal(t, 611.00, q0.Close)
+	assert.Equal(t, price.MustParse("612.00"), q0.High)
+	assert.Equal(t, price.MustParse("587.00"), q0.Low)
+	assert.Equal(t, price.MustParse("595.00"), q0.Open)
+	assert.Equal(t, price.MustParse("611.00"), q0.Close)
 
 	dates := make([]string, 0)
-	prices := make([]float64, 0)
+	prices := make([]price.Price, 0)
 
 	for _, q := range quotes {
 		dates = append(dates, q.Date.Format("20060102"))
@@ -88,59 +87,51 @@ func TestClient_FetchDailyQuotes(t *testing.T) {
 		"20210225",
 		"20210226",
 	}, dates)
-	assert.Equal(t, []float64{
-		611.00,
-		632.00,
-		630.00,
-		627.00,
-		632.00,
-		663.00,
-		660.00,
-		652.00,
-		650.00,
-		641.00,
-		625.00,
-		635.00,
-		606.00,
+	assert.Equal(t, []price.Price{
+		price.MustParse("611.00"),
+		price.MustParse("632.00"),
+		price.MustParse("630.00"),
+		price.MustParse("627.00"),
+		price.MustParse("632.00"),
+		price.MustParse("663.00"),
+		price.MustParse("660.00"),
+		price.MustParse("652.00"),
+		price.MustParse("650.00"),
+		price.MustParse("641.00"),
+		price.MustParse("625.00"),
+		price.MustParse("635.00"),
+		price.MustParse("606.00"),
 	}, prices)
 }
 
 func TestClient_FetchMonthlyQuotes(t *testing.T) {
-	quotes, err := client.FetchMonthlyQuotes("0050", 2020)
+	quotes, err := client.FetchMonthlyQuotes("2454", 2020)
 	assert.Nilf(t, err, "%+v", err)
 	assert.Equal(t, 12, len(quotes))
 
-	q12 := quotes[11]
-	assert.Equal(t, "0050", q12.Code)
-	assert.Equal(t, "", q12.Name)
-	assert.Equal(t, "20201201", q12.Date.Format("20060102"))
-	assert.Equal(t, uint64(13_1351_140), q12.Volume)
-	assert.Equal(t, uint64(113_776), q12.Transactions)
-	assert.Equal(t, uint64(15_518_022_408), q12.Value)
-	assert.Equal(t, 122.40, q12.High)
-	assert.Equal(t, 113.35, q12.Low)
-	assert.Equal(t, 0.00, q12.Open)
-	assert.Equal(t, 0.00, q12.Close)
-	assert.Equal(t, time.Time{}, q12.DateOfHigh)
-	assert.Equal(t, time.Time{}, q12.DateOfLow)
+	q4 := quotes[3]
+	assert.Equal(t, "2454", q4.Code)
+	assert.Equal(t, "20200401", q4.Date().Format("20060102"))
+	assert.Equal(t, uint64(218_553_058), q4.Volume)
+	assert.Equal(t, uint64(146_711), q4.Transactions)
+	assert.Equal(t, uint64(80_262_421_295), q4.Value)
+	assert.Equal(t, price.MustParse("415.50"), q4.High)
+	assert.Equal(t, price.MustParse("325.50"), q4.Low)
 }
 
 func TestClient_FetchYearlyQuotes(t *testing.T) {
-	quotes, err := client.FetchYearlyQuotes("2412")
+	quotes, err := client.FetchYearlyQuotes("0050")
 	assert.Nilf(t, err, "%+v", err)
-	assert.Greater(t, len(quotes), 20)
-
-	q := quotes[19]
-	assert.Equal(t, "2412", q.Code)
-	assert.Equal(t, "", q.Name)
-	assert.Equal(t, "20190101", q.Date.Format("20060102"))
-	assert.Equal(t, uint64(1_679_098_996), q.Volume)
-	assert.Equal(t, uint64(765_529), q.Transactions)
-	assert.Equal(t, uint64(185_060_260_891), q.Value)
-	assert.Equal(t, 114.00, q.High)
-	assert.Equal(t, 106.00, q.Low)
-	assert.Equal(t, 0.00, q.Open)
-	assert.Equal(t, 0.00, q.Close)
-	assert.Equal(t, "20191126", q.DateOfHigh.Format("20060102"))
-	assert.Equal(t, "20190222", q.DateOfLow.Format("20060102"))
+	assert.Equal(t, 18, len(quotes))
+
+	q := quotes[17]
+	assert.Equal(t, "0050", q.Code)
+	assert.Equal(t, "20200101", q.Date().Format("20060102"))
+	assert.Equal(t, uint64(2_564_396_277), q.Volume)
+	assert.Equal(t, uint64(1_413_186), q.Transactions)
+	assert.Equal(t, uint64(234_459_163_641), q.Value)
+	assert.Equal(t, price.MustParse("122.40"), q.High)
+	assert.Equal(t, price.MustParse("67.25"), q.Low)
+	assert.Equal(t, "20201231", q.DateOfHigh.Format("20060102"))
+	assert.Equal(t, "20200319", q.DateOfLow.Format("20060102"))
 }
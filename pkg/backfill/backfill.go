@@ -0,0 +1,174 @@
+// Package backfill fetches the day quotes of all stocks on every trading day in a range, for the TWSE,
+// the TPEx or both, and writes them to a Sink, e.g. a store.Store:
+//
+//     job := backfill.NewJob(backfill.StoreSink(s), twse.NewClient(time.Second*2), tpex.NewClient(time.Second))
+//     job.StateFile = "backfill.json"
+//     n, err := job.Run(calendar.Date(2000, 1, 1), calendar.Date(2021, 3, 31))
+//
+// Backfilling decades takes hours under the throttle of the TWSE, and is likely to stop halfway, e.g. when
+// banned. With StateFile set, a Job checkpoints the last day written of each market after every write, and
+// running the job again with the same from resumes right after it.
+//
+// A day is checkpointed only after the sink returns, so the day being written when the job stopped is
+// written again on resumption. Sinks should tolerate that, as the puts of store.Store do.
+package backfill
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+	"github.com/chehsunliu/tshakutshai/pkg/store"
+)
+
+// Markets.
+const (
+	MarketTWSE = "twse"
+	MarketTPEx = "tpex"
+)
+
+// DayFetcher fetches the day quotes of all stocks on a date. Both twse.Client and tpex.Client implement
+// it.
+type DayFetcher interface {
+	FetchDayQuotesInOrder(date time.Time) ([]quote.Day, error)
+}
+
+// Source is a market to backfill.
+type Source struct {
+	Market  string
+	Fetcher DayFetcher
+}
+
+// Sink receives the day quotes of a market on a trading day. qs is empty if the market has no quotes on
+// the day, e.g. closed by a typhoon not in the holiday schedule, whether the fetcher returns nothing or
+// fails with exchangeerr.ErrNoData as strict clients do.
+type Sink interface {
+	WriteDayQuotes(market string, date time.Time, qs []quote.Day) error
+}
+
+// SinkFunc is a function used as a Sink.
+type SinkFunc func(market string, date time.Time, qs []quote.Day) error
+
+func (f SinkFunc) WriteDayQuotes(market string, date time.Time, qs []quote.Day) error {
+	return f(market, date, qs)
+}
+
+// StoreSink returns a Sink putting the quotes into s.
+func StoreSink(s store.Store) Sink {
+	return SinkFunc(func(market string, date time.Time, qs []quote.Day) error {
+		if len(qs) == 0 {
+			return nil
+		}
+		return s.PutDayQuotes(market, qs)
+	})
+}
+
+// Job backfills the day quotes of the sources.
+type Job struct {
+	// Calendar decides which days are expected to have quotes.
+	Calendar *calendar.Calendar
+	// Sources are fetched in order on each trading day.
+	Sources []Source
+	Sink    Sink
+	// StateFile is the file the progress is saved to and resumed from. The progress is not saved if it is
+	// empty.
+	StateFile string
+	// Progress is called after the quotes of a market on a day are written, if not nil.
+	Progress func(market string, date time.Time, n int)
+}
+
+// NewJob returns a Job backfilling the TWSE and then the TPEx on each trading day, skipping the nil
+// clients, with the holiday schedule of the TWSE. The schedule is fetched with twseClient, or a new
+// twse.Client if it is nil.
+func NewJob(sink Sink, twseClient *twse.Client, tpexClient *tpex.Client) *Job {
+	job := &Job{Sink: sink, Sources: make([]Source, 0, 2)}
+
+	if twseClient != nil {
+		job.Sources = append(job.Sources, Source{Market: MarketTWSE, Fetcher: twseClient})
+		job.Calendar = calendar.New(twseClient)
+	} else {
+		job.Calendar = calendar.New(twse.NewClient(time.Second * 2))
+	}
+
+	if tpexClient != nil {
+		job.Sources = append(job.Sources, Source{Market: MarketTPEx, Fetcher: tpexClient})
+	}
+
+	return job
+}
+
+// Run fetches the day quotes of the sources on the trading days from from to to inclusively, day by day,
+// and writes them to the sink. It returns the number of quotes written. Days the fetchers fail with
+// exchangeerr.ErrNoData on are written as empty rather than failing the job.
+//
+// If StateFile exists, the days already written are skipped, and it fails if the file was saved by a job
+// run with another from. to can be moved, e.g. to extend a finished backfill to today. On failure, the
+// progress until the failure is kept, and the error tells the market and the day failed.
+func (j *Job) Run(from, to time.Time) (int, error) {
+	from, to = calendar.Normalize(from), calendar.Normalize(to)
+
+	s := newState(from)
+	if j.StateFile != "" {
+		loaded, err := loadState(j.StateFile)
+		if err != nil {
+			return 0, err
+		}
+		if loaded != nil {
+			if !loaded.from.Equal(from) {
+				return 0, fmt.Errorf("state file %s is of the backfill from %s rather than %s", j.StateFile,
+					loaded.from.Format(dateLayout), from.Format(dateLayout))
+			}
+			s = loaded
+		}
+	}
+
+	n := 0
+	for day := s.next(j.Sources); !day.After(to); day = day.AddDate(0, 0, 1) {
+		ok, err := j.Calendar.IsTradingDay(day)
+		if err != nil {
+			return n, fmt.Errorf("failed to check if %s is a trading day: %w", day.Format(dateLayout), err)
+		}
+		if !ok {
+			continue
+		}
+
+		for _, source := range j.Sources {
+			if done, ok := s.done[source.Market]; ok && !day.After(done) {
+				continue
+			}
+
+			qs, err := source.Fetcher.FetchDayQuotesInOrder(day)
+			if errors.Is(err, exchangeerr.ErrNoData) {
+				qs, err = nil, nil
+			}
+			if err != nil {
+				return n, fmt.Errorf("failed to fetch day quotes on %s from %s: %w", day.Format(dateLayout),
+					source.Market, err)
+			}
+
+			if err := j.Sink.WriteDayQuotes(source.Market, day, qs); err != nil {
+				return n, fmt.Errorf("failed to write day quotes on %s of %s: %w", day.Format(dateLayout),
+					source.Market, err)
+			}
+			n += len(qs)
+
+			s.done[source.Market] = day
+			if j.StateFile != "" {
+				if err := s.save(j.StateFile); err != nil {
+					return n, err
+				}
+			}
+
+			if j.Progress != nil {
+				j.Progress(source.Market, day, len(qs))
+			}
+		}
+	}
+
+	return n, nil
+}
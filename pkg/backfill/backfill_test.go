@@ -0,0 +1,225 @@
+package backfill_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/backfill"
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+	"github.com/chehsunliu/tshakutshai/pkg/store"
+)
+
+type fakeFetcher struct {
+	codes []string
+	// failOn fails the fetches on the date, formatted as 2006-01-02.
+	failOn string
+	// noDataOn fails the fetches on the date with a NoDataError, as strict clients do.
+	noDataOn string
+	calls    []string
+}
+
+func (f *fakeFetcher) FetchDayQuotesInOrder(date time.Time) ([]quote.Day, error) {
+	key := date.Format("2006-01-02")
+	f.calls = append(f.calls, key)
+	if key == f.failOn {
+		return nil, errors.New("banned")
+	}
+	if key == f.noDataOn {
+		return nil, &twse.NoDataError{Message: "no quotes on " + key, Reason: exchangeerr.ReasonNonTradingDay}
+	}
+
+	qs := make([]quote.Day, 0, len(f.codes))
+	for _, code := range f.codes {
+		qs = append(qs, quote.Day{Code: code, Date: date, Close: price.MustParse("100.00")})
+	}
+	return qs, nil
+}
+
+type write struct {
+	market string
+	date   string
+	n      int
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "backfill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	return dir
+}
+
+func newJob(twseFetcher, tpexFetcher *fakeFetcher, writes *[]write) *backfill.Job {
+	cal := calendar.New(nil)
+	cal.Load(2021, []calendar.Holiday{
+		{Date: calendar.Date(2021, 2, 10), Name: "春節"},
+		{Date: calendar.Date(2021, 2, 11), Name: "春節"},
+		{Date: calendar.Date(2021, 2, 12), Name: "春節"},
+		{Date: calendar.Date(2021, 2, 15), Name: "春節"},
+		{Date: calendar.Date(2021, 2, 16), Name: "春節"},
+	})
+
+	return &backfill.Job{
+		Calendar: cal,
+		Sources: []backfill.Source{
+			{Market: backfill.MarketTWSE, Fetcher: twseFetcher},
+			{Market: backfill.MarketTPEx, Fetcher: tpexFetcher},
+		},
+		Sink: backfill.SinkFunc(func(market string, date time.Time, qs []quote.Day) error {
+			*writes = append(*writes, write{market, date.Format("2006-01-02"), len(qs)})
+			return nil
+		}),
+	}
+}
+
+func TestJob_Run(t *testing.T) {
+	twseFetcher := &fakeFetcher{codes: []string{"0050", "2330"}}
+	tpexFetcher := &fakeFetcher{codes: []string{"8044"}}
+	var writes []write
+	job := newJob(twseFetcher, tpexFetcher, &writes)
+
+	n, err := job.Run(calendar.Date(2021, 2, 9), calendar.Date(2021, 2, 17))
+	assert.Nil(t, err)
+	assert.Equal(t, 6, n)
+	assert.Equal(t, []string{"2021-02-09", "2021-02-17"}, twseFetcher.calls)
+	assert.Equal(t, []string{"2021-02-09", "2021-02-17"}, tpexFetcher.calls)
+	assert.Equal(t, []write{
+		{"twse", "2021-02-09", 2},
+		{"tpex", "2021-02-09", 1},
+		{"twse", "2021-02-17", 2},
+		{"tpex", "2021-02-17", 1},
+	}, writes)
+}
+
+func TestJob_RunResuming(t *testing.T) {
+	twseFetcher := &fakeFetcher{codes: []string{"2330"}}
+	tpexFetcher := &fakeFetcher{codes: []string{"8044"}, failOn: "2021-02-18"}
+	var writes []write
+	job := newJob(twseFetcher, tpexFetcher, &writes)
+	job.StateFile = filepath.Join(tempDir(t), "backfill.json")
+
+	from, to := calendar.Date(2021, 2, 17), calendar.Date(2021, 2, 19)
+	n, err := job.Run(from, to)
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []write{
+		{"twse", "2021-02-17", 1},
+		{"tpex", "2021-02-17", 1},
+		{"twse", "2021-02-18", 1},
+	}, writes)
+
+	state, err := ioutil.ReadFile(job.StateFile)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"from":"2021-02-17","done":{"twse":"2021-02-18","tpex":"2021-02-17"}}`, string(state))
+
+	// Resumes from the TPEx on the day failed.
+	tpexFetcher.failOn = ""
+	twseFetcher.calls, tpexFetcher.calls, writes = nil, nil, nil
+	n, err = job.Run(from, to)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []string{"2021-02-19"}, twseFetcher.calls)
+	assert.Equal(t, []string{"2021-02-18", "2021-02-19"}, tpexFetcher.calls)
+	assert.Equal(t, []write{
+		{"tpex", "2021-02-18", 1},
+		{"twse", "2021-02-19", 1},
+		{"tpex", "2021-02-19", 1},
+	}, writes)
+
+	// Finished, so nothing is fetched until to is moved.
+	twseFetcher.calls, tpexFetcher.calls = nil, nil
+	n, err = job.Run(from, to)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 0, len(twseFetcher.calls)+len(tpexFetcher.calls))
+
+	n, err = job.Run(from, calendar.Date(2021, 2, 22))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"2021-02-22"}, twseFetcher.calls)
+
+	// The state is of another backfill.
+	_, err = job.Run(calendar.Date(2021, 1, 4), to)
+	assert.NotNil(t, err)
+}
+
+func TestJob_RunWithNoData(t *testing.T) {
+	twseFetcher := &fakeFetcher{codes: []string{"2330"}, noDataOn: "2021-02-18"}
+	tpexFetcher := &fakeFetcher{codes: []string{"8044"}}
+	var writes []write
+	job := newJob(twseFetcher, tpexFetcher, &writes)
+	job.StateFile = filepath.Join(tempDir(t), "backfill.json")
+
+	n, err := job.Run(calendar.Date(2021, 2, 17), calendar.Date(2021, 2, 19))
+	assert.Nil(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, []write{
+		{"twse", "2021-02-17", 1},
+		{"tpex", "2021-02-17", 1},
+		{"twse", "2021-02-18", 0},
+		{"tpex", "2021-02-18", 1},
+		{"twse", "2021-02-19", 1},
+		{"tpex", "2021-02-19", 1},
+	}, writes)
+
+	state, err := ioutil.ReadFile(job.StateFile)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"from":"2021-02-17","done":{"twse":"2021-02-19","tpex":"2021-02-19"}}`, string(state))
+}
+
+func TestJob_RunWithSinkError(t *testing.T) {
+	twseFetcher := &fakeFetcher{codes: []string{"2330"}}
+	tpexFetcher := &fakeFetcher{codes: []string{"8044"}}
+	var writes []write
+	job := newJob(twseFetcher, tpexFetcher, &writes)
+	job.StateFile = filepath.Join(tempDir(t), "backfill.json")
+
+	errSink := errors.New("disk full")
+	job.Sink = backfill.SinkFunc(func(market string, date time.Time, qs []quote.Day) error {
+		return errSink
+	})
+
+	_, err := job.Run(calendar.Date(2021, 2, 17), calendar.Date(2021, 2, 19))
+	assert.True(t, errors.Is(err, errSink))
+
+	_, err = os.Stat(job.StateFile)
+	assert.True(t, errors.Is(err, os.ErrNotExist), "no days should be checkpointed")
+}
+
+func TestStoreSink(t *testing.T) {
+	s := store.NewMemoryStore()
+	var writes []write
+	job := newJob(&fakeFetcher{codes: []string{"0050", "2330"}}, &fakeFetcher{}, &writes)
+	job.Sink = backfill.StoreSink(s)
+
+	var progress []write
+	job.Progress = func(market string, date time.Time, n int) {
+		progress = append(progress, write{market, date.Format("2006-01-02"), n})
+	}
+
+	n, err := job.Run(calendar.Date(2021, 2, 17), calendar.Date(2021, 2, 18))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []write{
+		{"twse", "2021-02-17", 2},
+		{"tpex", "2021-02-17", 0},
+		{"twse", "2021-02-18", 2},
+		{"tpex", "2021-02-18", 0},
+	}, progress)
+
+	qs, err := s.DayQuotesOn(store.MarketTWSE, calendar.Date(2021, 2, 18))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(qs))
+}
@@ -0,0 +1,126 @@
+package backfill
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+)
+
+const dateLayout = "2006-01-02"
+
+// state is the progress of a Job.
+type state struct {
+	// from is the first day of the backfill.
+	from time.Time
+	// done maps markets to the last days written.
+	done map[string]time.Time
+}
+
+// stateJSON is state with the dates formatted as YYYY-MM-DD, e.g.
+//
+//     {"from": "2000-01-04", "done": {"twse": "2013-07-12", "tpex": "2013-07-11"}}
+type stateJSON struct {
+	From string            `json:"from"`
+	Done map[string]string `json:"done"`
+}
+
+func newState(from time.Time) *state {
+	return &state{from: from, done: map[string]time.Time{}}
+}
+
+// next returns the first day some of sources have not been written.
+func (s *state) next(sources []Source) time.Time {
+	var next time.Time
+	for _, source := range sources {
+		day := s.from
+		if done, ok := s.done[source.Market]; ok {
+			day = done.AddDate(0, 0, 1)
+		}
+		if next.IsZero() || day.Before(next) {
+			next = day
+		}
+	}
+
+	if next.IsZero() {
+		return s.from
+	}
+	return next
+}
+
+// loadState returns the state saved in file, or nil if file does not exist.
+func loadState(file string) (*state, error) {
+	data, err := ioutil.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var j stateJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("failed to decode state file %s: %w", file, err)
+	}
+
+	from, err := parseDate(j.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from of state file %s: %w", file, err)
+	}
+
+	s := newState(from)
+	for market, d := range j.Done {
+		if s.done[market], err = parseDate(d); err != nil {
+			return nil, fmt.Errorf("invalid day of %s of state file %s: %w", market, file, err)
+		}
+	}
+
+	return s, nil
+}
+
+// save writes s to a temporary file and renames it to file, so that file is never left half-written.
+func (s *state) save(file string) error {
+	j := stateJSON{From: s.from.Format(dateLayout), Done: map[string]string{}}
+	for market, d := range s.done {
+		j.Done[market] = d.Format(dateLayout)
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save state file %s: %w", file, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to save state file %s: %w", file, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save state file %s: %w", file, err)
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to save state file %s: %w", file, err)
+	}
+
+	return nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is not a date like 2021-03-24: %w", s, err)
+	}
+
+	return calendar.Date(t.Year(), t.Month(), t.Day()), nil
+}
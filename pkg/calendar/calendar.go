@@ -0,0 +1,254 @@
+// Package calendar tells the trading days of the Taiwan exchanges apart from weekends, holidays and
+// typhoon closures, so that callers can skip the dates on which the exchanges have no data without
+// querying them.
+//
+// Holiday schedules are loaded lazily per year from a HolidayFetcher, e.g. twse.Client, and cached:
+//
+//     cal := calendar.New(twse.NewClient(time.Second * 2))
+//     ok, _ := cal.IsTradingDay(time.Date(2021, 2, 12, 0, 0, 0, 0, time.UTC))
+//
+// Typhoon closures are announced on short notice and not included in the schedule. Add them with
+// Calendar.AddClosure.
+//
+// The schedule of a year is published late in the year before. Until then, the fetcher fails with
+// exchangeerr.ErrNoData, and the year is taken as having no holidays but weekends, and fetched again
+// after RetryInterval.
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+)
+
+// DefaultRetryInterval is how long Calendar waits to fetch the schedule of a year not yet published again,
+// unless configured.
+const DefaultRetryInterval = time.Hour
+
+// maxSearchDays bounds the search of NextTradingDay and PrevTradingDay. No closure of the Taiwan
+// exchanges lasts this long.
+const maxSearchDays = 60
+
+// Holiday is a date on which the exchanges are closed, or a weekend day on which they open to make up for
+// a holiday if Open is set.
+type Holiday struct {
+	Date time.Time
+	Name string
+	// Description is the note from the exchange, e.g. the reason of the closure.
+	Description string
+	// Open tells a make-up trading day on a weekend, e.g. 補行交易日, which is not a holiday.
+	Open bool
+}
+
+// HolidayFetcher fetches the holidays of a year, e.g. twse.Client. It fails with an error matching
+// exchangeerr.ErrNoData if the schedule of the year is not yet published.
+type HolidayFetcher interface {
+	FetchHolidays(year int) ([]Holiday, error)
+}
+
+// Calendar determines trading days. It is safe for concurrent use.
+type Calendar struct {
+	// RetryInterval is how long the schedule of a year not yet published is taken as having no holidays
+	// before fetched again. Zero means DefaultRetryInterval.
+	RetryInterval time.Duration
+
+	fetcher HolidayFetcher
+
+	mutex  sync.Mutex
+	loaded map[int]bool
+	// unpublished are the times the schedules of the years were found not yet published.
+	unpublished map[int]time.Time
+	holidays    map[string]Holiday
+	// openDays are the make-up trading days on weekends.
+	openDays map[string]Holiday
+	// loading are closed when the schedules of the years being fetched are loaded.
+	loading map[int]chan struct{}
+}
+
+// New returns a Calendar loading holiday schedules by fetcher. If fetcher is nil, only weekends and
+// the holidays added by Calendar.Load and Calendar.AddClosure are considered non-trading days.
+func New(fetcher HolidayFetcher) *Calendar {
+	return &Calendar{
+		RetryInterval: DefaultRetryInterval,
+		fetcher:       fetcher,
+		loaded:        map[int]bool{},
+		unpublished:   map[int]time.Time{},
+		holidays:      map[string]Holiday{},
+		openDays:      map[string]Holiday{},
+		loading:       map[int]chan struct{}{},
+	}
+}
+
+func dateKey(date time.Time) string {
+	return date.Format("20060102")
+}
+
+// Load sets the holidays of the year, which will not be fetched afterwards. It is useful to restore
+// schedules cached elsewhere.
+func (c *Calendar) Load(year int, holidays []Holiday) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.loaded[year] = true
+	delete(c.unpublished, year)
+	c.add(holidays)
+}
+
+// add adds the entries of a holiday schedule. The caller must hold the mutex.
+func (c *Calendar) add(holidays []Holiday) {
+	for _, h := range holidays {
+		if h.Open {
+			c.openDays[dateKey(h.Date)] = h
+		} else {
+			c.holidays[dateKey(h.Date)] = h
+		}
+	}
+}
+
+// AddClosure marks date as a non-trading day, e.g. the exchanges are closed due to a typhoon.
+func (c *Calendar) AddClosure(date time.Time, reason string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.holidays[dateKey(date)] = Holiday{Date: Normalize(date), Name: reason}
+}
+
+// Holiday returns the holiday on date if there is any.
+func (c *Calendar) Holiday(date time.Time) (Holiday, bool, error) {
+	if err := c.ensureLoaded(date.Year()); err != nil {
+		return Holiday{}, false, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	h, ok := c.holidays[dateKey(date)]
+	return h, ok, nil
+}
+
+// ensureLoaded fetches the schedule of the year if not loaded. The mutex is not held while fetching, so
+// that a slow fetch does not block the years already loaded. Concurrent callers of the same year wait for
+// the same fetch, and fetch again if it fails. A schedule not yet published is not loaded, but fetched
+// again after RetryInterval.
+func (c *Calendar) ensureLoaded(year int) error {
+	for {
+		c.mutex.Lock()
+		if c.fetcher == nil || c.loaded[year] {
+			c.mutex.Unlock()
+			return nil
+		}
+		if at, ok := c.unpublished[year]; ok && time.Since(at) < c.retryInterval() {
+			c.mutex.Unlock()
+			return nil
+		}
+
+		if done, ok := c.loading[year]; ok {
+			c.mutex.Unlock()
+			<-done
+			continue
+		}
+
+		done := make(chan struct{})
+		c.loading[year] = done
+		c.mutex.Unlock()
+
+		holidays, err := c.fetcher.FetchHolidays(year)
+
+		c.mutex.Lock()
+		delete(c.loading, year)
+		close(done)
+		switch {
+		case err == nil:
+			c.loaded[year] = true
+			delete(c.unpublished, year)
+			c.add(holidays)
+		case errors.Is(err, exchangeerr.ErrNoData):
+			c.unpublished[year] = time.Now()
+			err = nil
+		}
+		c.mutex.Unlock()
+
+		if err != nil {
+			return fmt.Errorf("failed to fetch holidays of %d: %w", year, err)
+		}
+		return nil
+	}
+}
+
+func (c *Calendar) retryInterval() time.Duration {
+	if c.RetryInterval == 0 {
+		return DefaultRetryInterval
+	}
+	return c.RetryInterval
+}
+
+// IsTradingDay reports whether the exchanges open on date, i.e. a weekday not a holiday, or a make-up
+// trading day on a weekend. Only the year, month and day of date are considered.
+func (c *Calendar) IsTradingDay(date time.Time) (bool, error) {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		if err := c.ensureLoaded(date.Year()); err != nil {
+			return false, err
+		}
+
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		_, ok := c.openDays[dateKey(date)]
+		return ok, nil
+	}
+
+	_, isHoliday, err := c.Holiday(date)
+	if err != nil {
+		return false, err
+	}
+
+	return !isHoliday, nil
+}
+
+func (c *Calendar) searchTradingDay(date time.Time, step int) (time.Time, error) {
+	d := Normalize(date)
+	for i := 0; i < maxSearchDays; i++ {
+		d = d.AddDate(0, 0, step)
+
+		ok, err := c.IsTradingDay(d)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if ok {
+			return d, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("no trading days within %d days from %s", maxSearchDays, date.Format("2006-01-02"))
+}
+
+// NextTradingDay returns the first trading day after date, normalized by Normalize.
+func (c *Calendar) NextTradingDay(date time.Time) (time.Time, error) {
+	return c.searchTradingDay(date, 1)
+}
+
+// PrevTradingDay returns the last trading day before date, normalized by Normalize.
+func (c *Calendar) PrevTradingDay(date time.Time) (time.Time, error) {
+	return c.searchTradingDay(date, -1)
+}
+
+// TradingDaysBetween returns the trading days from from to to, both inclusive, in ascending order.
+// The returned dates are normalized by Normalize.
+func (c *Calendar) TradingDaysBetween(from, to time.Time) ([]time.Time, error) {
+	days := make([]time.Time, 0)
+
+	for d := Normalize(from); dateKey(d) <= dateKey(to); d = d.AddDate(0, 0, 1) {
+		ok, err := c.IsTradingDay(d)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			days = append(days, d)
+		}
+	}
+
+	return days, nil
+}
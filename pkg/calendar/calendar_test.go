@@ -0,0 +1,228 @@
+package calendar_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+)
+
+type fakeFetcher struct {
+	holidays map[int][]calendar.Holiday
+	calls    int
+	err      error
+}
+
+func (f *fakeFetcher) FetchHolidays(year int) ([]calendar.Holiday, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.holidays[year], nil
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return calendar.Date(year, month, day)
+}
+
+func newFakeFetcher() *fakeFetcher {
+	return &fakeFetcher{holidays: map[int][]calendar.Holiday{
+		2021: {
+			{Date: date(2021, time.February, 10), Name: "農曆除夕前一日"},
+			{Date: date(2021, time.February, 11), Name: "農曆除夕"},
+			{Date: date(2021, time.February, 12), Name: "春節"},
+			{Date: date(2021, time.February, 15), Name: "春節"},
+			{Date: date(2021, time.February, 16), Name: "春節"},
+		},
+	}}
+}
+
+func TestCalendar_IsTradingDay(t *testing.T) {
+	fetcher := newFakeFetcher()
+	cal := calendar.New(fetcher)
+
+	for _, tc := range []struct {
+		date     time.Time
+		expected bool
+	}{
+		{date(2021, time.February, 9), true},
+		{date(2021, time.February, 11), false},
+		{date(2021, time.February, 13), false},
+		{date(2021, time.February, 14), false},
+		{date(2021, time.February, 17), true},
+	} {
+		ok, err := cal.IsTradingDay(tc.date)
+		assert.Nil(t, err)
+		assert.Equalf(t, tc.expected, ok, "%v", tc.date)
+	}
+
+	assert.Equal(t, 1, fetcher.calls)
+}
+
+func TestCalendar_NextAndPrevTradingDay(t *testing.T) {
+	cal := calendar.New(newFakeFetcher())
+
+	next, err := cal.NextTradingDay(date(2021, time.February, 9))
+	assert.Nil(t, err)
+	assert.Equal(t, date(2021, time.February, 17), next)
+
+	prev, err := cal.PrevTradingDay(date(2021, time.February, 17))
+	assert.Nil(t, err)
+	assert.Equal(t, date(2021, time.February, 9), prev)
+}
+
+func TestCalendar_TradingDaysBetween(t *testing.T) {
+	cal := calendar.New(newFakeFetcher())
+
+	days, err := cal.TradingDaysBetween(date(2021, time.February, 8), date(2021, time.February, 18))
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{
+		date(2021, time.February, 8),
+		date(2021, time.February, 9),
+		date(2021, time.February, 17),
+		date(2021, time.February, 18),
+	}, days)
+}
+
+func TestCalendar_AddClosure(t *testing.T) {
+	cal := calendar.New(nil)
+
+	typhoonDay := date(2019, time.August, 9)
+	ok, err := cal.IsTradingDay(typhoonDay)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	cal.AddClosure(typhoonDay, "颱風")
+	ok, err = cal.IsTradingDay(typhoonDay)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	h, isHoliday, err := cal.Holiday(typhoonDay)
+	assert.Nil(t, err)
+	assert.True(t, isHoliday)
+	assert.Equal(t, "颱風", h.Name)
+}
+
+func TestCalendar_LoadSkipsFetching(t *testing.T) {
+	fetcher := &fakeFetcher{err: errors.New("should not be called")}
+	cal := calendar.New(fetcher)
+	cal.Load(2021, []calendar.Holiday{{Date: date(2021, time.January, 1), Name: "中華民國開國紀念日"}})
+
+	ok, err := cal.IsTradingDay(date(2021, time.January, 1))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 0, fetcher.calls)
+}
+
+func TestCalendar_FetchError(t *testing.T) {
+	cal := calendar.New(&fakeFetcher{err: errors.New("banned")})
+
+	_, err := cal.IsTradingDay(date(2021, time.January, 4))
+	assert.NotNil(t, err)
+}
+
+func TestCalendar_NotYetPublished(t *testing.T) {
+	fetcher := newFakeFetcher()
+	fetcher.err = fmt.Errorf("%w: holiday schedule of 2021", exchangeerr.ErrNoData)
+	cal := calendar.New(fetcher)
+
+	// Taken as having no holidays until published.
+	ok, err := cal.IsTradingDay(date(2021, time.February, 12))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	fetcher.err = nil
+	ok, err = cal.IsTradingDay(date(2021, time.February, 12))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, fetcher.calls, "the schedule should not be fetched again until RetryInterval passes")
+
+	cal.RetryInterval = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	ok, err = cal.IsTradingDay(date(2021, time.February, 12))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 2, fetcher.calls)
+
+	_, err = cal.IsTradingDay(date(2021, time.February, 15))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, fetcher.calls, "the published schedule should be kept")
+}
+
+func TestCalendar_MakeUpTradingDay(t *testing.T) {
+	cal := calendar.New(nil)
+	cal.Load(2012, []calendar.Holiday{
+		{Date: date(2012, time.December, 31), Name: "調整放假"},
+		{Date: date(2012, time.December, 22), Name: "補行交易日", Open: true},
+	})
+
+	for _, tc := range []struct {
+		date     time.Time
+		expected bool
+	}{
+		{date(2012, time.December, 22), true},
+		{date(2012, time.December, 29), false},
+		{date(2012, time.December, 31), false},
+	} {
+		ok, err := cal.IsTradingDay(tc.date)
+		assert.Nil(t, err)
+		assert.Equalf(t, tc.expected, ok, "%v", tc.date)
+	}
+
+	_, isHoliday, err := cal.Holiday(date(2012, time.December, 22))
+	assert.Nil(t, err)
+	assert.False(t, isHoliday)
+
+	d, err := cal.PrevTradingDay(date(2012, time.December, 24))
+	assert.Nil(t, err)
+	assert.Equal(t, date(2012, time.December, 22), d)
+}
+
+// blockingFetcher blocks the fetches of 2020 until release is closed.
+type blockingFetcher struct {
+	mutex   sync.Mutex
+	calls   map[int]int
+	release chan struct{}
+}
+
+func (f *blockingFetcher) FetchHolidays(year int) ([]calendar.Holiday, error) {
+	f.mutex.Lock()
+	f.calls[year]++
+	f.mutex.Unlock()
+
+	if year == 2020 {
+		<-f.release
+	}
+	return nil, nil
+}
+
+func TestCalendar_FetchWithoutBlocking(t *testing.T) {
+	fetcher := &blockingFetcher{calls: map[int]int{}, release: make(chan struct{})}
+	cal := calendar.New(fetcher)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := cal.IsTradingDay(date(2020, time.March, 2))
+			assert.Nil(t, err)
+			assert.True(t, ok)
+		}()
+	}
+
+	// 2021 is not blocked by the fetch of 2020.
+	ok, err := cal.IsTradingDay(date(2021, time.March, 1))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	close(fetcher.release)
+	wg.Wait()
+	assert.Equal(t, map[int]int{2020: 1, 2021: 1}, fetcher.calls, "concurrent callers should share a fetch")
+}
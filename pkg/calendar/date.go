@@ -0,0 +1,42 @@
+package calendar
+
+import (
+	"time"
+
+	// The zoneinfo database is embedded in case the system does not provide one, e.g. in scratch
+	// containers and on Windows.
+	_ "time/tzdata"
+)
+
+// Location is the time zone of the Taiwan exchanges, i.e. Asia/Taipei.
+var Location = loadLocation()
+
+func loadLocation() *time.Location {
+	loc, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		// Taiwan has not observed daylight saving time since 1980, so a fixed zone is good enough
+		// for all the data provided by the exchanges.
+		return time.FixedZone("CST", 8*60*60)
+	}
+
+	return loc
+}
+
+// Date returns the midnight of the date in Location. All the dates returned by this library are
+// normalized this way, so they can be compared with == and used as map keys.
+func Date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, Location)
+}
+
+// Normalize returns the midnight in Location of the date of t. The date is read in the location of t,
+// so time.Date(2021, 3, 24, 0, 0, 0, 0, time.UTC) and time.Date(2021, 3, 24, 23, 0, 0, 0, time.Local)
+// are both normalized to 2021-03-24 00:00 in Asia/Taipei. Use Today for instants like time.Now().
+func Normalize(t time.Time) time.Time {
+	return Date(t.Year(), t.Month(), t.Day())
+}
+
+// Today returns the midnight of the date in Taiwan at the instant now, e.g. 2021-03-25 for
+// 2021-03-24 16:00 UTC, regardless of the location of now.
+func Today(now time.Time) time.Time {
+	return Normalize(now.In(Location))
+}
@@ -0,0 +1,46 @@
+package calendar_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+)
+
+func TestLocation(t *testing.T) {
+	_, offset := calendar.Date(2021, time.March, 24).Zone()
+	assert.Equal(t, 8*60*60, offset)
+}
+
+func TestNormalize(t *testing.T) {
+	expected := calendar.Date(2021, time.March, 24)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+
+	for _, d := range []time.Time{
+		time.Date(2021, time.March, 24, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, time.March, 24, 23, 59, 59, 0, time.UTC),
+		time.Date(2021, time.March, 24, 13, 30, 0, 0, calendar.Location),
+		time.Date(2021, time.March, 24, 22, 0, 0, 0, newYork),
+	} {
+		assert.Equal(t, expected, calendar.Normalize(d))
+		assert.True(t, expected == calendar.Normalize(d))
+	}
+}
+
+func TestToday(t *testing.T) {
+	for _, tc := range []struct {
+		now      time.Time
+		expected time.Time
+	}{
+		{time.Date(2021, time.March, 24, 15, 59, 59, 0, time.UTC), calendar.Date(2021, time.March, 24)},
+		{time.Date(2021, time.March, 24, 16, 0, 0, 0, time.UTC), calendar.Date(2021, time.March, 25)},
+		{time.Date(2021, time.March, 24, 23, 0, 0, 0, time.UTC), calendar.Date(2021, time.March, 25)},
+		{time.Date(2021, time.March, 25, 0, 30, 0, 0, calendar.Location), calendar.Date(2021, time.March, 25)},
+	} {
+		assert.Equalf(t, tc.expected, calendar.Today(tc.now), "%v", tc.now)
+	}
+}
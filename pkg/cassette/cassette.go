@@ -0,0 +1,246 @@
+// Package cassette provides an HTTP client recording the responses of the exchanges into a directory and
+// replaying them later, so that tests built on the twse and tpex clients run offline and deterministically
+// without hand-made fixtures or mocks.
+//
+// Wrap the HTTP client of a twse or tpex client, record once against the exchanges, and commit the
+// directory:
+//
+//     client := twse.NewClient(time.Second * 2)
+//     client.HttpClient = cassette.New("testdata/cassettes", cassette.ModeRecord, client.HttpClient)
+//
+// Later runs replay the recorded responses, matched by the methods, the paths, the query parameters and
+// the form values of the requests:
+//
+//     client.HttpClient = cassette.New("testdata/cassettes", cassette.ModeReplay, nil)
+//
+// ModeAuto replays the recorded requests and records the others. ModeFromEnv picks the mode by the
+// environment variable TSHAKUTSHAI_CASSETTE, e.g. to re-record in a run of the tests:
+//
+//     TSHAKUTSHAI_CASSETTE=record go test ./integration/...
+package cassette
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+)
+
+// Mode tells a Client whether to send the requests or replay them.
+type Mode int
+
+const (
+	// ModeReplay serves the recorded responses, failing the requests not recorded with ErrNotRecorded.
+	ModeReplay Mode = iota
+	// ModeRecord sends every request and records the response, replacing the recorded one.
+	ModeRecord
+	// ModeAuto serves the recorded responses and records the responses of the other requests.
+	ModeAuto
+)
+
+// EnvMode is the environment variable read by ModeFromEnv, set to replay, record or auto.
+const EnvMode = "TSHAKUTSHAI_CASSETTE"
+
+// ModeFromEnv returns the mode set by EnvMode, or fallback if it is not set or unknown.
+func ModeFromEnv(fallback Mode) Mode {
+	switch os.Getenv(EnvMode) {
+	case "replay":
+		return ModeReplay
+	case "record":
+		return ModeRecord
+	case "auto":
+		return ModeAuto
+	default:
+		return fallback
+	}
+}
+
+// ErrNotRecorded is returned in ModeReplay for the requests without recorded responses.
+var ErrNotRecorded = errors.New("request not recorded")
+
+// ErrNoClient is returned for the requests to be recorded by a Client without a client to send them.
+var ErrNoClient = errors.New("no client to record the request with")
+
+// Interaction is a request and its response, saved as gzipped JSON.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	// Form is the form values of the body, if it is application/x-www-form-urlencoded.
+	Form url.Values `json:"form,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// Client records and replays requests in a directory. It is safe for concurrent use if the wrapped client
+// is.
+type Client struct {
+	dir    string
+	mode   Mode
+	client tkthttp.Client
+}
+
+// New returns a Client recording and replaying the requests in dir, which sends the requests through
+// client if they are to be recorded. client can be nil in ModeReplay; otherwise, the requests to be
+// recorded fail with ErrNoClient.
+func New(dir string, mode Mode, client tkthttp.Client) *Client {
+	return &Client{dir: dir, mode: mode, client: client}
+}
+
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	form, err := readForm(req)
+	if err != nil {
+		return nil, err
+	}
+
+	file := filepath.Join(c.dir, fileName(req, form))
+
+	if c.mode != ModeRecord {
+		i, err := load(file)
+		switch {
+		case err == nil:
+			return i.Response.toHTTP(req), nil
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, err
+		case c.mode == ModeReplay:
+			return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, req.Method, req.URL)
+		}
+	}
+
+	if c.client == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoClient, req.Method, req.URL)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	i := Interaction{
+		Request:  Request{Method: req.Method, URL: req.URL.String(), Header: req.Header, Form: form},
+		Response: Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body},
+	}
+	if err := save(file, i); err != nil {
+		return nil, fmt.Errorf("failed to record %s %s: %w", req.Method, req.URL, err)
+	}
+
+	return i.Response.toHTTP(req), nil
+}
+
+// readForm returns the form values of the body of req and restores the body.
+func readForm(req *http.Request) (url.Values, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return url.ParseQuery(string(body))
+}
+
+var invalidFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// fileName returns the name of the file of the request, made of the method, the last element of the path
+// and the hash of what requests are matched by, e.g. GET-MI_INDEX-3f2a9c1e0b7d.json.gz. Query parameters
+// and form values are encoded in the order of the keys, so their order does not matter.
+func fileName(req *http.Request, form url.Values) string {
+	key := strings.Join([]string{req.Method, req.URL.Path, req.URL.Query().Encode(), form.Encode()}, "\n")
+	sum := sha1.Sum([]byte(key))
+
+	name := invalidFileNameChars.ReplaceAllString(strings.TrimSuffix(path.Base(req.URL.Path), ".php"), "_")
+	return fmt.Sprintf("%s-%s-%s.json.gz", req.Method, name, hex.EncodeToString(sum[:6]))
+}
+
+func (r Response) toHTTP(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+func load(file string) (Interaction, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return Interaction{}, err
+	}
+	defer f.Close()
+
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		return Interaction{}, fmt.Errorf("failed to create GZIP reader of %s: %w", file, err)
+	}
+
+	var i Interaction
+	if err := json.NewDecoder(reader).Decode(&i); err != nil {
+		return Interaction{}, fmt.Errorf("failed to decode %s: %w", file, err)
+	}
+
+	return i, nil
+}
+
+func save(file string, i Interaction) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	writer := gzip.NewWriter(&b)
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(i); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, b.Bytes(), 0o644)
+}
@@ -0,0 +1,182 @@
+package cassette_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/cassette"
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/fakeexchange"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "cassette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	return dir
+}
+
+func newServer() *fakeexchange.Server {
+	s := fakeexchange.NewServer()
+	s.AddDayQuotes(fakeexchange.MarketTWSE, quote.Day{Code: "2330", Name: "台積電", Date: calendar.Date(2021, 3, 24),
+		Close: price.MustParse("576.00"), Volume: 1000})
+	s.AddMonthlyQuotes(fakeexchange.MarketTPEx,
+		quote.Monthly{Code: "8044", Year: 2019, Month: time.January, High: price.MustParse("140.00")},
+		quote.Monthly{Code: "8044", Year: 2020, Month: time.January, High: price.MustParse("96.40")})
+	return s
+}
+
+func TestClient_RecordAndReplay(t *testing.T) {
+	dir := tempDir(t)
+	s := newServer()
+
+	recorder := cassette.New(dir, cassette.ModeRecord, s.HttpClient())
+	twseClient := &twse.Client{HttpClient: recorder}
+	tpexClient := &tpex.Client{HttpClient: recorder}
+
+	dayQuotes, err := twseClient.FetchDayQuotes(calendar.Date(2021, 3, 24))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(dayQuotes))
+
+	monthlyQuotes2019, err := tpexClient.FetchMonthlyQuotes("8044", 2019)
+	assert.Nil(t, err)
+	monthlyQuotes2020, err := tpexClient.FetchMonthlyQuotes("8044", 2020)
+	assert.Nil(t, err)
+	assert.NotEqual(t, monthlyQuotes2019, monthlyQuotes2020)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json.gz"))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(files), "requests of different form values should be recorded separately")
+	s.Close()
+
+	player := cassette.New(dir, cassette.ModeReplay, nil)
+	twseClient = &twse.Client{HttpClient: player, BaseURL: "http://localhost:8080"}
+	tpexClient = &tpex.Client{HttpClient: player}
+
+	qs, err := twseClient.FetchDayQuotes(calendar.Date(2021, 3, 24))
+	assert.Nil(t, err)
+	assert.Equal(t, dayQuotes, qs, "the hosts should not matter")
+
+	ms, err := tpexClient.FetchMonthlyQuotes("8044", 2020)
+	assert.Nil(t, err)
+	assert.Equal(t, monthlyQuotes2020, ms)
+
+	ms, err = tpexClient.FetchMonthlyQuotes("8044", 2019)
+	assert.Nil(t, err)
+	assert.Equal(t, monthlyQuotes2019, ms)
+
+	_, err = twseClient.FetchDayQuotes(calendar.Date(2021, 3, 25))
+	assert.NotNil(t, err)
+}
+
+func TestClient_Replay(t *testing.T) {
+	dir := tempDir(t)
+	s := newServer()
+	defer s.Close()
+
+	recorder := cassette.New(dir, cassette.ModeRecord, s.HttpClient())
+	req, err := http.NewRequest("GET", "https://www.twse.com.tw/exchangeReport/MI_INDEX?date=20210324&type=ALL&response=json", nil)
+	assert.Nil(t, err)
+	resp, err := recorder.Do(req)
+	assert.Nil(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+
+	player := cassette.New(dir, cassette.ModeReplay, nil)
+
+	// The order of the query parameters does not matter.
+	req, err = http.NewRequest("GET", "https://www.twse.com.tw/exchangeReport/MI_INDEX?response=json&type=ALL&date=20210324", nil)
+	assert.Nil(t, err)
+	resp, err = player.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json"))
+	replayed, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, body, replayed)
+
+	for _, u := range []string{
+		"https://www.twse.com.tw/exchangeReport/MI_INDEX?response=json&type=ALL&date=20210325",
+		"https://www.twse.com.tw/exchangeReport/STOCK_DAY?response=json&type=ALL&date=20210324",
+	} {
+		req, err = http.NewRequest("GET", u, nil)
+		assert.Nil(t, err)
+		_, err = player.Do(req)
+		assert.True(t, errors.Is(err, cassette.ErrNotRecorded))
+	}
+
+	req, err = http.NewRequest("POST", "https://www.twse.com.tw/exchangeReport/MI_INDEX?response=json&type=ALL&date=20210324", nil)
+	assert.Nil(t, err)
+	_, err = player.Do(req)
+	assert.True(t, errors.Is(err, cassette.ErrNotRecorded), "methods should be matched")
+
+	assert.Equal(t, 1, len(s.Requests()))
+}
+
+func TestClient_Auto(t *testing.T) {
+	dir := tempDir(t)
+	s := newServer()
+	defer s.Close()
+
+	client := &twse.Client{HttpClient: cassette.New(dir, cassette.ModeAuto, s.HttpClient())}
+	for i := 0; i < 2; i++ {
+		qs, err := client.FetchDayQuotes(calendar.Date(2021, 3, 24))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(qs))
+	}
+	assert.Equal(t, 1, len(s.Requests()), "the second query should be replayed")
+
+	// Recording again replaces the responses.
+	client.HttpClient = cassette.New(dir, cassette.ModeRecord, s.HttpClient())
+	_, err := client.FetchDayQuotes(calendar.Date(2021, 3, 24))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(s.Requests()))
+
+	// Failures are not recorded.
+	s.SetFault("", fakeexchange.FaultEmptyReply)
+	client.HttpClient = cassette.New(dir, cassette.ModeAuto, s.HttpClient())
+	_, err = client.FetchYearlyQuotes("0050")
+	assert.NotNil(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json.gz"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+}
+
+func TestClient_AutoWithoutClient(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://www.twse.com.tw/exchangeReport/FMNPTK?response=json&stockNo=0050", nil)
+	assert.Nil(t, err)
+
+	_, err = cassette.New(tempDir(t), cassette.ModeAuto, nil).Do(req)
+	assert.True(t, errors.Is(err, cassette.ErrNoClient))
+}
+
+func TestModeFromEnv(t *testing.T) {
+	defer os.Unsetenv(cassette.EnvMode)
+
+	for value, mode := range map[string]cassette.Mode{
+		"replay": cassette.ModeReplay,
+		"record": cassette.ModeRecord,
+		"auto":   cassette.ModeAuto,
+		"":       cassette.ModeAuto,
+		"tape":   cassette.ModeAuto,
+	} {
+		assert.Nil(t, os.Setenv(cassette.EnvMode, value))
+		assert.Equalf(t, mode, cassette.ModeFromEnv(cassette.ModeAuto), "%s=%s", cassette.EnvMode, value)
+	}
+}
@@ -6,73 +6,113 @@ import (
 	"strconv"
 	"strings"
 	"time"
-)
-
-func deserializeSliceOfSlicesOfStrings(rawData map[string]json.RawMessage, key string) [][]string {
-	rawItems, ok := rawData[key]
-	if !ok {
-		panic(fmt.Sprintf("key '%s' does not exist", key))
-	}
-
-	var items [][]string
-	if err := json.Unmarshal(rawItems, &items); err != nil {
-		panic(fmt.Sprintf("failed to unmarshal: %s", err))
-	}
 
-	return items
-}
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+)
 
-func deserializeString(rawData map[string]json.RawMessage, key string) string {
+func deserializeString(rawData map[string]json.RawMessage, key string) (string, error) {
 	rawItem, ok := rawData[key]
 	if !ok {
-		panic(fmt.Sprintf("key '%s' does not exist", key))
+		return "", &ParseError{fmt.Sprintf("key '%s' does not exist", key), nil}
 	}
 
 	var s string
 	if err := json.Unmarshal(rawItem, &s); err != nil {
-		panic(fmt.Sprintf("failed to unmarshal: %s", err))
+		return "", &ParseError{fmt.Sprintf("failed to decode %s: %s", key, err), err}
 	}
 
-	return s
+	return s, nil
 }
 
-func stringToUint64(s string) uint64 {
-	v, err := strconv.ParseUint(strings.Replace(s, ",", "", -1), 10, 64)
-	if err != nil {
-		panic(fmt.Sprintf("value %v is not uint64: %s", s, err))
-	}
-
-	return v
+func stringToUint64(s string) (uint64, error) {
+	return strconv.ParseUint(strings.Replace(s, ",", "", -1), 10, 64)
 }
 
-func stringToFloat64(s string) float64 {
+func stringToPrice(s string) (price.Price, error) {
 	if strings.TrimSpace(s) == "---" {
-		return 0
-	}
-
-	v, err := strconv.ParseFloat(strings.Replace(s, ",", "", -1), 64)
-	if err != nil {
-		panic(fmt.Sprintf("value %v is not float64: %s", s, err))
+		return 0, nil
 	}
 
-	return v
+	return price.Parse(s)
 }
 
-func stringToDate(s string) time.Time {
+func stringToDate(s string) (time.Time, error) {
 	rawDate := strings.SplitN(s, "/", 2)
 	if len(rawDate) != 2 {
-		panic(fmt.Sprintf("the format of '%s' is unexpected", s))
+		return time.Time{}, fmt.Errorf("the format of '%s' is unexpected", s)
 	}
 
 	rocYear, err := strconv.ParseInt(rawDate[0], 0, 64)
 	if err != nil {
-		panic(fmt.Sprintf("failed to parse %s to int: %v", rawDate[0], err))
+		return time.Time{}, err
 	}
 
 	t, err := time.Parse("01/02", rawDate[1])
 	if err != nil {
-		panic(fmt.Sprintf("failed to parse %s to month and day: %v", rawDate[1], err))
+		return time.Time{}, err
+	}
+
+	// The years are in the ROC calendar in Chinese, but not always in English.
+	year := int(rocYear)
+	if year < 1911 {
+		year += 1911
 	}
 
-	return time.Date(int(rocYear+1911), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return calendar.Date(year, t.Month(), t.Day()), nil
+}
+
+// converter converts the values of a row, keeping the first error as ParseError, so that a row is
+// converted in a composite literal and checked once:
+//
+//     var conv converter
+//     q := DayQuote{Volume: conv.uint64(r, "volume"), Close: conv.price(r, "close")}
+//     if conv.err != nil {
+//         return conv.err
+//     }
+type converter struct {
+	err error
+}
+
+func (c *converter) fail(r row, name string, err error) {
+	if err != nil && c.err == nil {
+		c.err = &ParseError{
+			fmt.Sprintf("invalid %s '%s' of %s: %s", name, r.get(name), r.schema.endpoint, err), err,
+		}
+	}
+}
+
+func (c *converter) int(r row, name string) int {
+	v, err := strconv.Atoi(strings.TrimSpace(r.get(name)))
+	c.fail(r, name, err)
+	return v
+}
+
+func (c *converter) uint64(r row, name string) uint64 {
+	v, err := stringToUint64(r.get(name))
+	c.fail(r, name, err)
+	return v
+}
+
+func (c *converter) price(r row, name string) price.Price {
+	v, err := stringToPrice(r.get(name))
+	c.fail(r, name, err)
+	return v
+}
+
+// date converts ROC dates like 110/03/24.
+func (c *converter) date(r row, name string) time.Time {
+	v, err := stringToDate(r.get(name))
+	c.fail(r, name, err)
+	return v
+}
+
+// dayOfYear converts dates like 03/24 in year.
+func (c *converter) dayOfYear(r row, name string, year int) time.Time {
+	t, err := time.Parse("01/02", r.get(name))
+	c.fail(r, name, err)
+	if err != nil {
+		return time.Time{}
+	}
+	return calendar.Date(year, t.Month(), t.Day())
 }
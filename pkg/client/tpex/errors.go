@@ -0,0 +1,109 @@
+package tpex
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+)
+
+// ErrNoClient is returned by Fetch functions of a Client without Client.HttpClient to query the TPEx
+// server with.
+var ErrNoClient = errors.New("Client.HttpClient is nil")
+
+// NoDataError is returned in strict mode, see Client.Strict, when nothing matches the query, told by Reason.
+type NoDataError struct {
+	Message string
+	Reason  exchangeerr.NoDataReason
+}
+
+func (e *NoDataError) Error() string {
+	return fmt.Sprintf("NoData: %s (%s)", e.Message, e.Reason)
+}
+
+// Is matches exchangeerr.ErrNoData.
+func (e *NoDataError) Is(target error) bool {
+	return target == exchangeerr.ErrNoData
+}
+
+// QuotaExceededError is returned when the TPEx blocks the client, i.e. responds with 403 or 429.
+type QuotaExceededError struct {
+	Message string
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("QuotaExceeded: %s", e.Message)
+}
+
+// Is matches exchangeerr.ErrQuotaExceeded.
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == exchangeerr.ErrQuotaExceeded
+}
+
+// ConnectionError is returned when the TPEx cannot be connected, or the connection fails midway.
+type ConnectionError struct {
+	Message string
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("ConnectionError: %s", e.Message)
+}
+
+// Is matches exchangeerr.ErrConnection.
+func (e *ConnectionError) Is(target error) bool {
+	return target == exchangeerr.ErrConnection
+}
+
+// UnexpectedContentError is returned when the TPEx responds with status codes other than 200 or HTML pages,
+// e.g. during maintenance.
+type UnexpectedContentError struct {
+	Message     string
+	StatusCode  int
+	ContentType string
+	// Maintenance is true if the content is a maintenance page.
+	Maintenance bool
+}
+
+func (e *UnexpectedContentError) Error() string {
+	return fmt.Sprintf("UnexpectedContent: %s", e.Message)
+}
+
+// Is matches exchangeerr.ErrUnexpectedContent, and exchangeerr.ErrMaintenance if Maintenance is true.
+func (e *UnexpectedContentError) Is(target error) bool {
+	return target == exchangeerr.ErrUnexpectedContent || (e.Maintenance && target == exchangeerr.ErrMaintenance)
+}
+
+// ParseError is returned when the response is not valid JSON or CSV.
+type ParseError struct {
+	Message string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("ParseError: %s", e.Message)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches exchangeerr.ErrParse.
+func (e *ParseError) Is(target error) bool {
+	return target == exchangeerr.ErrParse
+}
+
+// SchemaMismatchError is returned when the layout of the rows of an endpoint differs from the known one, e.g.
+// the TPEx inserts a column, instead of reading values from the wrong columns.
+type SchemaMismatchError struct {
+	Endpoint string
+	Message  string
+}
+
+func (e *SchemaMismatchError) Error() string {
+	return fmt.Sprintf("SchemaMismatch: %s: %s", e.Endpoint, e.Message)
+}
+
+// Is matches exchangeerr.ErrSchemaMismatch and exchangeerr.ErrParse.
+func (e *SchemaMismatchError) Is(target error) bool {
+	return target == exchangeerr.ErrSchemaMismatch || target == exchangeerr.ErrParse
+}
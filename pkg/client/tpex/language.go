@@ -0,0 +1,33 @@
+package tpex
+
+import (
+	"github.com/chehsunliu/tshakutshai/pkg/internal/clientopt"
+)
+
+// Language is the language of the day and daily quotes requested from the TPEx. Monthly and yearly quotes
+// are always requested in English, which carry no stock names.
+type Language int
+
+const (
+	// LanguageChinese is the default, in which quotes come with the Chinese names in DayQuote.Name.
+	LanguageChinese Language = clientopt.LanguageChinese
+	// LanguageEnglish requests the English quotes, which come with the English names in
+	// DayQuote.EnglishName instead.
+	LanguageEnglish Language = clientopt.LanguageEnglish
+)
+
+func (c *Client) locale() string {
+	if c.Language == LanguageEnglish {
+		return "en-us"
+	}
+	return "zh-tw"
+}
+
+// setName sets the name of q in the language of c.
+func (c *Client) setName(q *DayQuote, name string) {
+	if c.Language == LanguageEnglish {
+		q.EnglishName = name
+	} else {
+		q.Name = name
+	}
+}
@@ -0,0 +1,173 @@
+package tpex_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+	"github.com/chehsunliu/tshakutshai/pkg/fakeexchange"
+	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+type countingLimiter struct {
+	waits int
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits++
+	return nil
+}
+
+func TestNewClient_WithOptions(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+	s.AddDayQuotes(fakeexchange.MarketTPEx, quote.Day{Code: "2330", Date: calendar.Date(2021, 3, 24)})
+
+	var b bytes.Buffer
+	limiter := &countingLimiter{}
+
+	client := tpex.NewClient(0,
+		tpex.WithBaseURL(s.URL),
+		tpex.WithTimeout(time.Second*5),
+		tpex.WithUserAgent("pipeline/1.0"),
+		tpex.WithHeader("X-Team", "quant"),
+		tpex.WithRequestFunc(func(req *http.Request) { req.AddCookie(&http.Cookie{Name: "session", Value: "42"}) }),
+		tpex.WithLimiter(limiter),
+		tpex.WithCache(tkthttp.NewMemoryCache(0, 0)),
+		tpex.WithLogger(log.New(&b, "", 0)),
+	)
+	assert.Equal(t, s.URL, client.BaseURL)
+
+	for i := 0; i < 2; i++ {
+		qs, err := client.FetchDayQuotes(calendar.Date(2021, 3, 24))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(qs))
+	}
+
+	requests := s.Requests()
+	assert.Equal(t, 1, len(requests), "the second query should be served from the cache")
+	assert.Equal(t, "pipeline/1.0", requests[0].Header.Get("User-Agent"))
+	assert.Equal(t, "quant", requests[0].Header.Get("X-Team"))
+	assert.Equal(t, "session=42", requests[0].Header.Get("Cookie"))
+	assert.Equal(t, 1, limiter.waits)
+	assert.Equal(t, 1, strings.Count(b.String(), "stk_quote_result.php"))
+}
+
+func TestNewClient_WithRetry(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+	s.SetFault("", fakeexchange.FaultHTMLError)
+
+	client := tpex.NewClient(0, tpex.WithBaseURL(s.URL), tpex.WithRetry(tkthttp.RetryPolicy{MaxAttempts: 3}))
+	_, err := client.FetchDayQuotes(calendar.Date(2021, 3, 24))
+
+	assert.True(t, errors.Is(err, exchangeerr.ErrMaintenance))
+	assert.Equal(t, 3, len(s.Requests()))
+	assert.True(t, strings.Contains(s.Requests()[0].Header.Get("User-Agent"), "tshakutshai"))
+}
+
+func TestNewClient_WithObserver(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	var events []tkthttp.Event
+	observer := tkthttp.ObserverFunc(func(e tkthttp.Event) { events = append(events, e) })
+
+	client := tpex.NewClient(0, tpex.WithBaseURL(s.URL), tpex.WithObserver(observer))
+	_, err := client.FetchYearlyQuotes("8044")
+	assert.Nil(t, err)
+
+	assert.Equal(t, 5, len(events))
+	assert.Equal(t, "POST", events[0].Method)
+	assert.Equal(t, tkthttp.EventThrottle, events[1].Kind)
+	assert.Equal(t, "text/csv", events[2].ContentType)
+	assert.Equal(t, tkthttp.EventParse, events[3].Kind)
+	assert.NotZero(t, events[3].Bytes)
+	assert.Equal(t, tkthttp.EventEnd, events[4].Kind)
+	assert.Nil(t, events[4].Err)
+}
+
+func TestNewClient_WithStrict(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	s.AddDayQuotes(fakeexchange.MarketTPEx, quote.Day{Code: "2330", Date: calendar.Date(2021, 2, 1)})
+	s.AddYearlyQuotes(fakeexchange.MarketTPEx, quote.Yearly{Code: "2330", Year: 2021})
+
+	lenient := tpex.NewClient(0, tpex.WithBaseURL(s.URL))
+	qs, err := lenient.FetchDailyQuotes("2330", 2020, time.January)
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Day{}, qs)
+
+	// Today is a holiday, e.g. closed by a typhoon, rather than not yet published.
+	today := calendar.Today(time.Now())
+	cal := calendar.New(nil)
+	cal.Load(2021, []calendar.Holiday{{Date: calendar.Date(2021, 2, 10), Name: "春節"}})
+	cal.AddClosure(today, "颱風")
+
+	client := tpex.NewClient(0, tpex.WithBaseURL(s.URL), tpex.WithStrict(), tpex.WithCalendar(cal))
+
+	dayQuotes, err := client.FetchDayQuotes(calendar.Date(2021, 2, 1))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(dayQuotes))
+
+	for _, tc := range []struct {
+		fetch  func() error
+		reason exchangeerr.NoDataReason
+	}{
+		{func() error { _, err := client.FetchDayQuotes(calendar.Date(2021, 2, 6)); return err }, exchangeerr.ReasonNonTradingDay},
+		{func() error { _, err := client.FetchDayQuotes(calendar.Date(2021, 2, 10)); return err }, exchangeerr.ReasonNonTradingDay},
+		{func() error { _, err := client.FetchDayQuotes(calendar.Date(2100, 1, 4)); return err }, exchangeerr.ReasonNotYetPublished},
+		{func() error { _, err := client.FetchDayQuotes(calendar.Date(2021, 2, 9)); return err }, exchangeerr.ReasonUndetermined},
+		{func() error { _, err := client.FetchDayQuotes(today); return err }, exchangeerr.ReasonNonTradingDay},
+		{func() error { _, err := client.FetchDailyQuotes("2330", 2020, time.January); return err }, exchangeerr.ReasonBeforeListing},
+		{func() error { _, err := client.FetchDailyQuotes("2330", 2100, time.January); return err }, exchangeerr.ReasonNotYetPublished},
+		{func() error { _, err := client.FetchDailyQuotes("9999", 2021, time.January); return err }, exchangeerr.ReasonUnknownCode},
+		{func() error { _, err := client.FetchMonthlyQuotes("2330", 2021); return err }, exchangeerr.ReasonUndetermined},
+		{func() error { _, err := client.FetchYearlyQuotes("9999"); return err }, exchangeerr.ReasonUnknownCode},
+	} {
+		err := tc.fetch()
+		assert.True(t, errors.Is(err, exchangeerr.ErrNoData))
+
+		var e *tpex.NoDataError
+		if assert.True(t, errors.As(err, &e)) {
+			assert.Equalf(t, tc.reason, e.Reason, "%s", e)
+		}
+	}
+}
+
+func TestNewClient_WithLanguage(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	q := quote.Day{Code: "8044", Name: "網家", EnglishName: "PChome Online Inc.", Date: calendar.Date(2021, 2, 1),
+		Close: price.MustParse("86.10"), Volume: 6_092_000}
+	s.AddDayQuotes(fakeexchange.MarketTPEx, q)
+
+	client := tpex.NewClient(0, tpex.WithBaseURL(s.URL), tpex.WithLanguage(tpex.LanguageEnglish))
+
+	q.Name = ""
+	dayQuotes, err := client.FetchDayQuotes(calendar.Date(2021, 2, 1))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]quote.Day{"8044": q}, dayQuotes)
+
+	dailyQuotes, err := client.FetchDailyQuotes("8044", 2021, time.February)
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Day{q}, dailyQuotes)
+
+	for _, r := range s.Requests() {
+		assert.Equal(t, "en-us", r.Params.Get("l"))
+	}
+}
@@ -0,0 +1,276 @@
+package tpex
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/chehsunliu/tshakutshai/pkg/internal/jsonstream"
+)
+
+var invalidCsvChars = regexp.MustCompile(`[a-zA-Z]+`)
+
+// column is a column of the rows of an endpoint.
+type column struct {
+	name string
+	// header is the CSV header of the column, compared after normalized by normalizeHeader. The JSON
+	// endpoints give no headers.
+	header string
+}
+
+// schema is the layout of the rows of an endpoint, by which the values are read by the names of the columns
+// rather than the positions, so that a change of the layout fails with SchemaMismatchError instead of
+// shifting values into the wrong fields. The JSON endpoints are checked by the numbers of columns, and the
+// CSV ones by the headers as well.
+type schema struct {
+	endpoint string
+	columns  []column
+	indices  map[string]int
+}
+
+func newSchema(endpoint string, columns ...column) *schema {
+	s := &schema{endpoint: endpoint, columns: columns, indices: map[string]int{}}
+	for i, c := range columns {
+		s.indices[c.name] = i
+	}
+	return s
+}
+
+var dayQuotesSchema = newSchema("stk_quote_result.php",
+	column{name: "code"}, column{name: "name"}, column{name: "close"}, column{name: "change"},
+	column{name: "open"}, column{name: "high"}, column{name: "low"}, column{name: "average"},
+	column{name: "volume"}, column{name: "value"}, column{name: "transactions"},
+	column{name: "bid"}, column{name: "bidVolume"}, column{name: "ask"}, column{name: "askVolume"},
+	column{name: "shares"}, column{name: "nextReference"}, column{name: "nextLimitUp"},
+	column{name: "nextLimitDown"},
+)
+
+// dailyQuotesSchema has the volumes and the values in thousands.
+var dailyQuotesSchema = newSchema("st43_result.php",
+	column{name: "date"}, column{name: "volume"}, column{name: "value"}, column{name: "open"},
+	column{name: "high"}, column{name: "low"}, column{name: "close"}, column{name: "change"},
+	column{name: "transactions"},
+)
+
+var afterHoursTradesSchema = newSchema("fixed_price_result.php",
+	column{name: "code"}, column{name: "name"}, column{name: "price"}, column{name: "volume"},
+	column{name: "transactions"}, column{name: "value"},
+)
+
+var blockTradesSchema = newSchema("block_day_result.php",
+	column{name: "code"}, column{name: "name"}, column{name: "method"}, column{name: "price"},
+	column{name: "volume"}, column{name: "value"},
+)
+
+var monthlyQuotesSchema = newSchema("download_st44.php",
+	column{"year", "Year"},
+	column{"month", "Month"},
+	column{"high", "Highest price"},
+	column{"low", "Lowest price"},
+	column{"average", "Average closing price"},
+	column{"transactions", "Number of transactions"},
+	column{"value", `Trading Value (NTD, in thousands) (A)`},
+	column{"volume", "Number shares (in thousands) (B)"},
+	column{"turnover", "Turnover ratio (%)"},
+)
+
+var yearlyQuotesSchema = newSchema("download_st42.php",
+	column{"year", "Year"},
+	column{"volume", "Number of thousand shares traded"},
+	column{"value", "Amount (NTD, in thousands)"},
+	column{"transactions", "Number of transactions (in thousands)"},
+	column{"high", "Highest price"},
+	column{"dateOfHigh", "Date"},
+	column{"low", "Lowest price"},
+	column{"dateOfLow", "Date"},
+	column{"average", "Average price"},
+)
+
+// row is a row of an endpoint, whose values are read by the names of the columns.
+type row struct {
+	schema *schema
+	values []string
+}
+
+// get returns the value of the column. It panics if the schema has no such column, which is a bug.
+func (r row) get(name string) string {
+	i, ok := r.schema.indices[name]
+	if !ok {
+		panic(fmt.Sprintf("%s has no column '%s'", r.schema.endpoint, name))
+	}
+	return r.values[i]
+}
+
+func (s *schema) mismatch(format string, a ...interface{}) error {
+	return &SchemaMismatchError{Endpoint: s.endpoint, Message: fmt.Sprintf(format, a...)}
+}
+
+// parseJSON returns the rows under key of rawData. The numbers of columns of the rows, and colNum if given,
+// must match s.
+func (s *schema) parseJSON(rawData map[string]json.RawMessage, key string) ([]row, error) {
+	if rawColNum, ok := rawData["colNum"]; ok {
+		var colNum int
+		if err := json.Unmarshal(rawColNum, &colNum); err != nil {
+			return nil, &ParseError{fmt.Sprintf("failed to decode colNum: %s", err), err}
+		}
+		// colNum is zero if there are no rows.
+		if colNum != 0 && colNum != len(s.columns) {
+			return nil, s.mismatch("expected %d columns but colNum is %d", len(s.columns), colNum)
+		}
+	}
+
+	rawItems, ok := rawData[key]
+	if !ok {
+		return nil, s.mismatch("key '%s' does not exist", key)
+	}
+
+	var items [][]string
+	if err := json.Unmarshal(rawItems, &items); err != nil {
+		return nil, &ParseError{fmt.Sprintf("failed to decode %s: %s", key, err), err}
+	}
+
+	return s.rows(items)
+}
+
+// decodeJSON decodes the object from r and calls fn with the rows under key in order, one at a time,
+// checked as parseJSON does. The values of the row passed to fn are overwritten by the next rows. Errors
+// returned by fn are returned as they are.
+func (s *schema) decodeJSON(r io.Reader, key string, fn func(r row) error) error {
+	dec := json.NewDecoder(r)
+
+	var fnErr error
+	found := false
+	values := make([]string, 0, len(s.columns))
+
+	err := jsonstream.Object(dec, func(k string) (bool, error) {
+		switch k {
+		case "colNum":
+			var colNum int
+			if err := dec.Decode(&colNum); err != nil {
+				return true, err
+			}
+			// colNum is zero if there are no rows.
+			if colNum != 0 && colNum != len(s.columns) {
+				return true, s.mismatch("expected %d columns but colNum is %d", len(s.columns), colNum)
+			}
+			return true, nil
+		case key:
+			found = true
+			return true, jsonstream.Array(dec, func() error {
+				values = values[:0]
+				if err := dec.Decode(&values); err != nil {
+					return err
+				}
+				if len(values) != len(s.columns) {
+					return s.mismatch("expected %d columns but got %d in %q", len(s.columns), len(values), values)
+				}
+
+				fnErr = fn(row{s, values})
+				return fnErr
+			})
+		default:
+			return false, nil
+		}
+	})
+	if err != nil {
+		var e *SchemaMismatchError
+		if err == fnErr || errors.As(err, &e) {
+			return err
+		}
+		return &ParseError{fmt.Sprintf("failed to decode JSON: %s", err), err}
+	}
+
+	if !found {
+		return s.mismatch("key '%s' does not exist", key)
+	}
+
+	return nil
+}
+
+// parseCSV returns the rows of the table in text, which follow the header line of the table until a blank
+// line or another table. The header must match s.
+func (s *schema) parseCSV(text string) ([]row, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	start := -1
+	for i, line := range lines {
+		if normalizeHeader(strings.SplitN(line, ",", 2)[0]) == normalizeHeader(s.columns[0].header) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, s.mismatch("header '%s' not found", s.columns[0].header)
+	}
+
+	headers := splitHeader(lines[start])
+	if len(headers) != len(s.columns) {
+		return nil, s.mismatch("expected %d columns but got headers %q", len(s.columns), headers)
+	}
+	for i, header := range headers {
+		if normalizeHeader(header) != normalizeHeader(s.columns[i].header) {
+			return nil, s.mismatch("expected header '%s' of column %d but got '%s'", s.columns[i].header, i, header)
+		}
+	}
+
+	end := start + 1
+	for end < len(lines) && strings.TrimSpace(lines[end]) != "" && !invalidCsvChars.MatchString(lines[end]) {
+		end++
+	}
+
+	reader := csv.NewReader(strings.NewReader(strings.Join(lines[start+1:end], "\n")))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, &ParseError{fmt.Sprintf("failed to read CSV: %s", err), err}
+	}
+
+	return s.rows(records)
+}
+
+func (s *schema) rows(items [][]string) ([]row, error) {
+	rows := make([]row, 0, len(items))
+	for _, item := range items {
+		if len(item) != len(s.columns) {
+			return nil, s.mismatch("expected %d columns but got %d in %q", len(s.columns), len(item), item)
+		}
+		rows = append(rows, row{s, item})
+	}
+
+	return rows, nil
+}
+
+// splitHeader splits the header line by the commas outside quotes. The CSV package rejects the header of
+// the monthly quotes, in which a quoted field is followed by more text, e.g. `"Trading Value (NTD, in
+// thousands)" (A)`.
+func splitHeader(line string) []string {
+	headers := make([]string, 0)
+	quoted, start := false, 0
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			headers = append(headers, line[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(headers, line[start:])
+}
+
+// normalizeHeader drops the spaces, quotes and punctuation of header, and lowers the cases, since the TPEx
+// is not consistent in them, e.g. `"Amount (NTD, in thousands)" ` and `Amount (NTD, in thousands)`.
+func normalizeHeader(header string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, header)
+}
@@ -0,0 +1,61 @@
+package tpex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+)
+
+// decodeDayQuotes decodes stk_quote_result from r and calls fn with the quotes in order, one row at a
+// time. The quote passed to fn is reused for the next rows.
+func (c *Client) decodeDayQuotes(r io.Reader, date time.Time, fn func(q *DayQuote) error) error {
+	var q DayQuote
+	return dayQuotesSchema.decodeJSON(r, "aaData", func(r row) error {
+		var conv converter
+		q = DayQuote{
+			Code:         r.get("code"),
+			Date:         date,
+			Volume:       conv.uint64(r, "volume"),
+			Transactions: conv.uint64(r, "transactions"),
+			Value:        conv.uint64(r, "value"),
+			High:         conv.price(r, "high"),
+			Low:          conv.price(r, "low"),
+			Open:         conv.price(r, "open"),
+			Close:        conv.price(r, "close"),
+		}
+		if conv.err != nil {
+			return conv.err
+		}
+		c.setName(&q, r.get("name"))
+		return fn(&q)
+	})
+}
+
+// streamDayQuotes queries stk_quote_result and calls fn with the quotes on date in the order of the TPEx
+// without decoding the whole document. The body is still read at once to tell error pages apart, see do.
+func (c *Client) streamDayQuotes(date time.Time, fn func(q *DayQuote) error) error {
+	rawQuery := url.Values{}
+	rawQuery.Set("d", fmt.Sprintf("%d/%s", date.Year()-1911, date.Format("01/02")))
+	rawQuery.Set("l", c.locale())
+
+	body, t, err := c.get("/web/stock/aftertrading/daily_close_quotes/stk_quote_result.php", rawQuery)
+	if err != nil {
+		return err
+	}
+
+	var fnErr error
+	err = c.decodeDayQuotes(bytes.NewReader(body), date, func(q *DayQuote) error {
+		fnErr = fn(q)
+		return fnErr
+	})
+
+	// Errors of fn are not of parsing.
+	if err != nil && err == fnErr {
+		t.Parse(nil)
+	} else {
+		t.Parse(err)
+	}
+	return err
+}
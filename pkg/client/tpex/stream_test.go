@@ -0,0 +1,140 @@
+package tpex
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/tkttest"
+)
+
+func readFixture(tb testing.TB, filepath string) []byte {
+	resp := tkttest.NewJsonResponseFromGzipFile(filepath, 200)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return body
+}
+
+// decodeDayQuotesByMaps is the former way to decode stk_quote_result, which decodes the whole document
+// before converting the rows, kept to compare with decodeDayQuotes.
+func decodeDayQuotesByMaps(c *Client, body []byte) []DayQuote {
+	rawData := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &rawData); err != nil {
+		panic(err)
+	}
+
+	rows, err := dayQuotesSchema.parseJSON(rawData, "aaData")
+	if err != nil {
+		panic(err)
+	}
+
+	date := calendar.Date(2021, 3, 30)
+	qs := make([]DayQuote, 0, len(rows))
+	for _, r := range rows {
+		var conv converter
+		q := DayQuote{
+			Code:         r.get("code"),
+			Date:         date,
+			Volume:       conv.uint64(r, "volume"),
+			Transactions: conv.uint64(r, "transactions"),
+			Value:        conv.uint64(r, "value"),
+			High:         conv.price(r, "high"),
+			Low:          conv.price(r, "low"),
+			Open:         conv.price(r, "open"),
+			Close:        conv.price(r, "close"),
+		}
+		if conv.err != nil {
+			panic(conv.err)
+		}
+		c.setName(&q, r.get("name"))
+		qs = append(qs, q)
+	}
+
+	return qs
+}
+
+func TestClient_DecodeDayQuotes(t *testing.T) {
+	c := &Client{}
+	body := readFixture(t, "./testdata/quotes-tw-20210330.json.gz")
+
+	var qs []DayQuote
+	err := c.decodeDayQuotes(bytes.NewReader(body), calendar.Date(2021, 3, 30), func(q *DayQuote) error {
+		qs = append(qs, *q)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, decodeDayQuotesByMaps(c, body), qs)
+
+	// The callback stops decoding with its error.
+	errStop := errors.New("stop")
+	n := 0
+	err = c.decodeDayQuotes(bytes.NewReader(body), calendar.Date(2021, 3, 30), func(q *DayQuote) error {
+		n++
+		if n == 3 {
+			return errStop
+		}
+		return nil
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, 3, n)
+}
+
+func TestClient_DecodeDayQuotesWithErrors(t *testing.T) {
+	c := &Client{}
+	for body, target := range map[string]error{
+		`{"colNum":18,"aaData":[]}`:         exchangeerr.ErrSchemaMismatch,
+		`{"colNum":19,"aaData":[["8044"]]}`: exchangeerr.ErrSchemaMismatch,
+		`{"colNum":0}`:                      exchangeerr.ErrSchemaMismatch,
+		`{"colNum":19,"aaData":[[`:          exchangeerr.ErrParse,
+		`["aaData",[]]`:                     exchangeerr.ErrParse,
+	} {
+		err := c.decodeDayQuotes(bytes.NewReader([]byte(body)), calendar.Date(2021, 3, 30), func(q *DayQuote) error {
+			return nil
+		})
+		assert.Truef(t, errors.Is(err, target), "%s: %v", body, err)
+	}
+}
+
+func BenchmarkDecodeDayQuotes_Maps(b *testing.B) {
+	c := &Client{}
+	body := readFixture(b, "./testdata/quotes-tw-20210330.json.gz")
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		qs := map[string]DayQuote{}
+		for _, q := range decodeDayQuotesByMaps(c, body) {
+			qs[q.Code] = q
+		}
+	}
+}
+
+func BenchmarkDecodeDayQuotes_Stream(b *testing.B) {
+	c := &Client{}
+	body := readFixture(b, "./testdata/quotes-tw-20210330.json.gz")
+	date := calendar.Date(2021, 3, 30)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		qs := map[string]DayQuote{}
+		err := c.decodeDayQuotes(bytes.NewReader(body), date, func(q *DayQuote) error {
+			qs[q.Code] = *q
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
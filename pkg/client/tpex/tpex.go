@@ -1,110 +1,293 @@
 package tpex
 
 import (
-	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
 	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/baseurl"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/clientopt"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/htmlpage"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/namefill"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/nodata"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/quotejson"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/trace"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
 )
 
-var invalidCsvChars = regexp.MustCompile(`[a-zA-Z]+`)
+// DayQuote is the quote of a stock on a trading day, returned by Client.FetchDayQuotes and
+// Client.FetchDailyQuotes.
+type DayQuote = quote.Day
 
+// MonthlyQuote is the aggregate of a stock in a month, returned by Client.FetchMonthlyQuotes.
+type MonthlyQuote = quote.Monthly
+
+// YearlyQuote is the aggregate of a stock in a year, returned by Client.FetchYearlyQuotes.
+type YearlyQuote = quote.Yearly
+
+// ErrStop stops EachDayQuote early, see quote.ErrStop.
+var ErrStop = quote.ErrStop
+
+// Quote is the basic unit formerly returned by all the Fetch functions. Open and Close are only
+// meaningful in day quotes, and DateOfHigh and DateOfLow in yearly quotes. All dates are the midnight in
+// Asia/Taipei, see calendar.Date.
+//
+// Deprecated: Use DayQuote, MonthlyQuote and YearlyQuote instead. Code still depending on Quote can
+// convert the new types with QuoteFromDay, QuoteFromMonthly and QuoteFromYearly.
 type Quote struct {
-	Code         string
-	Name         string
-	Date         time.Time
-	Volume       uint64
-	Transactions uint64
-	Value        uint64
-	High         float64
-	Low          float64
-	Open         float64
-	Close        float64
-	DateOfHigh   time.Time
-	DateOfLow    time.Time
+	Code         string      `json:"code"`
+	Name         string      `json:"name,omitempty"`
+	Date         time.Time   `json:"date"`
+	Volume       uint64      `json:"volume"`
+	Transactions uint64      `json:"transactions"`
+	Value        uint64      `json:"value"`
+	High         price.Price `json:"high"`
+	Low          price.Price `json:"low"`
+	Open         price.Price `json:"open"`
+	Close        price.Price `json:"close"`
+	DateOfHigh   time.Time   `json:"date_of_high"`
+	DateOfLow    time.Time   `json:"date_of_low"`
+}
+
+// MarshalJSON encodes q with the snake_case keys in its tags and dates as YYYY-MM-DD. All the keys are
+// present whatever the values are; DateOfHigh and DateOfLow, which only apply to yearly quotes, are null
+// if zero.
+func (q Quote) MarshalJSON() ([]byte, error) {
+	return quotejson.Marshal(quotejson.Quote(q))
+}
+
+// QuoteFromDay converts q to the deprecated Quote.
+func QuoteFromDay(q DayQuote) Quote {
+	return Quote{
+		Code:         q.Code,
+		Name:         q.Name,
+		Date:         q.Date,
+		Volume:       q.Volume,
+		Transactions: q.Transactions,
+		Value:        q.Value,
+		High:         q.High,
+		Low:          q.Low,
+		Open:         q.Open,
+		Close:        q.Close,
+	}
+}
+
+// QuoteFromMonthly converts q to the deprecated Quote, whose Date is the first day of the month.
+func QuoteFromMonthly(q MonthlyQuote) Quote {
+	return Quote{
+		Code:         q.Code,
+		Date:         q.Date(),
+		Volume:       q.Volume,
+		Transactions: q.Transactions,
+		Value:        q.Value,
+		High:         q.High,
+		Low:          q.Low,
+	}
+}
+
+// QuoteFromYearly converts q to the deprecated Quote, whose Date is the first day of the year.
+func QuoteFromYearly(q YearlyQuote) Quote {
+	return Quote{
+		Code:         q.Code,
+		Date:         q.Date(),
+		Volume:       q.Volume,
+		Transactions: q.Transactions,
+		Value:        q.Value,
+		High:         q.High,
+		Low:          q.Low,
+		DateOfHigh:   q.DateOfHigh,
+		DateOfLow:    q.DateOfLow,
+	}
+}
+
+// UnmarshalJSON decodes the output of MarshalJSON. Dates are restored as the midnight in Asia/Taipei.
+func (q *Quote) UnmarshalJSON(data []byte) error {
+	return quotejson.Unmarshal(data, (*quotejson.Quote)(q))
 }
 
+// DefaultBaseURL is used if Client.BaseURL is empty.
+const DefaultBaseURL = "https://www.tpex.org.tw"
+
 type Client struct {
+	// HttpClient is the actual object that interacts with the TPEx server. It must not be nil; otherwise,
+	// Fetch functions return ErrNoClient.
 	HttpClient tkthttp.Client
+	// BaseURL replaces DefaultBaseURL for all the endpoints. It must be an http or https URL, optionally
+	// with a path prepended to the paths of the endpoints; otherwise, Fetch functions return an error.
+	BaseURL string
+	// Observer receives the events of every request if not nil, see tkthttp.Event.
+	Observer tkthttp.Observer
+	// Strict makes Fetch functions return NoDataError instead of empty results if nothing matches the
+	// query. Telling an unknown code from dates before listing takes one more request of FetchYearlyQuotes.
+	Strict bool
+	// Language is the language of the day and daily quotes.
+	Language Language
+	// NameResolver fills the empty names of the quotes if not nil. It must not use c itself.
+	NameResolver quote.NameResolver
+	// Calendar tells holidays from trading days without quotes for the reasons of NoDataError of Strict,
+	// e.g. calendar.New(twseClient), since the TPEx shares the holiday schedule of the TWSE. Only weekends
+	// are known to be closed if it is nil.
+	Calendar *calendar.Calendar
 }
 
-func NewClient(minInterval time.Duration) *Client {
-	return &Client{HttpClient: tkthttp.NewThrottledClient(&http.Client{}, minInterval)}
-}
+func NewClient(minInterval time.Duration, opts ...Option) *Client {
+	o := clientopt.New()
+	for _, opt := range opts {
+		opt.apply(o)
+	}
 
-func (c *Client) fetchJSON(p string, rawQuery url.Values) (map[string]json.RawMessage, error) {
-	if c.HttpClient == nil {
-		panic("Client.HttpClient should not be nil")
+	return &Client{
+		HttpClient:   o.HttpClient(minInterval),
+		BaseURL:      o.BaseURL,
+		Observer:     o.Observer,
+		Strict:       o.Strict,
+		Language:     Language(o.Language),
+		NameResolver: o.Names,
+		Calendar:     o.Calendar,
 	}
+}
 
-	u := url.URL{Scheme: "https", Host: "www.tpex.org.tw", Path: p, RawQuery: rawQuery.Encode()}
+func (c *Client) endpoint(p string, rawQuery url.Values) (string, error) {
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
 
-	req, err := http.NewRequest("GET", u.String(), nil)
+	base, err := baseurl.Parse(baseURL)
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
-	resp, err := c.HttpClient.Do(req)
+	return baseurl.Resolve(base, p, rawQuery), nil
+}
+
+func (c *Client) fetchJSON(p string, rawQuery url.Values) (map[string]json.RawMessage, error) {
+	body, t, err := c.get(p, rawQuery)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
 	rawData := map[string]json.RawMessage{}
-	if err := json.NewDecoder(resp.Body).Decode(&rawData); err != nil {
-		return nil, err
+	if err := json.Unmarshal(body, &rawData); err != nil {
+		t.Parse(err)
+		return nil, &ParseError{fmt.Sprintf("failed to decode JSON: %s", err), err}
 	}
 
+	t.Parse(nil)
 	return rawData, nil
 }
 
+// get sends a GET request and returns the body of the response, see do.
+func (c *Client) get(p string, rawQuery url.Values) ([]byte, *trace.Trace, error) {
+	if c.HttpClient == nil {
+		return nil, nil, ErrNoClient
+	}
+
+	u, err := c.endpoint(p, rawQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return c.do(req)
+}
+
 func (c *Client) fetchPlainText(p string, rawQuery, formValues url.Values) (string, error) {
 	if c.HttpClient == nil {
-		panic("Client.HttpClient should not be nil")
+		return "", ErrNoClient
 	}
 
-	u := url.URL{Scheme: "https", Host: "www.tpex.org.tw", Path: p, RawQuery: rawQuery.Encode()}
+	u, err := c.endpoint(p, rawQuery)
+	if err != nil {
+		return "", err
+	}
 
-	req, err := http.NewRequest("POST", u.String(), strings.NewReader(formValues.Encode()))
+	req, err := http.NewRequest("POST", u, strings.NewReader(formValues.Encode()))
 	if err != nil {
 		panic(err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := c.HttpClient.Do(req)
+	body, t, err := c.do(req)
 	if err != nil {
 		return "", err
 	}
+
+	t.Parse(nil)
+	return string(body), nil
+}
+
+// do sends req and returns the body of the response, or an error if it is not 200 OK or is an HTML page,
+// which the TPEx serves on errors and maintenance. The TPEx serves JSON as text/html sometimes, so pages
+// are told by their content.
+func (c *Client) do(req *http.Request) ([]byte, *trace.Trace, error) {
+	t, req := trace.Start(c.Observer, req)
+	resp, err := c.HttpClient.Do(req)
+	t.Response(resp, err)
+	if err != nil {
+		err = &ConnectionError{fmt.Sprintf("failed to query: %s", err)}
+		t.End(err)
+		return nil, t, err
+	}
 	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := ioutil.ReadAll(t.Body(resp.Body))
 	if err != nil {
-		panic(err)
+		t.Parse(err)
+		return nil, t, &ConnectionError{fmt.Sprintf("failed to read the response: %s", err)}
 	}
 
-	return string(body), nil
+	if err := checkResponse(resp, body); err != nil {
+		t.Parse(err)
+		return nil, t, err
+	}
+
+	return body, t, nil
 }
 
-func (c *Client) fetchDayQuotes(date time.Time) (map[string]json.RawMessage, error) {
-	rawQuery := url.Values{}
-	rawQuery.Set("d", fmt.Sprintf("%d/%s", date.Year()-1911, date.Format("01/02")))
-	rawQuery.Set("l", "zh-tw")
-	return c.fetchJSON("/web/stock/aftertrading/daily_close_quotes/stk_quote_result.php", rawQuery)
+func checkResponse(resp *http.Response, body []byte) error {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden {
+		return &QuotaExceededError{fmt.Sprintf("received status %d", resp.StatusCode)}
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	e := &UnexpectedContentError{StatusCode: resp.StatusCode, ContentType: contentType}
+
+	switch {
+	case htmlpage.Is(body) && htmlpage.IsMaintenance(resp.StatusCode, body):
+		e.Message = fmt.Sprintf("received maintenance page with status %d", resp.StatusCode)
+		e.Maintenance = true
+	case htmlpage.Is(body):
+		e.Message = fmt.Sprintf("received HTML page with status %d", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		e.Message = fmt.Sprintf("received status %d", resp.StatusCode)
+	default:
+		return nil
+	}
+
+	return e
 }
 
 func (c *Client) fetchDailyQuotes(code string, year int, month time.Month) (map[string]json.RawMessage, error) {
-	date := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	date := calendar.Date(year, month, 1)
 	rawQuery := url.Values{}
 	rawQuery.Set("d", fmt.Sprintf("%d/%s", date.Year()-1911, date.Format("01/02")))
-	rawQuery.Set("l", "zh-tw")
+	rawQuery.Set("l", c.locale())
 	rawQuery.Set("stkno", code)
 	return c.fetchJSON("/web/stock/aftertrading/daily_trading_info/st43_result.php", rawQuery)
 }
@@ -126,225 +309,230 @@ func (c *Client) fetchYearlyQuotes(code string) (string, error) {
 	return c.fetchPlainText("/web/stock/statistics/monthly/download_st42.php", rawQuery, formValues)
 }
 
-func (c *Client) FetchDayQuotes(date time.Time) (map[string]Quote, error) {
-	rawData, err := c.fetchDayQuotes(date)
+func (c *Client) FetchDayQuotes(date time.Time) (map[string]DayQuote, error) {
+	qs := map[string]DayQuote{}
+	err := c.EachDayQuote(date, func(q DayQuote) error {
+		qs[q.Code] = q
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	qs := map[string]Quote{}
-
-	items := deserializeSliceOfSlicesOfStrings(rawData, "aaData")
-	for _, item := range items {
-		q := Quote{
-			Code:         item[0],
-			Name:         item[1],
-			Date:         date,
-			Volume:       stringToUint64(item[8]),
-			Transactions: stringToUint64(item[10]),
-			Value:        stringToUint64(item[9]),
-			High:         stringToFloat64(item[5]),
-			Low:          stringToFloat64(item[6]),
-			Open:         stringToFloat64(item[4]),
-			Close:        stringToFloat64(item[2]),
-		}
-		qs[q.Code] = q
-	}
-
 	return qs, nil
 }
 
-func (c *Client) FetchDailyQuotes(code string, year int, month time.Month) ([]Quote, error) {
-	rawData, err := c.fetchDailyQuotes(code, year, month)
+// FetchDayQuotesInOrder is FetchDayQuotes returning the quotes in the order of the TPEx, see EachDayQuote.
+func (c *Client) FetchDayQuotesInOrder(date time.Time) ([]DayQuote, error) {
+	qs := make([]DayQuote, 0)
+	err := c.EachDayQuote(date, func(q DayQuote) error {
+		qs = append(qs, q)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	qs := make([]Quote, 0)
-
-	items := deserializeSliceOfSlicesOfStrings(rawData, "aaData")
-	for _, item := range items {
-		q := Quote{
-			Code:         code,
-			Name:         deserializeString(rawData, "stkName"),
-			Date:         stringToDate(item[0]),
-			Volume:       stringToUint64(item[1]) * 1000,
-			Transactions: stringToUint64(item[8]),
-			Value:        stringToUint64(item[2]) * 1000,
-			Open:         stringToFloat64(item[3]),
-			Close:        stringToFloat64(item[6]),
-			High:         stringToFloat64(item[4]),
-			Low:          stringToFloat64(item[5]),
-		}
-		qs = append(qs, q)
-	}
-
 	return qs, nil
 }
 
-func filterOutInvalidLines(text string) string {
-	rawTextSplit := strings.Split(text, "\n")
-	dataLines := make([]string, 0)
-
-	isInAddingDataLineStage := false
-	for _, line := range rawTextSplit {
-		isMatched := invalidCsvChars.MatchString(line)
-
-		if !isInAddingDataLineStage {
-			if isMatched {
-				continue
-			} else {
-				isInAddingDataLineStage = true
-			}
-		} else if isMatched {
-			break
+// EachDayQuote calls fn with the quotes on date one at a time in the order of the TPEx, i.e. grouped by
+// the categories, e.g. ETFs before stocks, without holding all of them. fn returns ErrStop to stop early,
+// and other errors of fn are returned as they are. As FetchDayQuotes, fn is never called if the TPEx
+// provides no quotes on date, which is NoDataError only if Strict is set.
+func (c *Client) EachDayQuote(date time.Time, fn func(q DayQuote) error) error {
+	date = calendar.Normalize(date)
+
+	var fnErr error
+	n := 0
+	err := c.streamDayQuotes(date, func(q *DayQuote) error {
+		if err := namefill.Day(c.NameResolver, q); err != nil {
+			return err
 		}
+		n++
+		fnErr = fn(*q)
+		return fnErr
+	})
+	if err != nil {
+		if err == fnErr && errors.Is(err, ErrStop) {
+			return nil
+		}
+		return err
+	}
 
-		dataLines = append(dataLines, line)
+	if n == 0 && c.Strict {
+		return &NoDataError{fmt.Sprintf("no quotes on %s", date.Format("2006-01-02")), nodata.OfDay(date, time.Now(), c.Calendar)}
 	}
 
-	return strings.Join(dataLines, "\n")
+	return nil
 }
 
-func convertRawMonthlyQuote(code string, raw []string) Quote {
-	year, err := strconv.Atoi(raw[0])
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse year %s: %s", raw[0], err))
-	}
-
-	month, err := strconv.Atoi(raw[1])
+func (c *Client) FetchDailyQuotes(code string, year int, month time.Month) ([]DayQuote, error) {
+	rawData, err := c.fetchDailyQuotes(code, year, month)
 	if err != nil {
-		panic(fmt.Sprintf("failed to parse year %s: %s", raw[1], err))
+		return nil, err
 	}
 
-	high, err := strconv.ParseFloat(raw[2], 64)
+	rows, err := dailyQuotesSchema.parseJSON(rawData, "aaData")
 	if err != nil {
-		panic(fmt.Sprintf("failed to parse high %s: %s", raw[2], err))
+		return nil, err
 	}
 
-	low, err := strconv.ParseFloat(raw[3], 64)
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse low %s: %s", raw[3], err))
-	}
+	qs := make([]DayQuote, 0)
+	for _, r := range rows {
+		var conv converter
+		q := DayQuote{
+			Code:         code,
+			Date:         conv.date(r, "date"),
+			Volume:       conv.uint64(r, "volume") * 1000,
+			Transactions: conv.uint64(r, "transactions"),
+			Value:        conv.uint64(r, "value") * 1000,
+			Open:         conv.price(r, "open"),
+			Close:        conv.price(r, "close"),
+			High:         conv.price(r, "high"),
+			Low:          conv.price(r, "low"),
+		}
+		if conv.err != nil {
+			return nil, conv.err
+		}
 
-	transactions, err := strconv.ParseUint(strings.ReplaceAll(raw[5], ",", ""), 10, 64)
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse transactions %s: %s", raw[5], err))
+		name, err := deserializeString(rawData, "stkName")
+		if err != nil {
+			return nil, err
+		}
+		c.setName(&q, name)
+		if err := namefill.Day(c.NameResolver, &q); err != nil {
+			return nil, err
+		}
+		qs = append(qs, q)
 	}
 
-	value, err := strconv.ParseUint(strings.ReplaceAll(raw[6], ",", ""), 10, 64)
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse value %s: %s", raw[6], err))
+	if len(qs) == 0 && c.Strict {
+		from := calendar.Date(year, month, 1)
+		return nil, c.explainNoData(code, from, from.AddDate(0, 1, 0))
 	}
 
-	volume, err := strconv.ParseUint(strings.ReplaceAll(raw[7], ",", ""), 10, 64)
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse volume %s: %s", raw[7], err))
-	}
+	return qs, nil
+}
 
-	return Quote{
+func convertRawMonthlyQuote(code string, r row) (MonthlyQuote, error) {
+	var c converter
+	q := MonthlyQuote{
 		Code:         code,
-		Date:         time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC),
-		Volume:       volume * 1000,
-		Transactions: transactions,
-		Value:        value * 1000,
-		High:         high,
-		Low:          low,
+		Year:         c.int(r, "year"),
+		Month:        time.Month(c.int(r, "month")),
+		Volume:       c.uint64(r, "volume") * 1000,
+		Transactions: c.uint64(r, "transactions"),
+		Value:        c.uint64(r, "value") * 1000,
+		High:         c.price(r, "high"),
+		Low:          c.price(r, "low"),
 	}
+
+	return q, c.err
 }
 
-func (c *Client) FetchMonthlyQuotes(code string, year int) ([]Quote, error) {
+func (c *Client) FetchMonthlyQuotes(code string, year int) ([]MonthlyQuote, error) {
 	rawText, err := c.fetchMonthlyQuotes(code, year)
 	if err != nil {
 		return nil, err
 	}
 
-	reader := csv.NewReader(strings.NewReader(filterOutInvalidLines(rawText)))
-	records, err := reader.ReadAll()
+	rows, err := monthlyQuotesSchema.parseCSV(rawText)
 	if err != nil {
 		return nil, err
 	}
 
-	qs := make([]Quote, 0)
+	qs := make([]MonthlyQuote, 0)
+
+	for _, r := range rows {
+		q, err := convertRawMonthlyQuote(code, r)
+		if err != nil {
+			return nil, err
+		}
+		if err := namefill.Monthly(c.NameResolver, &q); err != nil {
+			return nil, err
+		}
+		qs = append(qs, q)
+	}
 
-	for _, record := range records {
-		qs = append(qs, convertRawMonthlyQuote(code, record))
+	if len(qs) == 0 && c.Strict {
+		from := calendar.Date(year, time.January, 1)
+		return nil, c.explainNoData(code, from, from.AddDate(1, 0, 0))
 	}
 
 	return qs, nil
 }
 
-func convertRawYearlyQuote(code string, raw []string) Quote {
-	year, err := strconv.Atoi(raw[0])
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse year %s: %s", raw[0], err))
-	}
-
-	volume, err := strconv.ParseUint(strings.ReplaceAll(raw[1], ",", ""), 10, 64)
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse volume %s: %s", raw[1], err))
+func convertRawYearlyQuote(code string, r row) (YearlyQuote, error) {
+	var c converter
+	year := c.int(r, "year")
+	q := YearlyQuote{
+		Code:         code,
+		Year:         year,
+		Volume:       c.uint64(r, "volume") * 1000,
+		Transactions: c.uint64(r, "transactions") * 1000,
+		Value:        c.uint64(r, "value") * 1000,
+		High:         c.price(r, "high"),
+		Low:          c.price(r, "low"),
+		DateOfHigh:   c.dayOfYear(r, "dateOfHigh", year),
+		DateOfLow:    c.dayOfYear(r, "dateOfLow", year),
 	}
 
-	value, err := strconv.ParseUint(strings.ReplaceAll(raw[2], ",", ""), 10, 64)
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse value %s: %s", raw[2], err))
-	}
+	return q, c.err
+}
 
-	transactions, err := strconv.ParseUint(strings.ReplaceAll(raw[3], ",", ""), 10, 64)
+func (c *Client) FetchYearlyQuotes(code string) ([]YearlyQuote, error) {
+	rawText, err := c.fetchYearlyQuotes(code)
 	if err != nil {
-		panic(fmt.Sprintf("failed to parse transactions %s: %s", raw[3], err))
+		return nil, err
 	}
 
-	high, err := strconv.ParseFloat(raw[4], 64)
+	rows, err := yearlyQuotesSchema.parseCSV(rawText)
 	if err != nil {
-		panic(fmt.Sprintf("failed to parse high %s: %s", raw[4], err))
+		return nil, err
 	}
 
-	dateOfHigh, err := time.Parse("01/02", raw[5])
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse date of high %s: %s", raw[5], err))
-	}
+	qs := make([]YearlyQuote, 0)
 
-	low, err := strconv.ParseFloat(raw[6], 64)
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse low %s: %s", raw[6], err))
+	for _, r := range rows {
+		q, err := convertRawYearlyQuote(code, r)
+		if err != nil {
+			return nil, err
+		}
+		if err := namefill.Yearly(c.NameResolver, &q); err != nil {
+			return nil, err
+		}
+		qs = append(qs, q)
 	}
 
-	dateOfLow, err := time.Parse("01/02", raw[7])
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse date of higlowh %s: %s", raw[7], err))
+	if len(qs) == 0 && c.Strict {
+		return nil, &NoDataError{fmt.Sprintf("no quotes of %s", code), exchangeerr.ReasonUnknownCode}
 	}
 
-	return Quote{
-		Code:         code,
-		Date:         time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
-		Volume:       volume * 1000,
-		Transactions: transactions * 1000,
-		Value:        value * 1000,
-		High:         high,
-		Low:          low,
-		DateOfHigh:   time.Date(year, dateOfHigh.Month(), dateOfHigh.Day(), 0, 0, 0, 0, time.UTC),
-		DateOfLow:    time.Date(year, dateOfLow.Month(), dateOfLow.Day(), 0, 0, 0, 0, time.UTC),
-	}
+	return qs, nil
 }
 
-func (c *Client) FetchYearlyQuotes(code string) ([]Quote, error) {
-	rawText, err := c.fetchYearlyQuotes(code)
-	if err != nil {
-		return nil, err
-	}
+// explainNoData returns NoDataError of no quotes of code from from until to, exclusively, or the error of
+// the extra request to tell the reason.
+func (c *Client) explainNoData(code string, from, to time.Time) error {
+	reason, err := nodata.OfPeriod(from, to, time.Now(), func() ([]int, error) {
+		lenient := *c
+		lenient.Strict = false
+		qs, err := lenient.FetchYearlyQuotes(code)
+		if err != nil {
+			return nil, err
+		}
 
-	reader := csv.NewReader(strings.NewReader(filterOutInvalidLines(rawText)))
-	records, err := reader.ReadAll()
+		years := make([]int, 0, len(qs))
+		for _, q := range qs {
+			years = append(years, q.Year)
+		}
+		return years, nil
+	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	qs := make([]Quote, 0)
-
-	for _, record := range records {
-		qs = append(qs, convertRawYearlyQuote(code, record))
-	}
-
-	return qs, nil
+	message := fmt.Sprintf("no quotes of %s from %s to %s", code, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	return &NoDataError{message, reason}
 }
@@ -1,6 +1,8 @@
 package tpex_test
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"testing"
 	"time"
@@ -8,8 +10,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
 	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
 	"github.com/chehsunliu/tshakutshai/pkg/internal/tkttest"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
 )
 
 func TestClient_FetchDayQuotes(t *testing.T) {
@@ -33,17 +38,68 @@ func TestClient_FetchDayQuotes(t *testing.T) {
 	assert.Equal(t, "006201", q.Code)
 	assert.Equal(t, "元大富櫃50", q.Name)
 	assert.Equal(t, "20210330", q.Date.Format("20060102"))
+	assert.Equal(t, calendar.Date(2021, time.March, 30), q.Date)
 	assert.Equal(t, uint64(54_765), q.Volume)
 	assert.Equal(t, uint64(33), q.Transactions)
 	assert.Equal(t, uint64(1_062_607), q.Value)
-	assert.Equal(t, 19.51, q.High)
-	assert.Equal(t, 19.32, q.Low)
-	assert.Equal(t, 19.37, q.Open)
-	assert.Equal(t, 19.49, q.Close)
+	assert.Equal(t, price.MustParse("19.51"), q.High)
+	assert.Equal(t, price.MustParse("19.32"), q.Low)
+	assert.Equal(t, price.MustParse("19.37"), q.Open)
+	assert.Equal(t, price.MustParse("19.49"), q.Close)
 
 	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
 }
 
+func newDayQuotesClient() *tpex.Client {
+	mockResponse := tkttest.NewJsonResponseFromGzipFile("./testdata/quotes-tw-20210330.json.gz", 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.Anything).Return(mockResponse, nil)
+	return &tpex.Client{HttpClient: mockHttpClient}
+}
+
+func TestClient_FetchDayQuotesInOrder(t *testing.T) {
+	date := calendar.Date(2021, 3, 30)
+
+	qs, err := newDayQuotesClient().FetchDayQuotesInOrder(date)
+	assert.Nilf(t, err, "%+v", err)
+	assert.Equal(t, 6877, len(qs))
+
+	m, err := newDayQuotesClient().FetchDayQuotes(date)
+	assert.Nilf(t, err, "%+v", err)
+	for _, q := range qs {
+		assert.Equal(t, m[q.Code], q)
+	}
+
+	assert.Equal(t, "006201", qs[0].Code)
+}
+
+func TestClient_EachDayQuote(t *testing.T) {
+	date := calendar.Date(2021, 3, 30)
+
+	qs, err := newDayQuotesClient().FetchDayQuotesInOrder(date)
+	assert.Nilf(t, err, "%+v", err)
+
+	var stopped []tpex.DayQuote
+	err = newDayQuotesClient().EachDayQuote(date, func(q tpex.DayQuote) error {
+		stopped = append(stopped, q)
+		if len(stopped) == 3 {
+			return tpex.ErrStop
+		}
+		return nil
+	})
+	assert.Nil(t, err, "ErrStop should not be returned")
+	assert.Equal(t, qs[:3], stopped)
+
+	errSink := errors.New("sink failed")
+	n := 0
+	err = newDayQuotesClient().EachDayQuote(date, func(q tpex.DayQuote) error {
+		n++
+		return errSink
+	})
+	assert.Equal(t, errSink, err)
+	assert.Equal(t, 1, n)
+}
+
 func TestClient_FetchDailyQuotes(t *testing.T) {
 	code := "8044"
 	date := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
@@ -69,10 +125,10 @@ func TestClient_FetchDailyQuotes(t *testing.T) {
 	assert.Equal(t, uint64(834_000), q.Volume)
 	assert.Equal(t, uint64(780), q.Transactions)
 	assert.Equal(t, uint64(69_098_000), q.Value)
-	assert.Equal(t, 84.00, q.High)
-	assert.Equal(t, 82.20, q.Low)
-	assert.Equal(t, 83.20, q.Open)
-	assert.Equal(t, 82.30, q.Close)
+	assert.Equal(t, price.MustParse("84.00"), q.High)
+	assert.Equal(t, price.MustParse("82.20"), q.Low)
+	assert.Equal(t, price.MustParse("83.20"), q.Open)
+	assert.Equal(t, price.MustParse("82.30"), q.Close)
 
 	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
 }
@@ -94,13 +150,13 @@ func TestClient_FetchMonthlyQuotes(t *testing.T) {
 
 	q := qs[0]
 	assert.Equal(t, code, q.Code)
-	assert.Equal(t, "", q.Name)
-	assert.Equal(t, "20200101", q.Date.Format("20060102"))
+	assert.Equal(t, 2020, q.Year)
+	assert.Equal(t, time.January, q.Month)
 	assert.Equal(t, uint64(6_092_000), q.Volume)
 	assert.Equal(t, uint64(5_274), q.Transactions)
 	assert.Equal(t, uint64(564_646_000), q.Value)
-	assert.Equal(t, 96.40, q.High)
-	assert.Equal(t, 88.70, q.Low)
+	assert.Equal(t, price.MustParse("96.40"), q.High)
+	assert.Equal(t, price.MustParse("88.70"), q.Low)
 }
 
 func TestClient_FetchYearlyQuotes(t *testing.T) {
@@ -119,15 +175,14 @@ func TestClient_FetchYearlyQuotes(t *testing.T) {
 
 	q := qs[16]
 	assert.Equal(t, code, q.Code)
-	assert.Equal(t, "", q.Name)
-	assert.Equal(t, "20050101", q.Date.Format("20060102"))
+	assert.Equal(t, 2005, q.Year)
 	assert.Equal(t, uint64(296_356_000), q.Volume)
 	assert.Equal(t, uint64(147_000), q.Transactions)
 	assert.Equal(t, uint64(14_075_258_000), q.Value)
-	assert.Equal(t, 59.70, q.High)
-	assert.Equal(t, 28.20, q.Low)
-	assert.Equal(t, time.Date(2005, time.September, 16, 0, 0, 0, 0, time.UTC), q.DateOfHigh)
-	assert.Equal(t, time.Date(2005, time.January, 24, 0, 0, 0, 0, time.UTC), q.DateOfLow)
+	assert.Equal(t, price.MustParse("59.70"), q.High)
+	assert.Equal(t, price.MustParse("28.20"), q.Low)
+	assert.Equal(t, calendar.Date(2005, time.September, 16), q.DateOfHigh)
+	assert.Equal(t, calendar.Date(2005, time.January, 24), q.DateOfLow)
 }
 
 func TestClient_FetchDayQuotesInPast(t *testing.T) {
@@ -183,3 +238,150 @@ func TestClient_FetchYearlyQuotesWithNonExistingStock(t *testing.T) {
 	assert.Nilf(t, err, "%s", err)
 	assert.Equal(t, 0, len(qs))
 }
+
+func TestClient_FetchYearlyQuotesWithBaseURL(t *testing.T) {
+	mockResponse := tkttest.NewResponseFromGzipFile("./testdata/quotes-en-8044.csv.gz", 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		u := req.URL
+		return u.Scheme == "http" && u.Host == "localhost:8080" &&
+			u.Path == "/web/stock/statistics/monthly/download_st42.php"
+	})).Return(mockResponse, nil)
+
+	client := &tpex.Client{HttpClient: mockHttpClient, BaseURL: "http://localhost:8080"}
+	qs, err := client.FetchYearlyQuotes("8044")
+	assert.Nilf(t, err, "%s", err)
+	assert.NotEqual(t, 0, len(qs))
+}
+
+func TestClient_FetchDayQuotesWithInvalidBaseURL(t *testing.T) {
+	mockHttpClient := &tkttest.MockHttpClient{}
+
+	client := &tpex.Client{HttpClient: mockHttpClient, BaseURL: "localhost:8080"}
+	_, err := client.FetchDayQuotes(time.Date(2021, 3, 30, 0, 0, 0, 0, time.UTC))
+	assert.NotNil(t, err)
+
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 0)
+}
+
+func TestClient_FetchDayQuotesWithSchemaMismatch(t *testing.T) {
+	item := `["8044","網家","86.10","-0.50","86.60","87.30","86.00","86.52","6,092,000","527,065,000","5,274",` +
+		`"86.10","12","86.20","3","117,000,000","86.10","94.70","77.50","1"]`
+
+	for _, content := range []string{
+		`{"reportDate":"110/02/01","colNum":20,"aaData":[` + item + `]}`,
+		`{"reportDate":"110/02/01","aaData":[` + item + `]}`,
+		`{"reportDate":"110/02/01","iTotalRecords":1}`,
+	} {
+		mockHttpClient := &tkttest.MockHttpClient{}
+		mockHttpClient.On("Do", mock.Anything).Return(tkttest.NewResponseFromString(content, 200), nil)
+
+		client := &tpex.Client{HttpClient: mockHttpClient}
+		_, err := client.FetchDayQuotes(calendar.Date(2021, 2, 1))
+		assert.Truef(t, errors.Is(err, exchangeerr.ErrSchemaMismatch), "%v", err)
+		assert.True(t, errors.Is(err, exchangeerr.ErrParse))
+
+		var e *tpex.SchemaMismatchError
+		if assert.True(t, errors.As(err, &e)) {
+			assert.Equal(t, "stk_quote_result.php", e.Endpoint)
+		}
+	}
+}
+
+func TestClient_FetchYearlyQuotesWithSchemaMismatch(t *testing.T) {
+	for _, content := range []string{
+		"Year,Number of thousand shares traded,Amount (NTD, in thousands),Highest price,Date,Lowest price,Date\n" +
+			"2020,392843,40810347,147.00,2020/07/08,64.10,2020/03/19\n",
+		"Annual Trading Statistics\n2020,392843,40810347,305,147.00,2020/07/08,64.10,2020/03/19,103.88\n",
+	} {
+		mockHttpClient := &tkttest.MockHttpClient{}
+		mockHttpClient.On("Do", mock.Anything).Return(tkttest.NewResponseFromString(content, 200), nil)
+
+		client := &tpex.Client{HttpClient: mockHttpClient}
+		_, err := client.FetchYearlyQuotes("8044")
+		assert.Truef(t, errors.Is(err, exchangeerr.ErrSchemaMismatch), "%v", err)
+	}
+}
+
+func TestClient_FetchQuotesWithMalformedValues(t *testing.T) {
+	item := `["8044","網家","86.10","-0.50","86.60","87.30","86.00","86.52","6,O92,000","527,065,000","5,274",` +
+		`"86.10","12","86.20","3","117,000,000","86.10","94.70","77.50"]`
+
+	for _, tc := range []struct {
+		response *http.Response
+		fetch    func(c *tpex.Client) error
+		message  string
+	}{
+		{
+			tkttest.NewResponseFromString(`{"reportDate":"110/02/01","colNum":19,"aaData":[`+item+`]}`, 200),
+			func(c *tpex.Client) error { _, err := c.FetchDayQuotes(calendar.Date(2021, 2, 1)); return err },
+			"invalid volume '6,O92,000' of stk_quote_result.php",
+		},
+		{
+			tkttest.NewJsonResponseFromGzipFile("./testdata/quotes-tw-202102-8044-malformed.json.gz", 200),
+			func(c *tpex.Client) error { _, err := c.FetchDailyQuotes("8044", 2021, time.February); return err },
+			"invalid volume '1,O31' of st43_result.php",
+		},
+		{
+			tkttest.NewResponseFromGzipFile("./testdata/quotes-en-2020-8044-malformed.csv.gz", 200),
+			func(c *tpex.Client) error { _, err := c.FetchMonthlyQuotes("8044", 2020); return err },
+			"invalid high '120..00' of download_st44.php",
+		},
+		{
+			tkttest.NewResponseFromGzipFile("./testdata/quotes-en-8044-malformed.csv.gz", 200),
+			func(c *tpex.Client) error { _, err := c.FetchYearlyQuotes("8044"); return err },
+			"invalid volume '55.384' of download_st42.php",
+		},
+	} {
+		mockHttpClient := &tkttest.MockHttpClient{}
+		mockHttpClient.On("Do", mock.Anything).Return(tc.response, nil)
+
+		err := tc.fetch(&tpex.Client{HttpClient: mockHttpClient})
+		assert.Truef(t, errors.Is(err, exchangeerr.ErrParse), "%v", err)
+
+		var e *tpex.ParseError
+		if assert.True(t, errors.As(err, &e)) {
+			assert.Contains(t, e.Message, tc.message)
+		}
+	}
+}
+
+func TestClient_FetchWithoutHttpClient(t *testing.T) {
+	client := &tpex.Client{}
+	date := time.Date(2021, 3, 24, 0, 0, 0, 0, time.UTC)
+
+	_, err := client.FetchDayQuotes(date)
+	assert.True(t, errors.Is(err, tpex.ErrNoClient), "%v", err)
+	_, err = client.FetchDailyQuotes("0050", 2021, time.March)
+	assert.True(t, errors.Is(err, tpex.ErrNoClient), "%v", err)
+	_, err = client.FetchMonthlyQuotes("0050", 2021)
+	assert.True(t, errors.Is(err, tpex.ErrNoClient), "%v", err)
+	_, err = client.FetchYearlyQuotes("0050")
+	assert.True(t, errors.Is(err, tpex.ErrNoClient), "%v", err)
+
+	err = client.EachDayQuote(date, func(q tpex.DayQuote) error { return nil })
+	assert.True(t, errors.Is(err, tpex.ErrNoClient), "%v", err)
+}
+
+func TestQuote_MarshalJSON(t *testing.T) {
+	monthlyQuotes := []tpex.Quote{
+		{
+			Code:         "8044",
+			Date:         calendar.Date(2021, time.January, 1),
+			Volume:       9_818_000,
+			Transactions: 6_713,
+			Value:        896_442_000,
+			High:         price.MustParse("98.60"),
+			Low:          price.MustParse("84.00"),
+		},
+	}
+
+	data, err := json.Marshal(monthlyQuotes)
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"code":"8044","date":"2021-01-01","open":0.00,"high":98.60,"low":84.00,"close":0.00,`+
+		`"volume":9818000,"transactions":6713,"value":896442000,"date_of_high":null,"date_of_low":null}]`, string(data))
+
+	var qs []tpex.Quote
+	assert.Nil(t, json.Unmarshal(data, &qs))
+	assert.Equal(t, monthlyQuotes, qs)
+}
@@ -0,0 +1,118 @@
+package tpex
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// Kinds of Trade.
+const (
+	TradeKindAfterHours = quote.TradeKindAfterHours
+	TradeKindBlock      = quote.TradeKindBlock
+)
+
+// Trade is a trading record made outside the regular session, returned by Client.FetchAfterHoursTrades
+// and Client.FetchBlockTrades. Its Kind is either TradeKindAfterHours or TradeKindBlock.
+//
+// Unlike the TWSE, the TPEx already includes these trades in the quotes returned by Client.FetchDayQuotes,
+// as the title of the report tells, 上櫃股票行情(含等價、零股、盤後、鉅額交易), so they are for the details only.
+type Trade = quote.Trade
+
+// CombineTrades returns q unchanged, since the Volume, Transactions and Value of the day quotes of the
+// TPEx already include the fixed-price, odd-lot, after-hours and block trades. It mirrors
+// twse.CombineTrades so that the quotes of both exchanges are combined alike.
+func CombineTrades(q DayQuote, trades ...Trade) DayQuote {
+	return q
+}
+
+func (c *Client) fetchAfterHoursTrades(date time.Time) (map[string]json.RawMessage, error) {
+	rawQuery := url.Values{}
+	rawQuery.Set("d", fmt.Sprintf("%d/%s", date.Year()-1911, date.Format("01/02")))
+	rawQuery.Set("l", "zh-tw")
+	return c.fetchJSON("/web/stock/aftertrading/fixed_price/fixed_price_result.php", rawQuery)
+}
+
+func (c *Client) fetchBlockTrades(date time.Time) (map[string]json.RawMessage, error) {
+	rawQuery := url.Values{}
+	rawQuery.Set("d", fmt.Sprintf("%d/%s", date.Year()-1911, date.Format("01/02")))
+	rawQuery.Set("l", "zh-tw")
+	return c.fetchJSON("/web/stock/block_trade/daily_qutoes/block_day_result.php", rawQuery)
+}
+
+// FetchAfterHoursTrades returns a map that maps stock symbols to their after-hours fixed-price trades
+// on that date.
+func (c *Client) FetchAfterHoursTrades(date time.Time) (map[string]Trade, error) {
+	date = calendar.Normalize(date)
+	rawData, err := c.fetchAfterHoursTrades(date)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := afterHoursTradesSchema.parseJSON(rawData, "aaData")
+	if err != nil {
+		return nil, err
+	}
+
+	ts := map[string]Trade{}
+	for _, r := range rows {
+		var conv converter
+		t := Trade{
+			Kind:         TradeKindAfterHours,
+			Code:         r.get("code"),
+			Name:         r.get("name"),
+			Date:         date,
+			Volume:       conv.uint64(r, "volume"),
+			Transactions: conv.uint64(r, "transactions"),
+			Value:        conv.uint64(r, "value"),
+			Price:        conv.price(r, "price"),
+		}
+		if conv.err != nil {
+			return nil, conv.err
+		}
+		ts[t.Code] = t
+	}
+
+	return ts, nil
+}
+
+// FetchBlockTrades returns a map that maps stock symbols to their block trades on that date. Each
+// block trade is reported separately, so a stock can have more than one Trade.
+func (c *Client) FetchBlockTrades(date time.Time) (map[string][]Trade, error) {
+	date = calendar.Normalize(date)
+	rawData, err := c.fetchBlockTrades(date)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := blockTradesSchema.parseJSON(rawData, "aaData")
+	if err != nil {
+		return nil, err
+	}
+
+	ts := map[string][]Trade{}
+	for _, r := range rows {
+		var conv converter
+		t := Trade{
+			Kind:         TradeKindBlock,
+			Code:         r.get("code"),
+			Name:         r.get("name"),
+			Date:         date,
+			Method:       r.get("method"),
+			Volume:       conv.uint64(r, "volume"),
+			Transactions: 1,
+			Value:        conv.uint64(r, "value"),
+			Price:        conv.price(r, "price"),
+		}
+		if conv.err != nil {
+			return nil, conv.err
+		}
+		ts[t.Code] = append(ts[t.Code], t)
+	}
+
+	return ts, nil
+}
@@ -0,0 +1,141 @@
+package tpex_test
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/tkttest"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+)
+
+func TestClient_FetchAfterHoursTrades(t *testing.T) {
+	date := calendar.Date(2021, 3, 30)
+
+	mockResponse := tkttest.NewResponseFromString(`{
+		"reportDate": "110/03/30",
+		"iTotalRecords": 2,
+		"aaData": [
+			["006201", "元大富櫃50", "19.49", "1,000", "1", "19,490"],
+			["8044", "網家", "82.30", "12,000", "7", "987,600"]
+		]
+	}`, 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		u := req.URL
+		return u.Path == "/web/stock/aftertrading/fixed_price/fixed_price_result.php" &&
+			u.Query().Get("d") == "110/03/30"
+	})).Return(mockResponse, nil)
+
+	client := &tpex.Client{HttpClient: mockHttpClient}
+	ts, err := client.FetchAfterHoursTrades(date)
+
+	assert.Nilf(t, err, "%+v", err)
+	assert.Equal(t, 2, len(ts))
+	assert.Equal(t, tpex.Trade{
+		Kind:         tpex.TradeKindAfterHours,
+		Code:         "8044",
+		Name:         "網家",
+		Date:         date,
+		Volume:       12_000,
+		Transactions: 7,
+		Value:        987_600,
+		Price:        price.MustParse("82.30"),
+	}, ts["8044"])
+
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
+}
+
+func TestClient_FetchBlockTrades(t *testing.T) {
+	date := calendar.Date(2021, 3, 30)
+
+	mockResponse := tkttest.NewResponseFromString(`{
+		"reportDate": "110/03/30",
+		"iTotalRecords": 2,
+		"aaData": [
+			["8044", "網家", "配對交易", "82.00", "500,000", "41,000,000"],
+			["8044", "網家", "逐筆交易", "82.50", "300,000", "24,750,000"]
+		]
+	}`, 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		u := req.URL
+		return u.Path == "/web/stock/block_trade/daily_qutoes/block_day_result.php" &&
+			u.Query().Get("d") == "110/03/30"
+	})).Return(mockResponse, nil)
+
+	client := &tpex.Client{HttpClient: mockHttpClient}
+	ts, err := client.FetchBlockTrades(date)
+
+	assert.Nilf(t, err, "%+v", err)
+	assert.Equal(t, 1, len(ts))
+	assert.Equal(t, []tpex.Trade{
+		{
+			Kind:         tpex.TradeKindBlock,
+			Code:         "8044",
+			Name:         "網家",
+			Date:         date,
+			Method:       "配對交易",
+			Volume:       500_000,
+			Transactions: 1,
+			Value:        41_000_000,
+			Price:        price.MustParse("82.00"),
+		},
+		{
+			Kind:         tpex.TradeKindBlock,
+			Code:         "8044",
+			Name:         "網家",
+			Date:         date,
+			Method:       "逐筆交易",
+			Volume:       300_000,
+			Transactions: 1,
+			Value:        24_750_000,
+			Price:        price.MustParse("82.50"),
+		},
+	}, ts["8044"])
+
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
+}
+
+// TestCombineTrades checks that the day quotes of the TPEx already include the trades outside the
+// regular session, i.e. the quotes of the stocks sum up to the totals of the report without combining any
+// trades.
+func TestCombineTrades(t *testing.T) {
+	date := calendar.Date(2021, 3, 30)
+
+	mockResponse := tkttest.NewJsonResponseFromGzipFile("./testdata/quotes-tw-20210330.json.gz", 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.Anything).Return(mockResponse, nil)
+
+	client := &tpex.Client{HttpClient: mockHttpClient}
+	qs, err := client.FetchDayQuotes(date)
+	assert.Nilf(t, err, "%+v", err)
+
+	block := tpex.Trade{Kind: tpex.TradeKindBlock, Code: "8044", Date: date, Volume: 300_000, Transactions: 1,
+		Value: 24_750_000}
+	assert.Equal(t, qs["8044"], tpex.CombineTrades(qs["8044"], block))
+
+	// The totals are of the stocks, including the preferred ones like 8349A, but not of the ETFs, the bonds
+	// and the warrants.
+	stock := regexp.MustCompile(`^[0-9]{4}[A-Z]?$`)
+	var volume, transactions, value uint64
+	for code, q := range qs {
+		if !stock.MatchString(code) {
+			continue
+		}
+		q = tpex.CombineTrades(q, block)
+		volume += q.Volume
+		transactions += q.Transactions
+		value += q.Value
+	}
+
+	// totalVolumn, totalCount and totalAmount of the fixture.
+	assert.Equal(t, uint64(1_032_687_865), volume)
+	assert.Equal(t, uint64(600_645), transactions)
+	assert.Equal(t, uint64(85_951_480_703), value)
+}
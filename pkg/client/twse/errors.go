@@ -1,16 +1,31 @@
 package twse
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
 
-// noDataError is an error returned by Fetch functions when query conditions matches nothing. It can be
-// no such stock symbol, dates before the stock's IPO, or the TWSE server did not have any data of all
-// the stocks before the query date.
-type noDataError struct {
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+)
+
+// ErrNoClient is returned by Fetch functions of a Client without Client.HttpClient to query the TWSE
+// server with.
+var ErrNoClient = errors.New("Client.HttpClient is nil")
+
+// NoDataError is an error returned by Fetch functions in strict mode, see Client.Strict, when query
+// conditions matches nothing. It can be no such stock symbol, dates before the stock's IPO, holidays, or
+// dates of which the TWSE server has not published data yet, told by Reason.
+type NoDataError struct {
 	Message string
+	Reason  exchangeerr.NoDataReason
+}
+
+func (e *NoDataError) Error() string {
+	return fmt.Sprintf("NoData: %s (%s)", e.Message, e.Reason)
 }
 
-func (e *noDataError) Error() string {
-	return fmt.Sprintf("NoData: %s", e.Message)
+// Is matches exchangeerr.ErrNoData.
+func (e *NoDataError) Is(target error) bool {
+	return target == exchangeerr.ErrNoData
 }
 
 // QuotaExceededError is an error returned by Fetch functions when query the TWSE server too frequently.
@@ -23,6 +38,11 @@ func (e *QuotaExceededError) Error() string {
 	return fmt.Sprintf("QuotaExceeded: %s", e.Message)
 }
 
+// Is matches exchangeerr.ErrQuotaExceeded.
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == exchangeerr.ErrQuotaExceeded
+}
+
 // ConnectionError is an error returned by Fetch functions when having problem to connect to the TWSE server.
 type ConnectionError struct {
 	Message string
@@ -31,3 +51,48 @@ type ConnectionError struct {
 func (e *ConnectionError) Error() string {
 	return fmt.Sprintf("ConnectionError: %s", e.Message)
 }
+
+// Is matches exchangeerr.ErrConnection.
+func (e *ConnectionError) Is(target error) bool {
+	return target == exchangeerr.ErrConnection
+}
+
+// UnexpectedContentError is an error returned by Fetch functions when the TWSE server responds with
+// unexpected status codes or content types other than the page shown to banned clients, e.g. during
+// maintenance.
+type UnexpectedContentError struct {
+	Message     string
+	StatusCode  int
+	ContentType string
+	// Maintenance is true if the content is a maintenance page.
+	Maintenance bool
+}
+
+func (e *UnexpectedContentError) Error() string {
+	return fmt.Sprintf("UnexpectedContent: %s", e.Message)
+}
+
+// Is matches exchangeerr.ErrUnexpectedContent, and exchangeerr.ErrMaintenance if Maintenance is true.
+func (e *UnexpectedContentError) Is(target error) bool {
+	return target == exchangeerr.ErrUnexpectedContent || (e.Maintenance && target == exchangeerr.ErrMaintenance)
+}
+
+// ParseError is an error returned by Fetch functions when the response is not valid JSON or has
+// unexpected fields or values, e.g. after the TWSE changes its API.
+type ParseError struct {
+	Message string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("ParseError: %s", e.Message)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches exchangeerr.ErrParse.
+func (e *ParseError) Is(target error) bool {
+	return target == exchangeerr.ErrParse
+}
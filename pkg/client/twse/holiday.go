@@ -0,0 +1,105 @@
+package twse
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+)
+
+func (c *Client) fetchHolidays(year int) ([]map[string]interface{}, error) {
+	rawQuery := url.Values{}
+	rawQuery.Set("response", "json")
+	rawQuery.Set("queryYear", strconv.Itoa(year-1911))
+
+	rawData, err := c.fetch("/holidaySchedule/holidaySchedule", rawQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return zipFieldsAndItems(rawData, "fields", "data")
+}
+
+// parseHolidayDate parses dates like 2021-02-10, 110/02/10 and 20210210.
+func parseHolidayDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "20060102"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return calendar.Normalize(t), nil
+		}
+	}
+
+	splitRawDate := strings.Split(s, "/")
+	if len(splitRawDate) != 3 {
+		return time.Time{}, fmt.Errorf("'%s' is ill-formatted", s)
+	}
+
+	parts := make([]int, 3)
+	for i := range splitRawDate {
+		v, err := strconv.Atoi(splitRawDate[i])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("'%s' is ill-formatted: %w", s, err)
+		}
+		parts[i] = v
+	}
+
+	return calendar.Date(parts[0]+1911, time.Month(parts[1]), parts[2]), nil
+}
+
+// isTradingDayNotice tells the entries like 國曆新年開始交易日 and 農曆春節前最後交易日, which are
+// listed in the holiday schedule but are trading days indeed.
+func isTradingDayNotice(name string) bool {
+	return strings.HasSuffix(name, "交易日") && !strings.Contains(name, "無交易")
+}
+
+// FetchHolidays returns the dates on which the market is closed in the year according to the holiday
+// schedule of the TWSE. Weekends are not included, and neither are the closures due to typhoons, but the
+// make-up trading days on weekends are, with calendar.Holiday.Open set.
+//
+// It fails with NoDataError if the schedule of the year is not yet published, which the TWSE does late in
+// the year before, rather than returning no holidays.
+func (c *Client) FetchHolidays(year int) ([]calendar.Holiday, error) {
+	rawHolidays, err := c.fetchHolidays(year)
+	var e *NoDataError
+	if errors.As(err, &e) || (err == nil && len(rawHolidays) == 0) {
+		return nil, &NoDataError{fmt.Sprintf("holiday schedule of %d", year), exchangeerr.ReasonNotYetPublished}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hs := make([]calendar.Holiday, 0)
+	for _, rawHoliday := range rawHolidays {
+		var conv converter
+		name := conv.string(rawHoliday, "名稱")
+		rawDate := conv.string(rawHoliday, "日期")
+		description := conv.string(rawHoliday, "說明")
+		if conv.err != nil {
+			return nil, conv.err
+		}
+
+		date, err := parseHolidayDate(rawDate)
+		if err != nil {
+			return nil, &ParseError{fmt.Sprintf("ill-formatted date '%s' in %v", rawDate, rawHoliday), err}
+		}
+
+		// Trading days on weekdays are listed only as notices.
+		open := isTradingDayNotice(name)
+		if open && date.Weekday() != time.Saturday && date.Weekday() != time.Sunday {
+			continue
+		}
+
+		hs = append(hs, calendar.Holiday{
+			Date:        date,
+			Name:        name,
+			Description: description,
+			Open:        open,
+		})
+	}
+
+	return hs, nil
+}
@@ -0,0 +1,107 @@
+package twse_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/tkttest"
+)
+
+func TestClient_FetchHolidays(t *testing.T) {
+	mockResponse := tkttest.NewResponseFromString(`{
+		"stat": "OK",
+		"queryYear": 110,
+		"fields": ["名稱", "日期", "星期", "說明"],
+		"data": [
+			["中華民國開國紀念日", "110/01/01", "五", "依規定放假1日。"],
+			["國曆新年開始交易日", "110/01/04", "一", "國曆新年開始交易。"],
+			["農曆春節前最後交易日", "2021-02-05", "五", "農曆春節前最後交易。"],
+			["市場無交易，僅辦理結算交割作業", "2021-02-08", "一", ""],
+			["農曆除夕", "20210211", "四", "依規定放假1日。"]
+		]
+	}`, 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		u := req.URL
+		return u.Path == "/holidaySchedule/holidaySchedule" && u.Query().Get("queryYear") == "110"
+	})).Return(mockResponse, nil)
+
+	client := &twse.Client{HttpClient: mockHttpClient}
+	hs, err := client.FetchHolidays(2021)
+
+	assert.Nilf(t, err, "%+v", err)
+	assert.Equal(t, []calendar.Holiday{
+		{Date: calendar.Date(2021, 1, 1), Name: "中華民國開國紀念日", Description: "依規定放假1日。"},
+		{Date: calendar.Date(2021, 2, 8), Name: "市場無交易，僅辦理結算交割作業", Description: ""},
+		{Date: calendar.Date(2021, 2, 11), Name: "農曆除夕", Description: "依規定放假1日。"},
+	}, hs)
+
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
+}
+
+func TestClient_FetchHolidaysWithMakeUpTradingDay(t *testing.T) {
+	mockResponse := tkttest.NewResponseFromString(`{
+		"stat": "OK",
+		"queryYear": 101,
+		"fields": ["名稱", "日期", "星期", "說明"],
+		"data": [
+			["補行交易日", "101/12/22", "六", "補行101年12月31日之交易。"],
+			["調整放假", "101/12/31", "一", ""]
+		]
+	}`, 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.Anything).Return(mockResponse, nil)
+
+	client := &twse.Client{HttpClient: mockHttpClient}
+	hs, err := client.FetchHolidays(2012)
+
+	assert.Nilf(t, err, "%+v", err)
+	assert.Equal(t, []calendar.Holiday{
+		{Date: calendar.Date(2012, 12, 22), Name: "補行交易日", Description: "補行101年12月31日之交易。", Open: true},
+		{Date: calendar.Date(2012, 12, 31), Name: "調整放假", Description: ""},
+	}, hs)
+}
+
+func TestClient_FetchHolidaysWithIllFormattedDate(t *testing.T) {
+	mockResponse := tkttest.NewResponseFromString(`{
+		"stat": "OK",
+		"queryYear": 110,
+		"fields": ["名稱", "日期", "星期", "說明"],
+		"data": [["農曆除夕", "02月11日", "四", ""]]
+	}`, 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.Anything).Return(mockResponse, nil)
+
+	client := &twse.Client{HttpClient: mockHttpClient}
+	_, err := client.FetchHolidays(2021)
+
+	var e *twse.ParseError
+	assert.True(t, errors.As(err, &e), "%v", err)
+	assert.True(t, errors.Is(err, exchangeerr.ErrParse))
+}
+
+func TestClient_FetchHolidaysNotYetPublished(t *testing.T) {
+	for _, body := range []string{
+		`{"stat": "很抱歉，沒有符合條件的資料!"}`,
+		`{"stat": "OK", "queryYear": 111, "fields": ["名稱", "日期", "星期", "說明"], "data": []}`,
+	} {
+		mockHttpClient := &tkttest.MockHttpClient{}
+		mockHttpClient.On("Do", mock.Anything).Return(tkttest.NewResponseFromString(body, 200), nil)
+
+		client := &twse.Client{HttpClient: mockHttpClient}
+		hs, err := client.FetchHolidays(2022)
+
+		assert.Nil(t, hs)
+		var e *twse.NoDataError
+		if assert.Truef(t, errors.As(err, &e), "%s: %v", body, err) {
+			assert.Equal(t, exchangeerr.ReasonNotYetPublished, e.Reason)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package twse
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/chehsunliu/tshakutshai/pkg/internal/clientopt"
+)
+
+// Language is the language of the responses requested from the TWSE by Fetch functions of quotes.
+type Language int
+
+const (
+	// LanguageChinese is the default, in which quotes of all stocks come with the Chinese names.
+	LanguageChinese Language = clientopt.LanguageChinese
+	// LanguageEnglish requests the English responses, in which the TWSE provides no stock names.
+	LanguageEnglish Language = clientopt.LanguageEnglish
+)
+
+// englishFields maps the English field names to the Chinese ones, by which the quotes are parsed. The
+// keys are normalized by normalizeField, since the TWSE is not consistent in spaces and cases.
+var englishFields = map[string]string{
+	"securitycode":   "證券代號",
+	"securityname":   "證券名稱",
+	"date":           "日期",
+	"year":           "年度",
+	"month":          "月份",
+	"tradevolume":    "成交股數",
+	"tradevolume(b)": "成交股數(B)",
+	"tradevalue":     "成交金額",
+	"tradevalue(a)":  "成交金額(A)",
+	"transaction":    "成交筆數",
+	"transactions":   "成交筆數",
+	"openingprice":   "開盤價",
+	"highestprice":   "最高價",
+	"lowestprice":    "最低價",
+	"closingprice":   "收盤價",
+}
+
+func normalizeField(field string) string {
+	return strings.ToLower(strings.Join(strings.Fields(field), ""))
+}
+
+// translateFields replaces the English field names in fields with the Chinese ones in place, and leaves
+// the others untouched.
+func translateFields(fields []string) []string {
+	for i, field := range fields {
+		if chinese, ok := englishFields[normalizeField(field)]; ok {
+			fields[i] = chinese
+		}
+	}
+
+	return fields
+}
+
+func (c *Client) setLanguage(rawQuery url.Values) {
+	if c.Language == LanguageEnglish {
+		rawQuery.Set("lang", "en")
+	}
+}
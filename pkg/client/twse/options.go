@@ -0,0 +1,90 @@
+package twse
+
+import (
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/clientopt"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// Option configures the Client created by NewClient. Options are created by the With functions.
+type Option struct {
+	apply func(o *clientopt.Options)
+}
+
+// WithTimeout sets the timeout of each request, which is 30 seconds by default. Zero means no timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return Option{func(o *clientopt.Options) { o.Timeout = timeout }}
+}
+
+// WithUserAgent replaces the default User-Agent, since the TWSE sometimes rejects the one of Go.
+func WithUserAgent(userAgent string) Option {
+	return Option{func(o *clientopt.Options) { o.UserAgent = userAgent }}
+}
+
+// WithHeader adds a header to every request. Headers of the same key are all sent.
+func WithHeader(key, value string) Option {
+	return Option{func(o *clientopt.Options) { o.Header.Add(key, value) }}
+}
+
+// WithBaseURL sets Client.BaseURL.
+func WithBaseURL(baseURL string) Option {
+	return Option{func(o *clientopt.Options) { o.BaseURL = baseURL }}
+}
+
+// WithLimiter makes each request wait for limiter, in addition to minInterval of NewClient. Pass zero
+// minInterval to rely on limiter only.
+func WithLimiter(limiter tkthttp.Limiter) Option {
+	return Option{func(o *clientopt.Options) { o.Limiter = limiter }}
+}
+
+// WithCache serves responses from cache if possible, without waiting for throttling. Error pages are
+// never cached.
+func WithCache(cache tkthttp.Cache) Option {
+	return Option{func(o *clientopt.Options) { o.Cache = cache }}
+}
+
+// WithRetry retries failed requests according to policy. Every retry is throttled as well.
+func WithRetry(policy tkthttp.RetryPolicy) Option {
+	return Option{func(o *clientopt.Options) { o.Retry = &policy }}
+}
+
+// WithLogger logs every request sent to the TWSE.
+func WithLogger(logger tkthttp.Logger) Option {
+	return Option{func(o *clientopt.Options) { o.Logger = logger }}
+}
+
+// WithObserver sets Client.Observer, e.g. tkthttp.NewLogObserver(logger) to log structured events of
+// every request.
+func WithObserver(observer tkthttp.Observer) Option {
+	return Option{func(o *clientopt.Options) { o.Observer = observer }}
+}
+
+// WithStrict sets Client.Strict, so that Fetch functions return NoDataError instead of empty results.
+func WithStrict() Option {
+	return Option{func(o *clientopt.Options) { o.Strict = true }}
+}
+
+// WithLanguage sets Client.Language.
+func WithLanguage(lang Language) Option {
+	return Option{func(o *clientopt.Options) { o.Language = int(lang) }}
+}
+
+// WithNameResolver sets Client.NameResolver, e.g. a names.Directory built from other clients.
+func WithNameResolver(r quote.NameResolver) Option {
+	return Option{func(o *clientopt.Options) { o.Names = r }}
+}
+
+// WithCalendar sets Client.Calendar.
+func WithCalendar(cal *calendar.Calendar) Option {
+	return Option{func(o *clientopt.Options) { o.Calendar = cal }}
+}
+
+// WithRequestFunc applies f to every outgoing request after the headers are set, e.g. to add cookies:
+//
+//     twse.WithRequestFunc(func(req *http.Request) { req.AddCookie(cookie) })
+func WithRequestFunc(f tkthttp.RequestFunc) Option {
+	return Option{func(o *clientopt.Options) { o.Mutators = append(o.Mutators, f) }}
+}
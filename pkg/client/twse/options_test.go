@@ -0,0 +1,241 @@
+package twse_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+	"github.com/chehsunliu/tshakutshai/pkg/fakeexchange"
+	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+type countingLimiter struct {
+	waits int
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits++
+	return nil
+}
+
+func TestNewClient_WithOptions(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+	s.AddDayQuotes(fakeexchange.MarketTWSE, quote.Day{Code: "2330", Date: calendar.Date(2021, 3, 24)})
+
+	var b bytes.Buffer
+	limiter := &countingLimiter{}
+
+	client := twse.NewClient(0,
+		twse.WithBaseURL(s.URL),
+		twse.WithTimeout(time.Second*5),
+		twse.WithUserAgent("pipeline/1.0"),
+		twse.WithHeader("X-Team", "quant"),
+		twse.WithRequestFunc(func(req *http.Request) { req.AddCookie(&http.Cookie{Name: "session", Value: "42"}) }),
+		twse.WithLimiter(limiter),
+		twse.WithCache(tkthttp.NewMemoryCache(0, 0)),
+		twse.WithLogger(log.New(&b, "", 0)),
+	)
+	assert.Equal(t, s.URL, client.BaseURL)
+
+	for i := 0; i < 2; i++ {
+		qs, err := client.FetchDayQuotes(calendar.Date(2021, 3, 24))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(qs))
+	}
+
+	requests := s.Requests()
+	assert.Equal(t, 1, len(requests), "the second query should be served from the cache")
+	assert.Equal(t, "pipeline/1.0", requests[0].Header.Get("User-Agent"))
+	assert.Equal(t, "quant", requests[0].Header.Get("X-Team"))
+	assert.Equal(t, "session=42", requests[0].Header.Get("Cookie"))
+	assert.Equal(t, 1, limiter.waits)
+	assert.Equal(t, 1, strings.Count(b.String(), "/exchangeReport/MI_INDEX"))
+}
+
+func TestNewClient_WithRetry(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+	s.SetFault("", fakeexchange.FaultHTMLError)
+
+	client := twse.NewClient(0, twse.WithBaseURL(s.URL), twse.WithRetry(tkthttp.RetryPolicy{MaxAttempts: 3}))
+	_, err := client.FetchYearlyQuotes("0050")
+
+	assert.ErrorIs(t, err, exchangeerr.ErrMaintenance)
+	assert.Equal(t, 3, len(s.Requests()))
+	assert.True(t, strings.Contains(s.Requests()[0].Header.Get("User-Agent"), "tshakutshai"))
+}
+
+func TestNewClient_WithObserver(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+	s.AddDayQuotes(fakeexchange.MarketTWSE, quote.Day{Code: "2330", Date: calendar.Date(2021, 3, 24)})
+
+	var events []tkthttp.Event
+	observer := tkthttp.ObserverFunc(func(e tkthttp.Event) { events = append(events, e) })
+
+	client := twse.NewClient(time.Millisecond, twse.WithBaseURL(s.URL), twse.WithObserver(observer))
+	_, err := client.FetchDayQuotes(calendar.Date(2021, 3, 24))
+	assert.Nil(t, err)
+	_, err = client.FetchDayQuotes(calendar.Date(2021, 3, 25))
+	assert.Nil(t, err)
+
+	kinds := make([]tkthttp.EventKind, 0)
+	for _, e := range events {
+		kinds = append(kinds, e.Kind)
+	}
+	assert.Equal(t, []tkthttp.EventKind{
+		tkthttp.EventRequest, tkthttp.EventThrottle, tkthttp.EventResponse, tkthttp.EventParse, tkthttp.EventEnd,
+		tkthttp.EventRequest, tkthttp.EventThrottle, tkthttp.EventResponse, tkthttp.EventParse, tkthttp.EventEnd,
+	}, kinds)
+
+	assert.True(t, strings.HasPrefix(events[0].URL, s.URL+"/exchangeReport/MI_INDEX?"))
+	assert.Equal(t, 200, events[2].StatusCode)
+	assert.Equal(t, "application/json", events[2].ContentType)
+	assert.NotZero(t, events[3].Bytes)
+	assert.Nil(t, events[3].Err)
+	assert.NotNil(t, events[8].Err, "no data should be reported")
+	assert.Equal(t, events[8].Err, events[9].Err)
+
+	// Bans end the request with the page parsed.
+	s.SetFault("", fakeexchange.FaultBan)
+	events = nil
+	_, err = client.FetchDayQuotes(calendar.Date(2021, 3, 24))
+	assert.ErrorIs(t, err, exchangeerr.ErrQuotaExceeded)
+	assert.Equal(t, 5, len(events))
+	assert.Equal(t, tkthttp.EventParse, events[3].Kind)
+	assert.NotZero(t, events[3].Bytes)
+	assert.ErrorIs(t, events[3].Err, exchangeerr.ErrQuotaExceeded)
+	assert.Equal(t, tkthttp.EventEnd, events[4].Kind)
+	assert.ErrorIs(t, events[4].Err, exchangeerr.ErrQuotaExceeded)
+
+	s.SetFault("", fakeexchange.FaultEmptyReply)
+	events = nil
+	_, err = client.FetchDayQuotes(calendar.Date(2021, 3, 24))
+	assert.ErrorIs(t, err, exchangeerr.ErrQuotaExceeded)
+	assert.Equal(t, 4, len(events))
+	assert.Equal(t, tkthttp.EventEnd, events[3].Kind)
+	assert.ErrorIs(t, events[3].Err, exchangeerr.ErrQuotaExceeded)
+}
+
+func TestNewClient_WithStrict(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	s.AddDayQuotes(fakeexchange.MarketTWSE, quote.Day{Code: "2330", Date: calendar.Date(2021, 2, 1)})
+	s.AddYearlyQuotes(fakeexchange.MarketTWSE, quote.Yearly{Code: "2330", Year: 2021})
+
+	lenient := twse.NewClient(0, twse.WithBaseURL(s.URL))
+	qs, err := lenient.FetchDailyQuotes("2330", 2020, time.January)
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Day{}, qs)
+
+	// Today is a holiday, e.g. closed by a typhoon, rather than not yet published.
+	today := calendar.Today(time.Now())
+	cal := calendar.New(nil)
+	cal.Load(2021, []calendar.Holiday{{Date: calendar.Date(2021, 2, 10), Name: "春節"}})
+	cal.AddClosure(today, "颱風")
+
+	client := twse.NewClient(0, twse.WithBaseURL(s.URL), twse.WithStrict(), twse.WithCalendar(cal))
+
+	dayQuotes, err := client.FetchDayQuotes(calendar.Date(2021, 2, 1))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(dayQuotes))
+
+	for _, tc := range []struct {
+		fetch  func() error
+		reason exchangeerr.NoDataReason
+	}{
+		{func() error { _, err := client.FetchDayQuotes(calendar.Date(2021, 2, 6)); return err }, exchangeerr.ReasonNonTradingDay},
+		{func() error { _, err := client.FetchDayQuotes(calendar.Date(2021, 2, 10)); return err }, exchangeerr.ReasonNonTradingDay},
+		{func() error { _, err := client.FetchDayQuotes(calendar.Date(2100, 1, 4)); return err }, exchangeerr.ReasonNotYetPublished},
+		{func() error { _, err := client.FetchDayQuotes(calendar.Date(2021, 2, 9)); return err }, exchangeerr.ReasonUndetermined},
+		{func() error { _, err := client.FetchDayQuotes(today); return err }, exchangeerr.ReasonNonTradingDay},
+		{func() error { _, err := client.FetchDailyQuotes("2330", 2020, time.January); return err }, exchangeerr.ReasonBeforeListing},
+		{func() error { _, err := client.FetchDailyQuotes("2330", 2100, time.January); return err }, exchangeerr.ReasonNotYetPublished},
+		{func() error { _, err := client.FetchDailyQuotes("9999", 2021, time.January); return err }, exchangeerr.ReasonUnknownCode},
+		{func() error { _, err := client.FetchMonthlyQuotes("2330", 2021); return err }, exchangeerr.ReasonUndetermined},
+		{func() error { _, err := client.FetchYearlyQuotes("9999"); return err }, exchangeerr.ReasonUnknownCode},
+	} {
+		err := tc.fetch()
+		assert.True(t, errors.Is(err, exchangeerr.ErrNoData))
+
+		var e *twse.NoDataError
+		if assert.True(t, errors.As(err, &e)) {
+			assert.Equalf(t, tc.reason, e.Reason, "%s", e)
+		}
+	}
+}
+
+func TestNewClient_WithLanguage(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	q := quote.Day{Code: "2330", Name: "台積電", EnglishName: "TSMC", Date: calendar.Date(2021, 2, 1),
+		Close: price.MustParse("611.00"), Volume: 70_161_000}
+	s.AddDayQuotes(fakeexchange.MarketTWSE, q)
+	y := quote.Yearly{Code: "2330", Year: 2021, High: price.MustParse("679.00"), Low: price.MustParse("575.00"),
+		DateOfHigh: calendar.Date(2021, 1, 21), DateOfLow: calendar.Date(2021, 3, 31)}
+	s.AddYearlyQuotes(fakeexchange.MarketTWSE, y)
+
+	client := twse.NewClient(0, twse.WithBaseURL(s.URL), twse.WithLanguage(twse.LanguageEnglish))
+
+	// The TWSE provides no stock names in English.
+	q.Name, q.EnglishName = "", ""
+	dayQuotes, err := client.FetchDayQuotes(calendar.Date(2021, 2, 1))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]quote.Day{"2330": q}, dayQuotes)
+
+	dailyQuotes, err := client.FetchDailyQuotes("2330", 2021, time.February)
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Day{q}, dailyQuotes)
+
+	yearlyQuotes, err := client.FetchYearlyQuotes("2330")
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Yearly{y}, yearlyQuotes)
+
+	for _, r := range s.Requests() {
+		assert.Equal(t, "en", r.Params.Get("lang"))
+	}
+}
+
+type nameMap map[string]quote.Names
+
+func (m nameMap) ResolveNames(code string) (quote.Names, error) {
+	return m[code], nil
+}
+
+func TestNewClient_WithNameResolver(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	s.AddDayQuotes(fakeexchange.MarketTWSE, quote.Day{Code: "2330", Name: "台積電", Date: calendar.Date(2021, 2, 1)})
+	s.AddYearlyQuotes(fakeexchange.MarketTWSE, quote.Yearly{Code: "2330", Year: 2021})
+
+	resolver := nameMap{"2330": {Chinese: "台積電", English: "TSMC", Short: "台積電"}}
+	client := twse.NewClient(0, twse.WithBaseURL(s.URL), twse.WithNameResolver(resolver))
+
+	dailyQuotes, err := client.FetchDailyQuotes("2330", 2021, time.February)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(dailyQuotes))
+	assert.Equal(t, "台積電", dailyQuotes[0].Name)
+	assert.Equal(t, "TSMC", dailyQuotes[0].EnglishName)
+
+	yearlyQuotes, err := client.FetchYearlyQuotes("2330")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(yearlyQuotes))
+	assert.Equal(t, "台積電", yearlyQuotes[0].Name)
+	assert.Equal(t, "TSMC", yearlyQuotes[0].EnglishName)
+}
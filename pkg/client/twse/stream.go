@@ -0,0 +1,220 @@
+package twse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/internal/jsonstream"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+)
+
+// dayQuoteColumns are the indices of the fields of day quotes in the rows of MI_INDEX. name is -1 if the
+// rows have no names, e.g. in English.
+type dayQuoteColumns struct {
+	fields                      int
+	code, name                  int
+	volume, transactions, value int
+	open, high, low, close      int
+}
+
+func newDayQuoteColumns(fields []string) (*dayQuoteColumns, error) {
+	fields = suffixDuplicateFields(translateFields(fields))
+
+	indices := map[string]int{}
+	for i, field := range fields {
+		indices[field] = i
+	}
+
+	c := &dayQuoteColumns{fields: len(fields), name: -1}
+	if i, ok := indices["證券名稱"]; ok {
+		c.name = i
+	}
+
+	for _, col := range []struct {
+		field string
+		index *int
+	}{
+		{"證券代號", &c.code},
+		{"成交股數", &c.volume},
+		{"成交筆數", &c.transactions},
+		{"成交金額", &c.value},
+		{"開盤價", &c.open},
+		{"最高價", &c.high},
+		{"最低價", &c.low},
+		{"收盤價", &c.close},
+	} {
+		i, ok := indices[col.field]
+		if !ok {
+			return nil, &ParseError{Message: fmt.Sprintf("field '%s' does not exist in %v", col.field, fields)}
+		}
+		*col.index = i
+	}
+
+	return c, nil
+}
+
+// convert fills q with row, so that q can be reused for all the rows.
+func (c *dayQuoteColumns) convert(row []string, date time.Time, q *DayQuote) error {
+	if len(row) != c.fields {
+		return &ParseError{Message: fmt.Sprintf("%d fields but item %v has %d elements", c.fields, row, len(row))}
+	}
+
+	*q = DayQuote{Code: row[c.code], Date: date}
+	if c.name >= 0 {
+		q.Name = row[c.name]
+	}
+
+	var err error
+	for _, v := range []struct {
+		index int
+		value *uint64
+	}{
+		{c.volume, &q.Volume},
+		{c.transactions, &q.Transactions},
+		{c.value, &q.Value},
+	} {
+		if *v.value, err = parseUint64(row[v.index]); err != nil {
+			return &ParseError{fmt.Sprintf("value '%s' of %s is not uint64: %s", row[v.index], q.Code, err), err}
+		}
+	}
+
+	for _, v := range []struct {
+		index int
+		value *price.Price
+	}{
+		{c.open, &q.Open},
+		{c.high, &q.High},
+		{c.low, &q.Low},
+		{c.close, &q.Close},
+	} {
+		if *v.value, err = parsePrice(row[v.index]); err != nil {
+			return &ParseError{fmt.Sprintf("value '%s' of %s is not price: %s", row[v.index], q.Code, err), err}
+		}
+	}
+
+	return nil
+}
+
+// decodeDayQuotes decodes MI_INDEX from r and calls fn with the quotes of data9 in order, one row at a
+// time, and returns stat. The quote passed to fn is reused for the next rows. The rows are buffered only
+// if data9 precedes fields9. Errors returned by fn are returned as they are.
+func decodeDayQuotes(r io.Reader, date time.Time, fn func(q *DayQuote) error) (string, error) {
+	dec := json.NewDecoder(r)
+
+	var stat string
+	var columns *dayQuoteColumns
+	var pending [][]string
+	var q DayQuote
+	var fnErr error
+	hasData := false
+
+	emit := func() error {
+		fnErr = fn(&q)
+		return fnErr
+	}
+
+	err := jsonstream.Object(dec, func(key string) (bool, error) {
+		switch key {
+		case "stat":
+			return true, dec.Decode(&stat)
+		case "fields9":
+			var fields []string
+			if err := dec.Decode(&fields); err != nil {
+				return true, err
+			}
+
+			var err error
+			columns, err = newDayQuoteColumns(fields)
+			return true, err
+		case "data9":
+			hasData = true
+			var row []string
+			return true, jsonstream.Array(dec, func() error {
+				row = row[:0]
+				if err := dec.Decode(&row); err != nil {
+					return err
+				}
+
+				if columns == nil {
+					pending = append(pending, append([]string(nil), row...))
+					return nil
+				}
+
+				if err := columns.convert(row, date, &q); err != nil {
+					return err
+				}
+				return emit()
+			})
+		default:
+			return false, nil
+		}
+	})
+	if err != nil {
+		var e *ParseError
+		if err == fnErr || errors.As(err, &e) {
+			return "", err
+		}
+		return "", &ParseError{fmt.Sprintf("failed to decode JSON: %s", err), err}
+	}
+
+	if len(pending) > 0 {
+		if columns == nil {
+			return "", &ParseError{Message: "key 'fields9' does not exist"}
+		}
+
+		for _, row := range pending {
+			if err := columns.convert(row, date, &q); err != nil {
+				return "", err
+			}
+			if err := emit(); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	switch {
+	case stat == "":
+		return "", &ParseError{Message: "key 'stat' does not exist"}
+	case stat == "OK" && !hasData:
+		return "", &ParseError{Message: "key 'data9' does not exist"}
+	}
+
+	return stat, nil
+}
+
+// streamDayQuotes queries MI_INDEX and calls fn with the quotes on date in the order of the TWSE without
+// decoding the whole document. It returns NoDataError if the TWSE provides no quotes on date.
+func (c *Client) streamDayQuotes(date time.Time, fn func(q *DayQuote) error) error {
+	rawQuery := url.Values{}
+	rawQuery.Set("response", "json")
+	rawQuery.Set("date", date.Format("20060102"))
+	rawQuery.Set("type", "ALL")
+	c.setLanguage(rawQuery)
+
+	resp, t, err := c.open("/exchangeReport/MI_INDEX", rawQuery)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var fnErr error
+	stat, err := decodeDayQuotes(t.Body(resp.Body), date, func(q *DayQuote) error {
+		fnErr = fn(q)
+		return fnErr
+	})
+	if err == nil && stat != "OK" {
+		err = &NoDataError{Message: fmt.Sprintf("expected stat 'OK' but got '%s'", stat)}
+	}
+
+	// Errors of fn are not of parsing.
+	if err != nil && err == fnErr {
+		t.Parse(nil)
+	} else {
+		t.Parse(err)
+	}
+	return err
+}
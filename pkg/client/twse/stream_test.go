@@ -0,0 +1,157 @@
+package twse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/tkttest"
+)
+
+func readFixture(tb testing.TB, filepath string) []byte {
+	resp := tkttest.NewJsonResponseFromGzipFile(filepath, 200)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return body
+}
+
+// decodeDayQuotesByMaps is the former way to decode MI_INDEX, which decodes the whole document and then
+// converts each row to a map, kept to compare with decodeDayQuotes.
+func decodeDayQuotesByMaps(body []byte) []DayQuote {
+	rawData := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &rawData); err != nil {
+		panic(err)
+	}
+
+	date := calendar.Date(2021, 3, 24)
+	rawDayQuotes, err := zipFieldsAndItems(rawData, "fields9", "data9")
+	if err != nil {
+		panic(err)
+	}
+
+	qs := make([]DayQuote, 0, len(rawDayQuotes))
+	for _, rawDayQuote := range rawDayQuotes {
+		var conv converter
+		q := convertRawQuote(&conv, rawDayQuote)
+		q.Code = conv.string(rawDayQuote, "證券代號")
+		q.Name = conv.string(rawDayQuote, "證券名稱")
+		if conv.err != nil {
+			panic(conv.err)
+		}
+		q.Date = date
+		qs = append(qs, *q)
+	}
+
+	return qs
+}
+
+func TestDecodeDayQuotes(t *testing.T) {
+	body := readFixture(t, "./testdata/quotes-tw-20210324.json.gz")
+
+	var qs []DayQuote
+	stat, err := decodeDayQuotes(bytes.NewReader(body), calendar.Date(2021, 3, 24), func(q *DayQuote) error {
+		qs = append(qs, *q)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "OK", stat)
+	assert.Equal(t, decodeDayQuotesByMaps(body), qs)
+	assert.Equal(t, "0050", qs[0].Code, "quotes should be in the order of the TWSE")
+
+	// The callback stops decoding with its error.
+	errStop := errors.New("stop")
+	n := 0
+	_, err = decodeDayQuotes(bytes.NewReader(body), calendar.Date(2021, 3, 24), func(q *DayQuote) error {
+		n++
+		if n == 3 {
+			return errStop
+		}
+		return nil
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, 3, n)
+}
+
+func TestDecodeDayQuotes_DataBeforeFields(t *testing.T) {
+	body := `{"data9":[["2330","台積電","1,000","10","576,000","576.00","577.00","575.00","576.00"]],` +
+		`"fields9":["證券代號","證券名稱","成交股數","成交筆數","成交金額","開盤價","最高價","最低價","收盤價"],"stat":"OK"}`
+
+	var qs []DayQuote
+	stat, err := decodeDayQuotes(bytes.NewReader([]byte(body)), calendar.Date(2021, 3, 24), func(q *DayQuote) error {
+		qs = append(qs, *q)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "OK", stat)
+	if assert.Equal(t, 1, len(qs)) {
+		assert.Equal(t, "台積電", qs[0].Name)
+		assert.Equal(t, uint64(576_000), qs[0].Value)
+	}
+}
+
+func TestDecodeDayQuotes_Errors(t *testing.T) {
+	for _, body := range []string{
+		`{"stat":"OK","fields9":["證券代號","成交股數"],"data9":[["2330","1,000"]]}`,
+		`{"stat":"OK","fields9":["證券代號","成交股數","成交筆數","成交金額","開盤價","最高價","最低價","收盤價"],` +
+			`"data9":[["2330","1,000"]]}`,
+		`{"stat":"OK","fields9":["證券代號","成交股數","成交筆數","成交金額","開盤價","最高價","最低價","收盤價"],` +
+			`"data9":[["2330","many","10","576,000","576.00","577.00","575.00","576.00"]]}`,
+		`{"stat":"OK","data9":[["2330"]]}`,
+		`{"stat":"OK"}`,
+		`{"data9":[]}`,
+		`{"stat":"OK","data9":[[`,
+		`["stat","OK"]`,
+	} {
+		_, err := decodeDayQuotes(bytes.NewReader([]byte(body)), calendar.Date(2021, 3, 24), func(q *DayQuote) error {
+			return nil
+		})
+		assert.Truef(t, errors.Is(err, exchangeerr.ErrParse), "%s: %v", body, err)
+	}
+
+	stat, err := decodeDayQuotes(bytes.NewReader([]byte(`{"stat":"很抱歉，沒有符合條件的資料!"}`)),
+		calendar.Date(2021, 3, 24), func(q *DayQuote) error { return nil })
+	assert.Nil(t, err)
+	assert.Equal(t, "很抱歉，沒有符合條件的資料!", stat)
+}
+
+func BenchmarkDecodeDayQuotes_Maps(b *testing.B) {
+	body := readFixture(b, "./testdata/quotes-tw-20210324.json.gz")
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		qs := map[string]DayQuote{}
+		for _, q := range decodeDayQuotesByMaps(body) {
+			qs[q.Code] = q
+		}
+	}
+}
+
+func BenchmarkDecodeDayQuotes_Stream(b *testing.B) {
+	body := readFixture(b, "./testdata/quotes-tw-20210324.json.gz")
+	date := calendar.Date(2021, 3, 24)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		qs := map[string]DayQuote{}
+		_, err := decodeDayQuotes(bytes.NewReader(body), date, func(q *DayQuote) error {
+			qs[q.Code] = *q
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package twse
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/nodata"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// Kinds of Trade.
+const (
+	TradeKindOddLot     = quote.TradeKindOddLot
+	TradeKindAfterHours = quote.TradeKindAfterHours
+	TradeKindBlock      = quote.TradeKindBlock
+)
+
+// Trade is a trading record made outside the regular session, returned by Client.FetchOddLotTrades,
+// Client.FetchAfterHoursTrades and Client.FetchBlockTrades. These trades are not included in the quotes
+// returned by Client.FetchDayQuotes and Client.FetchDailyQuotes; combine them by CombineTrades.
+type Trade = quote.Trade
+
+// CombineTrades returns a copy of q whose Volume, Transactions and Value are combined with those of the
+// trades of the same code and date, see quote.CombineTrades.
+func CombineTrades(q DayQuote, trades ...Trade) DayQuote {
+	return quote.CombineTrades(q, trades...)
+}
+
+func (c *Client) fetchTrades(p string, date time.Time, selectType string) ([]map[string]interface{}, error) {
+	rawQuery := url.Values{}
+	rawQuery.Set("response", "json")
+	rawQuery.Set("date", date.Format("20060102"))
+	rawQuery.Set("selectType", selectType)
+
+	rawData, err := c.fetch(p, rawQuery)
+	if err != nil {
+		var e *NoDataError
+		if errors.As(err, &e) {
+			if !c.Strict {
+				return []map[string]interface{}{}, nil
+			}
+			e.Reason = nodata.OfDay(date, time.Now(), c.Calendar)
+		}
+		return nil, err
+	}
+
+	return zipFieldsAndItems(rawData, "fields", "data")
+}
+
+func convertRawTrade(rawTrade map[string]interface{}, kind string, date time.Time) (*Trade, error) {
+	var conv converter
+	t := &Trade{
+		Kind:         kind,
+		Code:         conv.string(rawTrade, "證券代號"),
+		Name:         conv.string(rawTrade, "證券名稱"),
+		Date:         date,
+		Volume:       conv.uint64(rawTrade, "成交股數"),
+		Transactions: conv.uint64(rawTrade, "成交筆數"),
+		Value:        conv.uint64(rawTrade, "成交金額"),
+		Price:        conv.price(rawTrade, "成交價"),
+	}
+	if conv.err != nil {
+		return nil, conv.err
+	}
+	return t, nil
+}
+
+func convertRawBlockTrade(rawTrade map[string]interface{}, date time.Time) (*Trade, error) {
+	var conv converter
+	t := &Trade{
+		Kind:         TradeKindBlock,
+		Code:         conv.string(rawTrade, "證券代號"),
+		Name:         conv.string(rawTrade, "證券名稱"),
+		Date:         date,
+		Method:       conv.string(rawTrade, "交易別"),
+		Volume:       conv.uint64(rawTrade, "成交股數"),
+		Transactions: 1,
+		Value:        conv.uint64(rawTrade, "成交金額"),
+		Price:        conv.price(rawTrade, "成交價"),
+	}
+	if conv.err != nil {
+		return nil, conv.err
+	}
+	return t, nil
+}
+
+// FetchOddLotTrades returns a map that maps stock symbols to their after-hours odd-lot trades on that
+// date.
+func (c *Client) FetchOddLotTrades(date time.Time) (map[string]Trade, error) {
+	date = calendar.Normalize(date)
+	rawTrades, err := c.fetchTrades("/exchangeReport/TWT53U", date, "ALL")
+	if err != nil {
+		return nil, err
+	}
+
+	ts := map[string]Trade{}
+	for _, rawTrade := range rawTrades {
+		t, err := convertRawTrade(rawTrade, TradeKindOddLot, date)
+		if err != nil {
+			return nil, err
+		}
+		ts[t.Code] = *t
+	}
+
+	return ts, nil
+}
+
+// FetchAfterHoursTrades returns a map that maps stock symbols to their after-hours fixed-price trades
+// on that date.
+func (c *Client) FetchAfterHoursTrades(date time.Time) (map[string]Trade, error) {
+	date = calendar.Normalize(date)
+	rawTrades, err := c.fetchTrades("/exchangeReport/BFT41U", date, "ALL")
+	if err != nil {
+		return nil, err
+	}
+
+	ts := map[string]Trade{}
+	for _, rawTrade := range rawTrades {
+		t, err := convertRawTrade(rawTrade, TradeKindAfterHours, date)
+		if err != nil {
+			return nil, err
+		}
+		ts[t.Code] = *t
+	}
+
+	return ts, nil
+}
+
+// FetchBlockTrades returns a map that maps stock symbols to their block trades on that date. Each
+// block trade is reported separately, so a stock can have more than one Trade.
+func (c *Client) FetchBlockTrades(date time.Time) (map[string][]Trade, error) {
+	date = calendar.Normalize(date)
+	rawTrades, err := c.fetchTrades("/block/BFIAUU", date, "S")
+	if err != nil {
+		return nil, err
+	}
+
+	ts := map[string][]Trade{}
+	for _, rawTrade := range rawTrades {
+		t, err := convertRawBlockTrade(rawTrade, date)
+		if err != nil {
+			return nil, err
+		}
+		ts[t.Code] = append(ts[t.Code], *t)
+	}
+
+	return ts, nil
+}
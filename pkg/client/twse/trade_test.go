@@ -0,0 +1,143 @@
+package twse_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/tkttest"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+)
+
+func TestClient_FetchAfterHoursTrades(t *testing.T) {
+	date := calendar.Date(2021, 3, 24)
+
+	mockResponse := tkttest.NewResponseFromString(`{
+		"stat": "OK",
+		"date": "20210324",
+		"fields": ["證券代號", "證券名稱", "成交股數", "成交筆數", "成交金額", "成交價", "最後揭示買量", "最後揭示賣量"],
+		"data": [
+			["0050", "元大台灣50", "12,000", "5", "1,578,000", "131.50", "0", "3"],
+			["2330", "台積電", "452,000", "210", "260,352,000", "576.00", "12", "0"]
+		]
+	}`, 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		u := req.URL
+		return u.Path == "/exchangeReport/BFT41U" && u.Query().Get("date") == "20210324"
+	})).Return(mockResponse, nil)
+
+	client := &twse.Client{HttpClient: mockHttpClient}
+	ts, err := client.FetchAfterHoursTrades(date)
+
+	assert.Nilf(t, err, "%+v", err)
+	assert.Equal(t, 2, len(ts))
+	assert.Equal(t, twse.Trade{
+		Kind:         twse.TradeKindAfterHours,
+		Code:         "2330",
+		Name:         "台積電",
+		Date:         date,
+		Volume:       452_000,
+		Transactions: 210,
+		Value:        260_352_000,
+		Price:        price.MustParse("576.00"),
+	}, ts["2330"])
+
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
+}
+
+func TestClient_FetchBlockTrades(t *testing.T) {
+	date := calendar.Date(2021, 3, 24)
+
+	mockResponse := tkttest.NewResponseFromString(`{
+		"stat": "OK",
+		"date": "20210324",
+		"fields": ["證券代號", "證券名稱", "交易別", "成交價", "成交股數", "成交金額"],
+		"data": [
+			["2330", "台積電", "配對交易", "578.00", "1,000,000", "578,000,000"],
+			["2330", "台積電", "逐筆交易", "575.00", "600,000", "345,000,000"],
+			["2454", "聯發科", "配對交易", "950.00", "500,000", "475,000,000"]
+		]
+	}`, 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		u := req.URL
+		return u.Path == "/block/BFIAUU" && u.Query().Get("date") == "20210324"
+	})).Return(mockResponse, nil)
+
+	client := &twse.Client{HttpClient: mockHttpClient}
+	ts, err := client.FetchBlockTrades(date)
+
+	assert.Nilf(t, err, "%+v", err)
+	assert.Equal(t, 2, len(ts))
+	assert.Equal(t, 2, len(ts["2330"]))
+	assert.Equal(t, twse.Trade{
+		Kind:         twse.TradeKindBlock,
+		Code:         "2330",
+		Name:         "台積電",
+		Date:         date,
+		Method:       "逐筆交易",
+		Volume:       600_000,
+		Transactions: 1,
+		Value:        345_000_000,
+		Price:        price.MustParse("575.00"),
+	}, ts["2330"][1])
+
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
+}
+
+func TestClient_FetchOddLotTradesOnWeekend(t *testing.T) {
+	date := time.Date(2021, 3, 28, 0, 0, 0, 0, time.UTC)
+
+	mockResponse := tkttest.NewResponseFromString(`{"stat":"很抱歉，沒有符合條件的資料!"}`, 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.URL.Path == "/exchangeReport/TWT53U"
+	})).Return(mockResponse, nil)
+
+	client := &twse.Client{HttpClient: mockHttpClient}
+	ts, err := client.FetchOddLotTrades(date)
+	assert.Nilf(t, err, "%v", err)
+	assert.Equal(t, 0, len(ts))
+}
+
+func TestClient_FetchOddLotTradesOnWeekendStrictly(t *testing.T) {
+	date := time.Date(2021, 3, 28, 0, 0, 0, 0, time.UTC)
+
+	mockResponse := tkttest.NewResponseFromString(`{"stat":"很抱歉，沒有符合條件的資料!"}`, 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.Anything).Return(mockResponse, nil)
+
+	client := &twse.Client{HttpClient: mockHttpClient, Strict: true}
+	_, err := client.FetchOddLotTrades(date)
+
+	var e *twse.NoDataError
+	if assert.True(t, errors.As(err, &e), "%v", err) {
+		assert.Equal(t, exchangeerr.ReasonNonTradingDay, e.Reason)
+	}
+}
+
+func TestCombineTrades(t *testing.T) {
+	date := calendar.Date(2021, 3, 24)
+
+	q := twse.DayQuote{Code: "2330", Date: date, Volume: 115_318_351, Transactions: 242_138, Value: 66_559_451_738}
+	reconciled := twse.CombineTrades(q,
+		twse.Trade{Kind: twse.TradeKindOddLot, Code: "2330", Date: date, Volume: 1_234, Transactions: 56, Value: 710_784},
+		twse.Trade{Kind: twse.TradeKindAfterHours, Code: "2330", Date: date, Volume: 452_000, Transactions: 210, Value: 260_352_000},
+		twse.Trade{Kind: twse.TradeKindBlock, Code: "2330", Date: date, Volume: 1_000_000, Transactions: 1, Value: 578_000_000},
+		twse.Trade{Kind: twse.TradeKindBlock, Code: "2454", Date: date, Volume: 500_000, Transactions: 1, Value: 475_000_000},
+		twse.Trade{Kind: twse.TradeKindBlock, Code: "2330", Date: date.AddDate(0, 0, 1), Volume: 9, Transactions: 1, Value: 9},
+	)
+
+	assert.Equal(t, uint64(116_771_585), reconciled.Volume)
+	assert.Equal(t, uint64(242_405), reconciled.Transactions)
+	assert.Equal(t, uint64(67_398_514_522), reconciled.Value)
+	assert.Equal(t, uint64(115_318_351), q.Volume)
+}
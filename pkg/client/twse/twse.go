@@ -10,9 +10,18 @@
 //     q := qs["2330"]
 //     fmt.Println(q.Code, q.Name, q.Open, q.Close)
 //
-// You can also create Client with your own HTTP client:
+// Quotes of different granularities have different types, DayQuote, MonthlyQuote and YearlyQuote,
+// which are shared with the tpex package, see the quote package.
 //
-//     client := &twse.Client{HttpClient: &http.Client{}}
+// You can also create Client with your own HTTP client, and query a proxy or a stand-in server instead
+// of the TWSE with BaseURL:
+//
+//     client := &twse.Client{HttpClient: &http.Client{}, BaseURL: "http://localhost:8080"}
+//
+// Quotes are parsed from the Chinese responses by default. Set Client.Language, or create the client with
+// WithLanguage, to request the English ones instead:
+//
+//     client := twse.NewClient(time.Second*2, twse.WithLanguage(twse.LanguageEnglish))
 //
 // Error handling
 //
@@ -25,7 +34,21 @@
 //         }
 //     }
 //
-// If panic happens, it is possibly due to the API change on the TWSE server side.
+// The errors are QuotaExceededError, ConnectionError, UnexpectedContentError, e.g. maintenance pages,
+// ParseError, and NoDataError in strict mode. They also match the sentinels of the exchangeerr package by
+// errors.Is, which are shared with the tpex package.
+//
+// By default, Fetch functions return empty results if nothing matches the query. Set Client.Strict, or
+// create the client with WithStrict, to tell unknown codes, dates before listing, holidays and dates not
+// published yet apart:
+//
+//     _, err := client.FetchDailyQuotes("2330", 1990, time.January)
+//     var e *twse.NoDataError
+//     if errors.As(err, &e) && e.Reason == exchangeerr.ReasonBeforeListing {
+//         // ...
+//     }
+//
+// If ParseError is returned, it is possibly due to the API change on the TWSE server side.
 package twse
 
 import (
@@ -33,6 +56,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"mime"
 	"net/http"
 	"net/url"
@@ -40,113 +64,294 @@ import (
 	"strings"
 	"time"
 
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
 	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/baseurl"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/clientopt"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/htmlpage"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/namefill"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/nodata"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/quotejson"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/trace"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
 )
 
-// Quote is the basic unit returned by the Fetch functions.
+// DayQuote is the quote of a stock on a trading day, returned by Client.FetchDayQuotes and
+// Client.FetchDailyQuotes.
+type DayQuote = quote.Day
+
+// MonthlyQuote is the aggregate of a stock in a month, returned by Client.FetchMonthlyQuotes.
+type MonthlyQuote = quote.Monthly
+
+// YearlyQuote is the aggregate of a stock in a year, returned by Client.FetchYearlyQuotes.
+type YearlyQuote = quote.Yearly
+
+// ErrStop stops EachDayQuote early, see quote.ErrStop.
+var ErrStop = quote.ErrStop
+
+// Quote is the basic unit formerly returned by all the Fetch functions.
+//
+// Deprecated: Use DayQuote, MonthlyQuote and YearlyQuote instead. Code still depending on Quote can
+// convert the new types with QuoteFromDay, QuoteFromMonthly and QuoteFromYearly.
 type Quote struct {
 	// Code/symbol of a stock, e.g. 0050 and 2330.
-	Code string
+	Code string `json:"code"`
 	// Name is the Chinese stock name and only available in Client.FetchDayQuotes.
-	Name string
+	Name string `json:"name,omitempty"`
 	// Date represents the date in Client.FetchDayQuotes and Client.FetchDailyQuotes. You should ignore
 	// the day field in Client.FetchMonthlyQuotes and even the month field in Client.FetchYearlyQuotes.
-	Date time.Time
+	// All dates are the midnight in Asia/Taipei, see calendar.Date.
+	Date time.Time `json:"date"`
 
-	Volume       uint64
-	Transactions uint64
-	Value        uint64
+	Volume       uint64 `json:"volume"`
+	Transactions uint64 `json:"transactions"`
+	Value        uint64 `json:"value"`
 
 	// If no transactions are made, i.e. Transactions equals to zero, they will all zeros. Note that
 	// Open and Close are only meaningful in Client.FetchDayQuotes and Client.FetchDailyQuotes.
-	High  float64
-	Low   float64
-	Open  float64
-	Close float64
+	High  price.Price `json:"high"`
+	Low   price.Price `json:"low"`
+	Open  price.Price `json:"open"`
+	Close price.Price `json:"close"`
 
 	// These two fields are only used in Client.FetchYearlyQuotes.
-	DateOfHigh time.Time
-	DateOfLow  time.Time
+	DateOfHigh time.Time `json:"date_of_high"`
+	DateOfLow  time.Time `json:"date_of_low"`
+}
+
+// MarshalJSON encodes q with the snake_case keys in its tags and dates as YYYY-MM-DD. All the keys are
+// present whatever the values are; DateOfHigh and DateOfLow, which only apply to yearly quotes, are null
+// if zero.
+func (q Quote) MarshalJSON() ([]byte, error) {
+	return quotejson.Marshal(quotejson.Quote(q))
+}
+
+// QuoteFromDay converts q to the deprecated Quote.
+func QuoteFromDay(q DayQuote) Quote {
+	return Quote{
+		Code:         q.Code,
+		Name:         q.Name,
+		Date:         q.Date,
+		Volume:       q.Volume,
+		Transactions: q.Transactions,
+		Value:        q.Value,
+		High:         q.High,
+		Low:          q.Low,
+		Open:         q.Open,
+		Close:        q.Close,
+	}
+}
+
+// QuoteFromMonthly converts q to the deprecated Quote, whose Date is the first day of the month.
+func QuoteFromMonthly(q MonthlyQuote) Quote {
+	return Quote{
+		Code:         q.Code,
+		Date:         q.Date(),
+		Volume:       q.Volume,
+		Transactions: q.Transactions,
+		Value:        q.Value,
+		High:         q.High,
+		Low:          q.Low,
+	}
+}
+
+// QuoteFromYearly converts q to the deprecated Quote, whose Date is the first day of the year.
+func QuoteFromYearly(q YearlyQuote) Quote {
+	return Quote{
+		Code:         q.Code,
+		Date:         q.Date(),
+		Volume:       q.Volume,
+		Transactions: q.Transactions,
+		Value:        q.Value,
+		High:         q.High,
+		Low:          q.Low,
+		DateOfHigh:   q.DateOfHigh,
+		DateOfLow:    q.DateOfLow,
+	}
 }
 
+// UnmarshalJSON decodes the output of MarshalJSON. Dates are restored as the midnight in Asia/Taipei.
+func (q *Quote) UnmarshalJSON(data []byte) error {
+	return quotejson.Unmarshal(data, (*quotejson.Quote)(q))
+}
+
+// DefaultBaseURL is the base URL of the TWSE server, used if Client.BaseURL is empty.
+const DefaultBaseURL = "https://www.twse.com.tw"
+
 // Client is a crawler gathering data from the TWSE server.
 type Client struct {
 	// HttpClient is the actual object that interacts with the TWSE server. It must not be nil; otherwise,
-	// it will panic during fetching data.
+	// Fetch functions return ErrNoClient.
 	HttpClient tkthttp.Client
+	// BaseURL replaces DefaultBaseURL for all the endpoints, e.g. to query a caching proxy or a local
+	// stand-in server. It must be an http or https URL, optionally with a path prepended to the paths of
+	// the endpoints, e.g. http://proxy.local:8080/twse; otherwise, Fetch functions return an error.
+	BaseURL string
+	// Observer receives the events of every request if not nil, including the throttling of
+	// tkthttp.ThrottledClient, see tkthttp.Event.
+	Observer tkthttp.Observer
+	// Strict makes the Fetch functions of quotes and trades return NoDataError instead of empty results if
+	// nothing matches the query. Telling an unknown code from dates before listing takes one more request of
+	// FetchYearlyQuotes.
+	Strict bool
+	// Language is the language of the responses requested by Fetch functions of quotes. The English ones
+	// provide no stock names.
+	Language Language
+	// NameResolver fills the empty names of the quotes if not nil, e.g. the Chinese names of daily quotes
+	// and the English names of all the quotes. It must not use c itself.
+	NameResolver quote.NameResolver
+	// Calendar tells holidays from trading days without quotes for the reasons of NoDataError of Strict.
+	// Only weekends are known to be closed if it is nil.
+	Calendar *calendar.Calendar
 }
 
 // NewClient returns a new Client, which intervals between each query are not less than minInterval.
-func NewClient(minInterval time.Duration) *Client {
-	return &Client{HttpClient: tkthttp.NewThrottledClient(&http.Client{}, minInterval)}
+// Requests time out after 30 seconds and are sent with a browser-like User-Agent unless configured by
+// opts:
+//
+//     client := twse.NewClient(time.Second*2, twse.WithTimeout(time.Minute), twse.WithLogger(logger))
+//
+// Client.Calendar is the holiday schedule fetched by the client itself unless set by WithCalendar.
+func NewClient(minInterval time.Duration, opts ...Option) *Client {
+	o := clientopt.New()
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+
+	c := &Client{
+		HttpClient:   o.HttpClient(minInterval),
+		BaseURL:      o.BaseURL,
+		Observer:     o.Observer,
+		Strict:       o.Strict,
+		Language:     Language(o.Language),
+		NameResolver: o.Names,
+		Calendar:     o.Calendar,
+	}
+	if c.Calendar == nil {
+		c.Calendar = calendar.New(c)
+	}
+
+	return c
 }
 
 func (c *Client) fetch(p string, rawQuery url.Values) (map[string]json.RawMessage, error) {
+	resp, t, err := c.open(p, rawQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	rawData := map[string]json.RawMessage{}
+	if err := json.NewDecoder(t.Body(resp.Body)).Decode(&rawData); err != nil {
+		t.Parse(err)
+		return nil, &ParseError{fmt.Sprintf("failed to decode JSON: %s", err), err}
+	}
+
+	stat, err := retrieveStat(rawData)
+	if err != nil {
+		t.Parse(err)
+		return nil, err
+	}
+	if stat != "OK" {
+		err := &NoDataError{Message: fmt.Sprintf("expected stat 'OK' but got '%s'", stat)}
+		t.Parse(err)
+		return nil, err
+	}
+
+	t.Parse(nil)
+	return rawData, nil
+}
+
+// open sends the request and returns the response, whose body is JSON and is to be closed by the caller.
+func (c *Client) open(p string, rawQuery url.Values) (*http.Response, *trace.Trace, error) {
 	if c.HttpClient == nil {
-		panic("Client.HttpClient should not be nil")
+		return nil, nil, ErrNoClient
 	}
 
-	u := url.URL{Scheme: "https", Host: "www.twse.com.tw", Path: p, RawQuery: rawQuery.Encode()}
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
 
-	req, err := http.NewRequest("GET", u.String(), nil)
+	base, err := baseurl.Parse(baseURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("GET", baseurl.Resolve(base, p, rawQuery), nil)
 	if err != nil {
 		panic(err)
 	}
 
+	t, req := trace.Start(c.Observer, req)
 	resp, err := c.HttpClient.Do(req)
+	t.Response(resp, err)
 	if err != nil {
 		if errors.Is(err, io.EOF) {
-			return nil, &QuotaExceededError{"empty reply from server"}
+			err = &QuotaExceededError{"empty reply from server"}
 		} else {
-			return nil, &ConnectionError{fmt.Sprintf("failed to query: %s", err)}
+			err = &ConnectionError{fmt.Sprintf("failed to query: %s", err)}
 		}
+		t.End(err)
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
 
-	contentType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
-	if err != nil {
-		panic(err)
+	if err := checkResponse(resp, t.Body(resp.Body)); err != nil {
+		resp.Body.Close()
+		t.Parse(err)
+		return nil, nil, err
 	}
 
-	if contentType != "application/json" {
-		return nil, &QuotaExceededError{fmt.Sprintf("received unexpected content type '%s'", contentType)}
-	}
+	return resp, t, nil
+}
 
-	rawData := map[string]json.RawMessage{}
-	if err := json.NewDecoder(resp.Body).Decode(&rawData); err != nil {
-		panic(err)
+// checkResponse returns an error unless resp is JSON with 200 OK, reading the page from body otherwise.
+// The TWSE shows an HTML page to banned clients, which is distinguished from maintenance pages.
+func checkResponse(resp *http.Response, body io.Reader) error {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden {
+		return &QuotaExceededError{fmt.Sprintf("received status %d", resp.StatusCode)}
 	}
 
-	if stat := retrieveStat(rawData); stat != "OK" {
-		return nil, &noDataError{fmt.Sprintf("expected stat 'OK' but got '%s'", stat)}
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if contentType == "application/json" && resp.StatusCode == http.StatusOK {
+		return nil
 	}
 
-	return rawData, nil
-}
+	page, _ := ioutil.ReadAll(io.LimitReader(body, 64<<10))
+	e := &UnexpectedContentError{StatusCode: resp.StatusCode, ContentType: contentType}
+
+	switch {
+	case contentType != "text/html" && !htmlpage.Is(page):
+		e.Message = fmt.Sprintf("received status %d and content type '%s'", resp.StatusCode, contentType)
+	case htmlpage.IsMaintenance(resp.StatusCode, page):
+		e.Message = fmt.Sprintf("received maintenance page with status %d", resp.StatusCode)
+		e.Maintenance = true
+	default:
+		return &QuotaExceededError{fmt.Sprintf("received unexpected content type '%s'", contentType)}
+	}
 
-func (c *Client) fetchDayQuotes(date time.Time) (map[string]json.RawMessage, error) {
-	rawQuery := url.Values{}
-	rawQuery.Set("response", "json")
-	rawQuery.Set("date", date.Format("20060102"))
-	rawQuery.Set("type", "ALL")
-	return c.fetch("/exchangeReport/MI_INDEX", rawQuery)
+	return e
 }
 
 func (c *Client) fetchDailyQuotes(code string, year int, month time.Month) (map[string]json.RawMessage, error) {
-	date := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	date := calendar.Date(year, month, 1)
 	rawQuery := url.Values{}
 	rawQuery.Set("response", "json")
 	rawQuery.Set("date", date.Format("20060102"))
 	rawQuery.Set("stockNo", code)
+	c.setLanguage(rawQuery)
 	return c.fetch("/exchangeReport/STOCK_DAY", rawQuery)
 }
 
 func (c *Client) fetchMonthlyQuotes(code string, year int) (map[string]json.RawMessage, error) {
-	date := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	date := calendar.Date(year, time.January, 1)
 	rawQuery := url.Values{}
 	rawQuery.Set("response", "json")
 	rawQuery.Set("date", date.Format("20060102"))
 	rawQuery.Set("stockNo", code)
+	c.setLanguage(rawQuery)
 	return c.fetch("/exchangeReport/FMSRFK", rawQuery)
 }
 
@@ -154,177 +359,297 @@ func (c *Client) fetchYearlyQuotes(code string) (map[string]json.RawMessage, err
 	rawQuery := url.Values{}
 	rawQuery.Set("response", "json")
 	rawQuery.Set("stockNo", code)
+	c.setLanguage(rawQuery)
 	return c.fetch("/exchangeReport/FMNPTK", rawQuery)
 }
 
-func convertRawQuote(rawDayQuote map[string]interface{}) *Quote {
-	return &Quote{
-		Volume:       convertToStringThenUint64(rawDayQuote, "成交股數"),
-		Transactions: convertToStringThenUint64(rawDayQuote, "成交筆數"),
-		Value:        convertToStringThenUint64(rawDayQuote, "成交金額"),
-		Open:         convertToStringThenFloat64(rawDayQuote, "開盤價"),
-		High:         convertToStringThenFloat64(rawDayQuote, "最高價"),
-		Low:          convertToStringThenFloat64(rawDayQuote, "最低價"),
-		Close:        convertToStringThenFloat64(rawDayQuote, "收盤價"),
+func convertRawQuote(conv *converter, rawDayQuote map[string]interface{}) *DayQuote {
+	return &DayQuote{
+		Volume:       conv.uint64(rawDayQuote, "成交股數"),
+		Transactions: conv.uint64(rawDayQuote, "成交筆數"),
+		Value:        conv.uint64(rawDayQuote, "成交金額"),
+		Open:         conv.price(rawDayQuote, "開盤價"),
+		High:         conv.price(rawDayQuote, "最高價"),
+		Low:          conv.price(rawDayQuote, "最低價"),
+		Close:        conv.price(rawDayQuote, "收盤價"),
 	}
 }
 
-func convertRawDayQuote(rawDayQuote map[string]interface{}, date time.Time) *Quote {
-	q := convertRawQuote(rawDayQuote)
-	q.Code = convertToString(rawDayQuote, "證券代號")
-	q.Name = convertToString(rawDayQuote, "證券名稱")
-	q.Date = date
-	return q
-}
-
-func convertRawDailyQuote(rawDailyQuote map[string]interface{}, code string, year int, month time.Month) *Quote {
-	rawDate := convertToString(rawDailyQuote, "日期")
+func convertRawDailyQuote(rawDailyQuote map[string]interface{}, code string, year int, month time.Month) (*DayQuote, error) {
+	var conv converter
+	rawDate := conv.string(rawDailyQuote, "日期")
+	q := convertRawQuote(&conv, rawDailyQuote)
+	if conv.err != nil {
+		return nil, conv.err
+	}
 
 	splitRawDate := strings.Split(rawDate, "/")
 	if len(splitRawDate) != 3 {
-		panic(fmt.Sprintf("'%s' of %v is ill-formatted", rawDate, rawDailyQuote))
+		return nil, &ParseError{Message: fmt.Sprintf("ill-formatted date '%s' in %v", rawDate, rawDailyQuote)}
 	}
 
 	day, err := strconv.ParseInt(splitRawDate[2], 10, 32)
 	if err != nil {
-		panic(fmt.Sprintf("ill-formatted date '%s' in %v", rawDate, rawDailyQuote))
+		return nil, &ParseError{fmt.Sprintf("ill-formatted date '%s' in %v", rawDate, rawDailyQuote), err}
 	}
 
-	q := convertRawQuote(rawDailyQuote)
 	q.Code = code
-	q.Date = time.Date(year, month, int(day), 0, 0, 0, 0, time.UTC)
-	return q
+	q.Date = calendar.Date(year, month, int(day))
+	return q, nil
 }
 
-func convertRawMonthlyQuote(rawMonthlyQuote map[string]interface{}, code string, year int) *Quote {
-	rawMonth := convertToFloat64(rawMonthlyQuote, "月份")
+func convertRawMonthlyQuote(rawMonthlyQuote map[string]interface{}, code string, year int) (*MonthlyQuote, error) {
+	var conv converter
+	rawMonth := conv.float64(rawMonthlyQuote, "月份")
+	q := &MonthlyQuote{
+		Code:         code,
+		Year:         year,
+		Volume:       conv.uint64(rawMonthlyQuote, "成交股數(B)"),
+		Transactions: conv.uint64(rawMonthlyQuote, "成交筆數"),
+		Value:        conv.uint64(rawMonthlyQuote, "成交金額(A)"),
+		High:         conv.price(rawMonthlyQuote, "最高價"),
+		Low:          conv.price(rawMonthlyQuote, "最低價"),
+	}
+	if conv.err != nil {
+		return nil, conv.err
+	}
 
 	t, err := time.Parse("01", fmt.Sprintf("%02d", int(rawMonth)))
 	if err != nil {
-		panic(fmt.Sprintf("%f is not a legal month: %s", rawMonth, err))
+		return nil, &ParseError{fmt.Sprintf("%v is not a legal month: %s", rawMonth, err), err}
 	}
 
-	return &Quote{
+	q.Month = t.Month()
+	return q, nil
+}
+
+func convertRawYearlyQuote(rawYearlyQuote map[string]interface{}, code string) (*YearlyQuote, error) {
+	var conv converter
+	rawDateOfHigh := conv.string(rawYearlyQuote, "日期")
+	rawDateOfLow := conv.string(rawYearlyQuote, "日期2")
+	q := &YearlyQuote{
 		Code:         code,
-		Date:         time.Date(year, t.Month(), 1, 0, 0, 0, 0, time.UTC),
-		Volume:       convertToStringThenUint64(rawMonthlyQuote, "成交股數(B)"),
-		Transactions: convertToStringThenUint64(rawMonthlyQuote, "成交筆數"),
-		Value:        convertToStringThenUint64(rawMonthlyQuote, "成交金額(A)"),
-		High:         convertToStringThenFloat64(rawMonthlyQuote, "最高價"),
-		Low:          convertToStringThenFloat64(rawMonthlyQuote, "最低價"),
+		Year:         int(conv.float64(rawYearlyQuote, "年度")),
+		Volume:       conv.uint64(rawYearlyQuote, "成交股數"),
+		Transactions: conv.uint64(rawYearlyQuote, "成交筆數"),
+		Value:        conv.uint64(rawYearlyQuote, "成交金額"),
+		High:         conv.price(rawYearlyQuote, "最高價"),
+		Low:          conv.price(rawYearlyQuote, "最低價"),
+	}
+	if conv.err != nil {
+		return nil, conv.err
 	}
-}
 
-func convertRawYearlyQuote(rawYearlyQuote map[string]interface{}, code string) *Quote {
-	rawYear := convertToFloat64(rawYearlyQuote, "年度")
-	year := 1911 + int(rawYear)
+	// The years are in the ROC calendar in Chinese, but not in English.
+	if q.Year < 1911 {
+		q.Year += 1911
+	}
 
-	rawDateOfHigh := convertToString(rawYearlyQuote, "日期")
-	dateOfHigh, err := time.Parse("2006/1/02", fmt.Sprintf("%d/%s", year, rawDateOfHigh))
+	dateOfHigh, err := time.Parse("2006/1/02", fmt.Sprintf("%d/%s", q.Year, rawDateOfHigh))
 	if err != nil {
-		panic(fmt.Sprintf("%s is not a legal month/day: %s", rawDateOfHigh, err))
+		return nil, &ParseError{fmt.Sprintf("%s is not a legal month/day: %s", rawDateOfHigh, err), err}
 	}
 
-	rawDateOfLow := convertToString(rawYearlyQuote, "日期2")
-	dateOfLow, err := time.Parse("2006/1/02", fmt.Sprintf("%d/%s", year, rawDateOfLow))
+	dateOfLow, err := time.Parse("2006/1/02", fmt.Sprintf("%d/%s", q.Year, rawDateOfLow))
 	if err != nil {
-		panic(fmt.Sprintf("%s is not a legal month/day: %s", rawDateOfLow, err))
+		return nil, &ParseError{fmt.Sprintf("%s is not a legal month/day: %s", rawDateOfLow, err), err}
 	}
 
-	return &Quote{
-		Code:         code,
-		Date:         time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
-		Volume:       convertToStringThenUint64(rawYearlyQuote, "成交股數"),
-		Transactions: convertToStringThenUint64(rawYearlyQuote, "成交筆數"),
-		Value:        convertToStringThenUint64(rawYearlyQuote, "成交金額"),
-		High:         convertToStringThenFloat64(rawYearlyQuote, "最高價"),
-		Low:          convertToStringThenFloat64(rawYearlyQuote, "最低價"),
-		DateOfHigh:   dateOfHigh,
-		DateOfLow:    dateOfLow,
-	}
+	q.DateOfHigh = calendar.Normalize(dateOfHigh)
+	q.DateOfLow = calendar.Normalize(dateOfLow)
+	return q, nil
 }
 
-// FetchDayQuotes returns a map that maps stock symbols to their corresponding quotes on that date.
-func (c *Client) FetchDayQuotes(date time.Time) (map[string]Quote, error) {
-	rawData, err := c.fetchDayQuotes(date)
+// FetchDayQuotes returns a map that maps stock symbols to their corresponding quotes on that date. Only
+// the year, month and day of date are considered, see calendar.Normalize.
+func (c *Client) FetchDayQuotes(date time.Time) (map[string]DayQuote, error) {
+	qs := map[string]DayQuote{}
+	err := c.EachDayQuote(date, func(q DayQuote) error {
+		qs[q.Code] = q
+		return nil
+	})
 	if err != nil {
-		var e *noDataError
-		if errors.As(err, &e) {
-			return map[string]Quote{}, nil
-		}
 		return nil, err
 	}
 
-	rawDayQuotes := zipFieldsAndItems(rawData, "fields9", "data9")
+	return qs, nil
+}
 
-	qs := map[string]Quote{}
-	for _, rawDayQuote := range rawDayQuotes {
-		q := convertRawDayQuote(rawDayQuote, date)
-		qs[q.Code] = *q
+// FetchDayQuotesInOrder is FetchDayQuotes returning the quotes in the order of the TWSE, see EachDayQuote.
+func (c *Client) FetchDayQuotesInOrder(date time.Time) ([]DayQuote, error) {
+	qs := make([]DayQuote, 0)
+	err := c.EachDayQuote(date, func(q DayQuote) error {
+		qs = append(qs, q)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return qs, nil
 }
 
-// FetchDailyQuotes return a Quote slice containing daily quotes on the month of the year.
-func (c *Client) FetchDailyQuotes(code string, year int, month time.Month) ([]Quote, error) {
+// EachDayQuote calls fn with the quotes on date one at a time in the order of the TWSE, i.e. grouped by
+// the categories, e.g. ETFs before stocks, without holding all of them. fn returns ErrStop to stop early,
+// and other errors of fn are returned as they are. As FetchDayQuotes, fn is never called if the TWSE
+// provides no quotes on date, which is NoDataError only if Strict is set.
+func (c *Client) EachDayQuote(date time.Time, fn func(q DayQuote) error) error {
+	date = calendar.Normalize(date)
+
+	var fnErr error
+	err := c.streamDayQuotes(date, func(q *DayQuote) error {
+		if err := namefill.Day(c.NameResolver, q); err != nil {
+			return err
+		}
+		fnErr = fn(*q)
+		return fnErr
+	})
+	if err != nil {
+		if err == fnErr {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+
+		var e *NoDataError
+		if errors.As(err, &e) {
+			if !c.Strict {
+				return nil
+			}
+			e.Reason = nodata.OfDay(date, time.Now(), c.Calendar)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FetchDailyQuotes returns the daily quotes of a stock in the month of the year.
+func (c *Client) FetchDailyQuotes(code string, year int, month time.Month) ([]DayQuote, error) {
 	rawData, err := c.fetchDailyQuotes(code, year, month)
 	if err != nil {
-		var e *noDataError
+		var e *NoDataError
 		if errors.As(err, &e) {
-			return []Quote{}, nil
+			if !c.Strict {
+				return []DayQuote{}, nil
+			}
+			from := calendar.Date(year, month, 1)
+			return nil, c.explainNoData(e, code, from, from.AddDate(0, 1, 0))
 		}
 		return nil, err
 	}
 
-	rawDailyQuotes := zipFieldsAndItems(rawData, "fields", "data")
+	rawDailyQuotes, err := zipFieldsAndItems(rawData, "fields", "data")
+	if err != nil {
+		return nil, err
+	}
 
-	qs := make([]Quote, 0)
+	qs := make([]DayQuote, 0)
 	for _, rawDailyQuote := range rawDailyQuotes {
-		qs = append(qs, *convertRawDailyQuote(rawDailyQuote, code, year, month))
+		q, err := convertRawDailyQuote(rawDailyQuote, code, year, month)
+		if err != nil {
+			return nil, err
+		}
+		if err := namefill.Day(c.NameResolver, q); err != nil {
+			return nil, err
+		}
+		qs = append(qs, *q)
 	}
 
 	return qs, nil
 }
 
-// FetchMonthlyQuotes return a Quote slice containing monthly quotes of the year.
-func (c *Client) FetchMonthlyQuotes(code string, year int) ([]Quote, error) {
+// FetchMonthlyQuotes returns the monthly quotes of a stock in the year.
+func (c *Client) FetchMonthlyQuotes(code string, year int) ([]MonthlyQuote, error) {
 	rawData, err := c.fetchMonthlyQuotes(code, year)
 	if err != nil {
-		var e *noDataError
+		var e *NoDataError
 		if errors.As(err, &e) {
-			return []Quote{}, nil
+			if !c.Strict {
+				return []MonthlyQuote{}, nil
+			}
+			from := calendar.Date(year, time.January, 1)
+			return nil, c.explainNoData(e, code, from, from.AddDate(1, 0, 0))
 		}
 		return nil, err
 	}
 
-	rawMonthlyQuotes := zipFieldsAndItems(rawData, "fields", "data")
+	rawMonthlyQuotes, err := zipFieldsAndItems(rawData, "fields", "data")
+	if err != nil {
+		return nil, err
+	}
 
-	qs := make([]Quote, 0)
+	qs := make([]MonthlyQuote, 0)
 	for _, rawMonthlyQuote := range rawMonthlyQuotes {
-		qs = append(qs, *convertRawMonthlyQuote(rawMonthlyQuote, code, year))
+		q, err := convertRawMonthlyQuote(rawMonthlyQuote, code, year)
+		if err != nil {
+			return nil, err
+		}
+		if err := namefill.Monthly(c.NameResolver, q); err != nil {
+			return nil, err
+		}
+		qs = append(qs, *q)
 	}
 
 	return qs, nil
 }
 
-// FetchYearlyQuotes return a Quote slice containing yearly quotes of all time.
-func (c *Client) FetchYearlyQuotes(code string) ([]Quote, error) {
+// FetchYearlyQuotes returns the yearly quotes of a stock of all time.
+func (c *Client) FetchYearlyQuotes(code string) ([]YearlyQuote, error) {
 	rawData, err := c.fetchYearlyQuotes(code)
 	if err != nil {
-		var e *noDataError
+		var e *NoDataError
 		if errors.As(err, &e) {
-			return []Quote{}, nil
+			if !c.Strict {
+				return []YearlyQuote{}, nil
+			}
+			e.Reason = exchangeerr.ReasonUnknownCode
 		}
 		return nil, err
 	}
 
-	rawYearlyQuotes := zipFieldsAndItems(rawData, "fields", "data")
+	rawYearlyQuotes, err := zipFieldsAndItems(rawData, "fields", "data")
+	if err != nil {
+		return nil, err
+	}
 
-	qs := make([]Quote, 0)
+	qs := make([]YearlyQuote, 0)
 	for _, rawYearlyQuote := range rawYearlyQuotes {
-		qs = append(qs, *convertRawYearlyQuote(rawYearlyQuote, code))
+		q, err := convertRawYearlyQuote(rawYearlyQuote, code)
+		if err != nil {
+			return nil, err
+		}
+		if err := namefill.Yearly(c.NameResolver, q); err != nil {
+			return nil, err
+		}
+		qs = append(qs, *q)
 	}
 
 	return qs, nil
 }
+
+// explainNoData sets the reason of e for no quotes of code from from until to, exclusively, and returns e,
+// or the error of the extra request to tell the reason.
+func (c *Client) explainNoData(e *NoDataError, code string, from, to time.Time) error {
+	reason, err := nodata.OfPeriod(from, to, time.Now(), func() ([]int, error) {
+		lenient := *c
+		lenient.Strict = false
+		qs, err := lenient.FetchYearlyQuotes(code)
+		if err != nil {
+			return nil, err
+		}
+
+		years := make([]int, 0, len(qs))
+		for _, q := range qs {
+			years = append(years, q.Year)
+		}
+		return years, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	e.Reason = reason
+	return e
+}
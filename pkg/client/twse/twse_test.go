@@ -1,6 +1,8 @@
 package twse_test
 
 import (
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"testing"
@@ -9,12 +11,16 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
 	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
 	"github.com/chehsunliu/tshakutshai/pkg/internal/tkttest"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
 )
 
 func TestClient_FetchDayQuotes(t *testing.T) {
 	date := time.Date(2021, 3, 24, 0, 0, 0, 0, time.UTC)
+	expectedDate := calendar.Date(2021, time.March, 24)
 
 	mockResponse := tkttest.NewJsonResponseFromGzipFile("./testdata/quotes-tw-20210324.json.gz", 200)
 	mockHttpClient := &tkttest.MockHttpClient{}
@@ -29,38 +35,114 @@ func TestClient_FetchDayQuotes(t *testing.T) {
 	assert.Nilf(t, err, "%+v", err)
 	assert.Greater(t, len(quotes), 20000)
 
-	assert.Equal(t, twse.Quote{
+	assert.Equal(t, twse.DayQuote{
 		Code:         "0050",
 		Name:         "元大台灣50",
-		Date:         date,
+		Date:         expectedDate,
 		Volume:       11_082_813,
 		Transactions: 20_959,
 		Value:        1_459_923_222,
-		Open:         131.80,
-		High:         132.45,
-		Low:          131.30,
-		Close:        131.50,
+		Open:         price.MustParse("131.80"),
+		High:         price.MustParse("132.45"),
+		Low:          price.MustParse("131.30"),
+		Close:        price.MustParse("131.50"),
 	}, quotes["0050"])
 
-	assert.Equal(t, twse.Quote{
+	assert.Equal(t, twse.DayQuote{
 		Code:         "2330",
 		Name:         "台積電",
-		Date:         date,
+		Date:         expectedDate,
 		Volume:       115_318_351,
 		Transactions: 242_138,
 		Value:        66_559_451_738,
-		Open:         571.00,
-		High:         582.00,
-		Low:          571.00,
-		Close:        576.00,
+		Open:         price.MustParse("571.00"),
+		High:         price.MustParse("582.00"),
+		Low:          price.MustParse("571.00"),
+		Close:        price.MustParse("576.00"),
 	}, quotes["2330"])
 
 	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
 }
 
+func newDayQuotesClient() *twse.Client {
+	mockResponse := tkttest.NewJsonResponseFromGzipFile("./testdata/quotes-tw-20210324.json.gz", 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.Anything).Return(mockResponse, nil)
+	return &twse.Client{HttpClient: mockHttpClient}
+}
+
+func TestClient_FetchDayQuotesInOrder(t *testing.T) {
+	date := calendar.Date(2021, 3, 24)
+
+	qs, err := newDayQuotesClient().FetchDayQuotesInOrder(date)
+	assert.Nilf(t, err, "%+v", err)
+
+	m, err := newDayQuotesClient().FetchDayQuotes(date)
+	assert.Nilf(t, err, "%+v", err)
+	assert.Equal(t, len(m), len(qs))
+	for _, q := range qs {
+		assert.Equal(t, m[q.Code], q)
+	}
+
+	// ETFs come before stocks.
+	assert.Equal(t, "0050", qs[0].Code)
+	assert.Equal(t, "0051", qs[1].Code)
+}
+
+func TestClient_EachDayQuote(t *testing.T) {
+	date := calendar.Date(2021, 3, 24)
+
+	var codes []string
+	err := newDayQuotesClient().EachDayQuote(date, func(q twse.DayQuote) error {
+		codes = append(codes, q.Code)
+		if len(codes) == 3 {
+			return twse.ErrStop
+		}
+		return nil
+	})
+	assert.Nil(t, err, "ErrStop should not be returned")
+	assert.Equal(t, []string{"0050", "0051", "0052"}, codes)
+
+	errSink := errors.New("sink failed")
+	n := 0
+	err = newDayQuotesClient().EachDayQuote(date, func(q twse.DayQuote) error {
+		n++
+		return errSink
+	})
+	assert.Equal(t, errSink, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestClient_FetchDayQuotesInEnglish(t *testing.T) {
+	mockResponse := tkttest.NewJsonResponseFromGzipFile("./testdata/quotes-en-20210325.json.gz", 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		u := req.URL
+		return u.Path == "/exchangeReport/MI_INDEX" && u.Query().Get("date") == "20210325" &&
+			u.Query().Get("lang") == "en"
+	})).Return(mockResponse, nil)
+
+	client := &twse.Client{HttpClient: mockHttpClient, Language: twse.LanguageEnglish}
+	quotes, err := client.FetchDayQuotes(calendar.Date(2021, 3, 25))
+
+	assert.Nilf(t, err, "%+v", err)
+	assert.Greater(t, len(quotes), 20000)
+
+	assert.Equal(t, twse.DayQuote{
+		Code:         "2330",
+		Date:         calendar.Date(2021, time.March, 25),
+		Volume:       74_225_812,
+		Transactions: 115_951,
+		Value:        42_594_849_855,
+		Open:         price.MustParse("572.00"),
+		High:         price.MustParse("581.00"),
+		Low:          price.MustParse("570.00"),
+		Close:        price.MustParse("575.00"),
+	}, quotes["2330"])
+}
 func TestClient_FetchDailyQuotes(t *testing.T) {
 	code := "2330"
-	date := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	date := calendar.Date(2021, 2, 1)
 
 	mockResponse := tkttest.NewJsonResponseFromGzipFile("./testdata/quotes-tw-202102-2330.json.gz", 200)
 	mockHttpClient := &tkttest.MockHttpClient{}
@@ -77,17 +159,17 @@ func TestClient_FetchDailyQuotes(t *testing.T) {
 	assert.Nilf(t, err, "%+v", err)
 	assert.Greater(t, len(quotes), 10)
 
-	assert.Equal(t, twse.Quote{
+	assert.Equal(t, twse.DayQuote{
 		Code:         "2330",
 		Name:         "",
 		Date:         date,
 		Volume:       70_161_939,
 		Transactions: 81_346,
 		Value:        42_004_241_697,
-		Open:         595.00,
-		High:         612.00,
-		Low:          587.00,
-		Close:        611.00,
+		Open:         price.MustParse("595.00"),
+		High:         price.MustParse("612.00"),
+		Low:          price.MustParse("587.00"),
+		Close:        price.MustParse("611.00"),
 	}, quotes[0])
 
 	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
@@ -112,14 +194,15 @@ func TestClient_FetchMonthlyQuotes(t *testing.T) {
 	assert.Nilf(t, err, "%+v", err)
 	assert.Equal(t, 12, len(qs))
 
-	assert.Equal(t, twse.Quote{
+	assert.Equal(t, twse.MonthlyQuote{
 		Code:         code,
-		Date:         time.Date(year, time.April, 1, 0, 0, 0, 0, time.UTC),
+		Year:         year,
+		Month:        time.April,
 		Volume:       218_553_058,
 		Transactions: 146_711,
 		Value:        80_262_421_295,
-		High:         415.50,
-		Low:          325.50,
+		High:         price.MustParse("415.50"),
+		Low:          price.MustParse("325.50"),
 	}, qs[3])
 
 	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
@@ -141,16 +224,16 @@ func TestClient_FetchYearlyQuotes(t *testing.T) {
 	assert.Nilf(t, err, "%+v", err)
 	assert.Equal(t, 18, len(qs))
 
-	assert.Equal(t, twse.Quote{
+	assert.Equal(t, twse.YearlyQuote{
 		Code:         code,
-		Date:         time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Year:         2020,
 		Volume:       2_564_396_277,
 		Value:        234_459_163_641,
 		Transactions: 1_413_186,
-		High:         122.40,
-		Low:          67.25,
-		DateOfHigh:   time.Date(2020, time.December, 31, 0, 0, 0, 0, time.UTC),
-		DateOfLow:    time.Date(2020, time.March, 19, 0, 0, 0, 0, time.UTC),
+		High:         price.MustParse("122.40"),
+		Low:          price.MustParse("67.25"),
+		DateOfHigh:   calendar.Date(2020, time.December, 31),
+		DateOfLow:    calendar.Date(2020, time.March, 19),
 	}, qs[17])
 
 	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
@@ -246,3 +329,190 @@ func TestClient_FetchDayQuotesTooFrequentlyCausingEmptyReply(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.ErrorAs(t, err, &twseErr)
 }
+
+func TestClient_FetchYearlyQuotesWithBaseURL(t *testing.T) {
+	mockResponse := tkttest.NewJsonResponseFromGzipFile("./testdata/quotes-tw-0050.json.gz", 200)
+	mockHttpClient := &tkttest.MockHttpClient{}
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		u := req.URL
+		return u.Scheme == "http" && u.Host == "proxy.local:8080" && u.Path == "/twse/exchangeReport/FMNPTK" &&
+			u.Query().Get("stockNo") == "0050"
+	})).Return(mockResponse, nil)
+
+	client := &twse.Client{HttpClient: mockHttpClient, BaseURL: "http://proxy.local:8080/twse/"}
+	qs, err := client.FetchYearlyQuotes("0050")
+	assert.Nilf(t, err, "%v", err)
+	assert.NotEqual(t, 0, len(qs))
+}
+
+func TestClient_FetchYearlyQuotesWithInvalidBaseURL(t *testing.T) {
+	mockHttpClient := &tkttest.MockHttpClient{}
+
+	for _, baseURL := range []string{"www.twse.com.tw", "ftp://www.twse.com.tw", "https://", "https://www.twse.com.tw?a=b", "://"} {
+		client := &twse.Client{HttpClient: mockHttpClient, BaseURL: baseURL}
+		_, err := client.FetchYearlyQuotes("0050")
+		assert.NotNilf(t, err, "%s", baseURL)
+	}
+
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 0)
+}
+
+func TestClient_FetchQuotesWithMalformedValues(t *testing.T) {
+	fetchDaily := func(c *twse.Client) error { _, err := c.FetchDailyQuotes("0050", 2021, time.March); return err }
+
+	for _, tc := range []struct {
+		body    string
+		fetch   func(c *twse.Client) error
+		message string
+	}{
+		{
+			`{"stat":"OK","fields":["日期","成交股數","成交金額","開盤價","最高價","最低價","收盤價","漲跌價差","成交筆數"],"data":[["110/03","6,092,000","840,224,040","138.00","138.35","137.55","138.00","-0.10","3,457"]]}`,
+			fetchDaily,
+			"ill-formatted date '110/03'",
+		},
+		{
+			`{"stat":"OK","fields":["日期","成交股數","成交金額","開盤價","最高價","最低價","收盤價","漲跌價差","成交筆數"],"data":[["110/03/02","6,O92,000","840,224,040","138.00","138.35","137.55","138.00","-0.10","3,457"]]}`,
+			fetchDaily,
+			"value 6,O92,000 of field '成交股數'",
+		},
+		{
+			`{"stat":"OK","fields":["日期","成交股數","成交金額","開盤價","最高價","最低價","收盤價","漲跌價差","成交筆數"],"data":[["110/03/02","6,092,000","840,224,040","138.00"]]}`,
+			fetchDaily,
+			"has 9 elements but item",
+		},
+		{
+			`{"fields":[],"data":[]}`,
+			fetchDaily,
+			"key 'stat' does not exist",
+		},
+		{
+			`{"stat":"OK","fields":["年度","月份","最高價","最低價","加權(A/B)平均價","成交筆數","成交金額(A)","成交股數(B)","週轉率(%)"],"data":[[110,"1","145.00","130.60","138.07","263,946","32,093,393,812","232,386,563","1.86"]]}`,
+			func(c *twse.Client) error { _, err := c.FetchMonthlyQuotes("0050", 2021); return err },
+			"value 1 of field '月份'",
+		},
+		{
+			`{"stat":"OK","fields":["年度","成交股數","成交金額","成交筆數","最高價","日期","最低價","日期","收盤平均價"],"data":[[109,"2,376,016,862","244,048,128,564","937,412","122.40","12/45","67.25","3/19","103.39"]]}`,
+			func(c *twse.Client) error { _, err := c.FetchYearlyQuotes("0050"); return err },
+			"12/45 is not a legal month/day",
+		},
+		{
+			`{"stat":"OK","fields":["證券代號","證券名稱","成交股數","成交筆數","成交金額","成交價"],"data":[["0050","元大台灣50","2,000","1","278,000","139..00"]]}`,
+			func(c *twse.Client) error { _, err := c.FetchAfterHoursTrades(calendar.Date(2021, 3, 24)); return err },
+			"value 139..00 of field '成交價'",
+		},
+	} {
+		mockHttpClient := &tkttest.MockHttpClient{}
+		mockHttpClient.On("Do", mock.Anything).Return(tkttest.NewResponseFromString(tc.body, 200), nil)
+
+		err := tc.fetch(&twse.Client{HttpClient: mockHttpClient})
+		assert.Truef(t, errors.Is(err, exchangeerr.ErrParse), "%v", err)
+
+		var e *twse.ParseError
+		if assert.True(t, errors.As(err, &e)) {
+			assert.Contains(t, e.Message, tc.message)
+		}
+	}
+}
+
+func TestClient_FetchWithoutHttpClient(t *testing.T) {
+	client := &twse.Client{}
+	date := time.Date(2021, 3, 24, 0, 0, 0, 0, time.UTC)
+
+	_, err := client.FetchDayQuotes(date)
+	assert.True(t, errors.Is(err, twse.ErrNoClient), "%v", err)
+	_, err = client.FetchDailyQuotes("0050", 2021, time.March)
+	assert.True(t, errors.Is(err, twse.ErrNoClient), "%v", err)
+	_, err = client.FetchMonthlyQuotes("0050", 2021)
+	assert.True(t, errors.Is(err, twse.ErrNoClient), "%v", err)
+	_, err = client.FetchYearlyQuotes("0050")
+	assert.True(t, errors.Is(err, twse.ErrNoClient), "%v", err)
+
+	err = client.EachDayQuote(date, func(q twse.DayQuote) error { return nil })
+	assert.True(t, errors.Is(err, twse.ErrNoClient), "%v", err)
+}
+
+func TestQuote_MarshalJSON(t *testing.T) {
+	dayQuote := twse.Quote{
+		Code:         "2330",
+		Name:         "台積電",
+		Date:         calendar.Date(2021, time.March, 24),
+		Volume:       115_318_351,
+		Transactions: 242_138,
+		Value:        66_559_451_738,
+		High:         price.MustParse("582.00"),
+		Low:          price.MustParse("571.00"),
+		Open:         price.MustParse("571.00"),
+		Close:        price.MustParse("576.00"),
+	}
+	yearlyQuote := twse.Quote{
+		Code:         "0050",
+		Date:         calendar.Date(2020, time.January, 1),
+		Volume:       4_236_045_283,
+		Transactions: 1_569_123,
+		Value:        418_361_838_316,
+		High:         price.MustParse("122.40"),
+		Low:          price.MustParse("67.25"),
+		DateOfHigh:   calendar.Date(2020, time.December, 31),
+		DateOfLow:    calendar.Date(2020, time.March, 19),
+	}
+
+	for _, tc := range []struct {
+		quote    twse.Quote
+		expected string
+	}{
+		{
+			dayQuote,
+			`{"code":"2330","name":"台積電","date":"2021-03-24","open":571.00,"high":582.00,"low":571.00,` +
+				`"close":576.00,"volume":115318351,"transactions":242138,"value":66559451738,"date_of_high":null,` +
+				`"date_of_low":null}`,
+		},
+		{
+			// The keys do not depend on the values, e.g. the prices of a day without transactions.
+			twse.Quote{Code: "2330", Date: calendar.Date(2021, time.March, 24)},
+			`{"code":"2330","date":"2021-03-24","open":0.00,"high":0.00,"low":0.00,"close":0.00,"volume":0,` +
+				`"transactions":0,"value":0,"date_of_high":null,"date_of_low":null}`,
+		},
+		{
+			yearlyQuote,
+			`{"code":"0050","date":"2020-01-01","open":0.00,"high":122.40,"low":67.25,"close":0.00,"volume":4236045283,` +
+				`"transactions":1569123,"value":418361838316,"date_of_high":"2020-12-31","date_of_low":"2020-03-19"}`,
+		},
+	} {
+		data, err := json.Marshal(tc.quote)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expected, string(data))
+
+		var q twse.Quote
+		assert.Nil(t, json.Unmarshal(data, &q))
+		assert.Equal(t, tc.quote, q)
+	}
+}
+
+func TestQuote_UnmarshalJSONWithInvalidDate(t *testing.T) {
+	var q twse.Quote
+	assert.NotNil(t, json.Unmarshal([]byte(`{"code":"2330","date":"2021/03/24"}`), &q))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"code":"2330","date_of_high":"0001-01-01T00:00:00Z"}`), &q))
+}
+
+func TestQuoteFromMonthly(t *testing.T) {
+	q := twse.QuoteFromMonthly(twse.MonthlyQuote{
+		Code:         "2454",
+		Year:         2020,
+		Month:        time.April,
+		High:         price.MustParse("415.50"),
+		Low:          price.MustParse("325.50"),
+		Volume:       218_553_058,
+		Transactions: 146_711,
+		Value:        80_262_421_295,
+	})
+
+	assert.Equal(t, twse.Quote{
+		Code:         "2454",
+		Date:         calendar.Date(2020, time.April, 1),
+		Volume:       218_553_058,
+		Transactions: 146_711,
+		Value:        80_262_421_295,
+		High:         price.MustParse("415.50"),
+		Low:          price.MustParse("325.50"),
+	}, q)
+}
@@ -6,48 +6,50 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+
+	"github.com/chehsunliu/tshakutshai/pkg/price"
 )
 
-func retrieveStat(rawData map[string]json.RawMessage) string {
+func retrieveStat(rawData map[string]json.RawMessage) (string, error) {
 	rawStat, ok := rawData["stat"]
 	if !ok {
-		panic("key 'stat' does not exist")
+		return "", &ParseError{Message: "key 'stat' does not exist"}
 	}
 
 	var stat string
 	if err := json.Unmarshal(rawStat, &stat); err != nil {
-		panic(fmt.Sprintf("failed to unmarshal: %s", err))
+		return "", &ParseError{fmt.Sprintf("failed to decode stat: %s", err), err}
 	}
 
-	return stat
+	return stat, nil
 }
 
-func retrieveFields(rawData map[string]json.RawMessage, key string) []string {
+func retrieveFields(rawData map[string]json.RawMessage, key string) ([]string, error) {
 	rawFields, ok := rawData[key]
 	if !ok {
-		panic(fmt.Sprintf("key '%s' does not exist", key))
+		return nil, &ParseError{Message: fmt.Sprintf("key '%s' does not exist", key)}
 	}
 
 	var fields []string
 	if err := json.Unmarshal(rawFields, &fields); err != nil {
-		panic(fmt.Sprintf("failed to unmarshal: %s", err))
+		return nil, &ParseError{fmt.Sprintf("failed to decode %s: %s", key, err), err}
 	}
 
-	return fields
+	return fields, nil
 }
 
-func retrieveItems(rawData map[string]json.RawMessage, key string) [][]interface{} {
+func retrieveItems(rawData map[string]json.RawMessage, key string) ([][]interface{}, error) {
 	rawItems, ok := rawData[key]
 	if !ok {
-		panic(fmt.Sprintf("key '%s' does not exist", key))
+		return nil, &ParseError{Message: fmt.Sprintf("key '%s' does not exist", key)}
 	}
 
 	var items [][]interface{}
 	if err := json.Unmarshal(rawItems, &items); err != nil {
-		panic(fmt.Sprintf("failed to unmarshal: %s", err))
+		return nil, &ParseError{fmt.Sprintf("failed to decode %s: %s", key, err), err}
 	}
 
-	return items
+	return items, nil
 }
 
 func suffixDuplicateFields(fields []string) []string {
@@ -74,87 +76,124 @@ func suffixDuplicateFields(fields []string) []string {
 	return fields
 }
 
-func zipFieldsAndItems(rawData map[string]json.RawMessage, fieldsKey, itemsKey string) []map[string]interface{} {
-	fields := retrieveFields(rawData, fieldsKey)
-	items := retrieveItems(rawData, itemsKey)
+func zipFieldsAndItems(rawData map[string]json.RawMessage, fieldsKey, itemsKey string) ([]map[string]interface{}, error) {
+	fields, err := retrieveFields(rawData, fieldsKey)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := retrieveItems(rawData, itemsKey)
+	if err != nil {
+		return nil, err
+	}
 
+	// The English field names are translated to the Chinese ones first, see englishFields.
+	//
 	// The TWSE uses the same field name to denote the days of the highest and
 	// the lowest prices in yearly quotes. Here I just made the second appearance
 	// to be 'name2', the third one to be 'name3' and so on.
-	fields = suffixDuplicateFields(fields)
+	fields = suffixDuplicateFields(translateFields(fields))
 	rawRecords := make([]map[string]interface{}, len(items))
 
 	for i := range rawRecords {
 		if len(fields) != len(items[i]) {
-			panic(fmt.Sprintf("fields %v has %d elements but item %v has only %d", fields, len(fields), items[i], len(items[i])))
+			return nil, &ParseError{
+				Message: fmt.Sprintf("fields %v has %d elements but item %v has %d", fields, len(fields), items[i], len(items[i])),
+			}
 		}
 
 		rawRecord := map[string]interface{}{}
 		for j := range fields {
-			if j >= len(items[i]) {
-				break
-			}
-
 			rawRecord[fields[j]] = items[i][j]
 		}
 
 		rawRecords[i] = rawRecord
 	}
 
-	return rawRecords
+	return rawRecords, nil
+}
+
+// converter converts the values of a raw record, keeping the first error as ParseError, so that a record
+// is converted in a composite literal and checked once:
+//
+//     var conv converter
+//     q := DayQuote{Volume: conv.uint64(rawQuote, "成交股數"), Close: conv.price(rawQuote, "收盤價")}
+//     if conv.err != nil {
+//         return conv.err
+//     }
+type converter struct {
+	err error
+}
+
+func (c *converter) fail(message string, err error) {
+	if c.err == nil {
+		c.err = &ParseError{message, err}
+	}
 }
 
-func convertToString(rawQuote map[string]interface{}, field string) string {
-	i, ok := rawQuote[field]
+func (c *converter) string(rawRecord map[string]interface{}, field string) string {
+	i, ok := rawRecord[field]
 	if !ok {
-		panic(fmt.Sprintf("field '%s' does not exist in %v", field, rawQuote))
+		c.fail(fmt.Sprintf("field '%s' does not exist in %v", field, rawRecord), nil)
+		return ""
 	}
 
 	s, ok := i.(string)
 	if !ok {
-		panic(fmt.Sprintf("value %v of field '%s' in %v is not string", i, field, rawQuote))
+		c.fail(fmt.Sprintf("value %v of field '%s' in %v is not string", i, field, rawRecord), nil)
 	}
 
 	return s
 }
 
-func convertToFloat64(rawQuote map[string]interface{}, field string) float64 {
-	i, ok := rawQuote[field]
+func (c *converter) float64(rawRecord map[string]interface{}, field string) float64 {
+	i, ok := rawRecord[field]
 	if !ok {
-		panic(fmt.Sprintf("field '%s' does not exist in %v", field, rawQuote))
+		c.fail(fmt.Sprintf("field '%s' does not exist in %v", field, rawRecord), nil)
+		return 0
 	}
 
 	f, ok := i.(float64)
 	if !ok {
-		panic(fmt.Sprintf("value %v of field '%s' in %v is not int but %s", i, field, rawQuote, reflect.TypeOf(i)))
+		c.fail(fmt.Sprintf("value %v of field '%s' in %v is not number but %s", i, field, rawRecord, reflect.TypeOf(i)), nil)
 	}
 
 	return f
 }
 
-func convertToStringThenUint64(rawQuote map[string]interface{}, field string) uint64 {
-	s := convertToString(rawQuote, field)
+// uint64 converts numbers in strings like 1,234.
+func (c *converter) uint64(rawRecord map[string]interface{}, field string) uint64 {
+	s := c.string(rawRecord, field)
 
-	v, err := strconv.ParseUint(strings.Replace(s, ",", "", -1), 10, 64)
+	v, err := parseUint64(s)
 	if err != nil {
-		panic(fmt.Sprintf("value %v of field '%s' in %v is not uint64: %s", s, field, rawQuote, err))
+		c.fail(fmt.Sprintf("value %v of field '%s' in %v is not uint64: %s", s, field, rawRecord, err), err)
 	}
 
 	return v
 }
 
-func convertToStringThenFloat64(rawQuote map[string]interface{}, field string) float64 {
-	s := convertToString(rawQuote, field)
+// price converts prices in strings, see parsePrice.
+func (c *converter) price(rawRecord map[string]interface{}, field string) price.Price {
+	s := c.string(rawRecord, field)
 
-	// If a stock have no transactions made, its 4 prices will be '--'.
-	if s == "--" {
-		return 0
-	}
-
-	v, err := strconv.ParseFloat(strings.Replace(s, ",", "", -1), 64)
+	v, err := parsePrice(s)
 	if err != nil {
-		panic(fmt.Sprintf("value %v of field '%s' in %v is not float64: %s", s, field, rawQuote, err))
+		c.fail(fmt.Sprintf("value %v of field '%s' in %v is not price: %s", s, field, rawRecord, err), err)
 	}
 
 	return v
 }
+
+func parseUint64(s string) (uint64, error) {
+	return strconv.ParseUint(strings.Replace(s, ",", "", -1), 10, 64)
+}
+
+func parsePrice(s string) (price.Price, error) {
+	// If a stock have no transactions made, its 4 prices will be '--'.
+	if s == "--" {
+		return 0, nil
+	}
+
+	return price.Parse(s)
+}
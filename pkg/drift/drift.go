@@ -0,0 +1,309 @@
+// Package drift detects changes of the schemas of the responses of the TWSE and the TPEx, which the
+// exchanges make without notice.
+//
+// Record fetches quotes through the twse and tpex clients by probes, saves the responses as gzipped
+// fixtures and fingerprints their schemas into a manifest:
+//
+//     client := tkthttp.NewThrottledClient(&http.Client{Timeout: time.Second * 30}, time.Second*2)
+//     _, err := drift.Record("testdata", client, drift.DefaultProbes(calendar.Date(2021, 3, 24)))
+//
+// Verify fetches them again later and reports the endpoints whose schemas differ from the manifest:
+//
+//     drifts, err := drift.Verify("testdata", client, drift.DefaultProbes(time.Now()))
+//     for _, d := range drifts {
+//         fmt.Println(d) // twse-day /exchangeReport/MI_INDEX: ~ $.data9[]: array[16] -> array[17]
+//     }
+//
+// Responses of dates without quotes, e.g. holidays, have empty rows and thus fingerprints different from
+// the ones of trading days, so record and verify quotes of trading days.
+package drift
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+)
+
+// ManifestFile is the name of the manifest in the directories of fixtures.
+const ManifestFile = "fingerprints.json"
+
+// Probe fetches quotes of an endpoint through client.
+type Probe struct {
+	Name  string
+	Fetch func(client tkthttp.Client) error
+}
+
+// DefaultProbes returns the probes of the day, daily, monthly and yearly quotes of both exchanges, i.e.
+// the quotes on date, of 2330 and 8044 in the month of date and in the year before, and of all time.
+func DefaultProbes(date time.Time) []Probe {
+	year, month := date.Year(), date.Month()
+
+	return []Probe{
+		{"twse-day", func(c tkthttp.Client) error {
+			_, err := (&twse.Client{HttpClient: c}).FetchDayQuotes(date)
+			return err
+		}},
+		{"twse-daily", func(c tkthttp.Client) error {
+			_, err := (&twse.Client{HttpClient: c}).FetchDailyQuotes("2330", year, month)
+			return err
+		}},
+		{"twse-monthly", func(c tkthttp.Client) error {
+			_, err := (&twse.Client{HttpClient: c}).FetchMonthlyQuotes("2330", year-1)
+			return err
+		}},
+		{"twse-yearly", func(c tkthttp.Client) error {
+			_, err := (&twse.Client{HttpClient: c}).FetchYearlyQuotes("2330")
+			return err
+		}},
+		{"tpex-day", func(c tkthttp.Client) error {
+			_, err := (&tpex.Client{HttpClient: c}).FetchDayQuotes(date)
+			return err
+		}},
+		{"tpex-daily", func(c tkthttp.Client) error {
+			_, err := (&tpex.Client{HttpClient: c}).FetchDailyQuotes("8044", year, month)
+			return err
+		}},
+		{"tpex-monthly", func(c tkthttp.Client) error {
+			_, err := (&tpex.Client{HttpClient: c}).FetchMonthlyQuotes("8044", year-1)
+			return err
+		}},
+		{"tpex-yearly", func(c tkthttp.Client) error {
+			_, err := (&tpex.Client{HttpClient: c}).FetchYearlyQuotes("8044")
+			return err
+		}},
+	}
+}
+
+// Entry is the record of a probe in a manifest.
+type Entry struct {
+	// Path is the path of the URL of the endpoint, e.g. /exchangeReport/MI_INDEX.
+	Path string `json:"path"`
+	// Fixture is the path of the gzipped response relative to the directory of the manifest, which can be
+	// a fixture of other packages, e.g. ../../client/twse/testdata/quotes-tw-20210324.json.gz.
+	Fixture     string      `json:"fixture"`
+	Fingerprint Fingerprint `json:"fingerprint"`
+}
+
+// Manifest maps the names of probes to their records.
+type Manifest map[string]Entry
+
+// LoadManifest reads the manifest in dir.
+func LoadManifest(dir string) (Manifest, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", ManifestFile, err)
+	}
+
+	return m, nil
+}
+
+// ReadFixture returns the response of the entry saved in dir.
+func ReadFixture(dir string, e Entry) ([]byte, error) {
+	f, err := os.Open(filepath.Join(dir, e.Fixture))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GZIP reader: %w", err)
+	}
+
+	return ioutil.ReadAll(reader)
+}
+
+// Capture is a response captured by a probe.
+type Capture struct {
+	Path string
+	Body []byte
+}
+
+// capturingClient keeps the last response it receives.
+type capturingClient struct {
+	client tkthttp.Client
+
+	mutex   sync.Mutex
+	capture *Capture
+}
+
+func (c *capturingClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	c.capture = &Capture{Path: req.URL.Path, Body: body}
+	c.mutex.Unlock()
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// CaptureProbe runs p through client and returns the response it receives. Errors of parsing the response
+// are ignored, since they are likely due to the drifts to be detected.
+func CaptureProbe(client tkthttp.Client, p Probe) (Capture, error) {
+	c := &capturingClient{client: client}
+	if err := p.Fetch(c); err != nil && !errors.Is(err, exchangeerr.ErrParse) {
+		return Capture{}, fmt.Errorf("probe %s failed: %w", p.Name, err)
+	}
+	if c.capture == nil {
+		return Capture{}, fmt.Errorf("probe %s sent no requests", p.Name)
+	}
+
+	return *c.capture, nil
+}
+
+// Record runs the probes through client, saves the responses into dir and writes the manifest of them,
+// replacing the entries of the same probes, including the ones referring to fixtures of other packages.
+func Record(dir string, client tkthttp.Client, probes []Probe) (Manifest, error) {
+	m, err := LoadManifest(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		m, err = Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	for _, p := range probes {
+		c, err := CaptureProbe(client, p)
+		if err != nil {
+			return nil, err
+		}
+
+		fp, err := FingerprintOf(c.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fingerprint %s: %w", p.Name, err)
+		}
+
+		e := Entry{Path: c.Path, Fixture: p.Name + "." + fp[keyFormat] + ".gz", Fingerprint: fp}
+		if err := writeGzipFile(filepath.Join(dir, e.Fixture), c.Body); err != nil {
+			return nil, err
+		}
+		m[p.Name] = e
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ManifestFile), append(b, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func writeGzipFile(path string, body []byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(f)
+	if _, err := writer.Write(body); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Drift is the changes of the schema of an endpoint.
+type Drift struct {
+	Probe   string
+	Path    string
+	Changes []Change
+}
+
+func (d Drift) String() string {
+	changes := make([]string, 0, len(d.Changes))
+	for _, c := range d.Changes {
+		changes = append(changes, c.String())
+	}
+
+	return fmt.Sprintf("%s %s: %s", d.Probe, d.Path, strings.Join(changes, "; "))
+}
+
+// Verify runs the probes through client and returns the drifts of the responses from the manifest in dir,
+// in the order of the probes. The probes must have been recorded.
+func Verify(dir string, client tkthttp.Client, probes []Probe) ([]Drift, error) {
+	m, err := LoadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	drifts := make([]Drift, 0)
+	for _, p := range probes {
+		e, ok := m[p.Name]
+		if !ok {
+			return nil, fmt.Errorf("probe %s is not recorded in %s", p.Name, ManifestFile)
+		}
+
+		c, err := CaptureProbe(client, p)
+		if err != nil {
+			return nil, err
+		}
+
+		fp, err := FingerprintOf(c.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fingerprint %s: %w", p.Name, err)
+		}
+
+		changes := Diff(e.Fingerprint, fp)
+		if c.Path != e.Path {
+			changes = append([]Change{{Kind: Modified, Path: "path", Old: e.Path, New: c.Path}}, changes...)
+		}
+		if len(changes) > 0 {
+			drifts = append(drifts, Drift{Probe: p.Name, Path: c.Path, Changes: changes})
+		}
+	}
+
+	return drifts, nil
+}
+
+// Names returns the names of the probes recorded in m in order.
+func (m Manifest) Names() []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
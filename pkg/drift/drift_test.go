@@ -0,0 +1,177 @@
+package drift_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/drift"
+	"github.com/chehsunliu/tshakutshai/pkg/fakeexchange"
+)
+
+const fixtures = "./testdata"
+
+var probes = drift.DefaultProbes(calendar.Date(2021, 3, 24))
+
+// newServer returns a server serving the fixtures of the manifest.
+func newServer(t *testing.T, m drift.Manifest) *fakeexchange.Server {
+	s := fakeexchange.NewServer()
+	for _, name := range m.Names() {
+		e := m[name]
+		body, err := drift.ReadFixture(fixtures, e)
+		if !assert.Nil(t, err) {
+			continue
+		}
+		s.Handle(e.Path, nil, fakeexchange.Response{ContentType: contentTypeOf(e), Body: body})
+	}
+
+	return s
+}
+
+// contentTypeOf returns the content type the clients expect of the fixture of e.
+func contentTypeOf(e drift.Entry) string {
+	if e.Fingerprint["format"] == "csv" {
+		return "text/csv"
+	}
+	return "application/json"
+}
+
+// TestManifest_Fixtures checks that the recorded fixtures still have the recorded fingerprints.
+func TestManifest_Fixtures(t *testing.T) {
+	m, err := drift.LoadManifest(fixtures)
+	assert.Nil(t, err)
+	assert.Equal(t, len(probes), len(m))
+
+	for _, name := range m.Names() {
+		e := m[name]
+		body, err := drift.ReadFixture(fixtures, e)
+		assert.Nil(t, err)
+
+		fp, err := drift.FingerprintOf(body)
+		assert.Nil(t, err)
+		assert.Emptyf(t, drift.Diff(e.Fingerprint, fp), "fixture of %s", name)
+	}
+}
+
+// TestContract checks that the clients parse the recorded fixtures.
+func TestContract(t *testing.T) {
+	m, err := drift.LoadManifest(fixtures)
+	assert.Nil(t, err)
+
+	s := newServer(t, m)
+	defer s.Close()
+
+	for _, p := range probes {
+		assert.Nilf(t, p.Fetch(s.HttpClient()), "probe %s", p.Name)
+	}
+
+	drifts, err := drift.Verify(fixtures, s.HttpClient(), probes)
+	assert.Nil(t, err)
+	assert.Empty(t, drifts)
+}
+
+func TestVerify_Drift(t *testing.T) {
+	m, err := drift.LoadManifest(fixtures)
+	assert.Nil(t, err)
+
+	s := newServer(t, m)
+	defer s.Close()
+
+	// Insert a column into the daily quotes of the TWSE.
+	body, err := drift.ReadFixture(fixtures, m["twse-daily"])
+	assert.Nil(t, err)
+
+	var v struct {
+		Stat   string     `json:"stat"`
+		Date   string     `json:"date"`
+		Title  string     `json:"title"`
+		Fields []string   `json:"fields"`
+		Data   [][]string `json:"data"`
+		Notes  []string   `json:"notes"`
+	}
+	assert.Nil(t, json.Unmarshal(body, &v))
+	v.Fields = append(v.Fields, "本益比")
+	for i := range v.Data {
+		v.Data[i] = append(v.Data[i], "30.00")
+	}
+	body, err = json.Marshal(v)
+	assert.Nil(t, err)
+	s.Handle(m["twse-daily"].Path, nil, fakeexchange.Response{ContentType: "application/json", Body: body})
+
+	// Rename a column of the yearly quotes of the TPEx, which the client fails to parse.
+	body, err = drift.ReadFixture(fixtures, m["tpex-yearly"])
+	assert.Nil(t, err)
+	body = bytes.Replace(body, []byte("Average price"), []byte("Average closing price"), 1)
+	s.Handle(m["tpex-yearly"].Path, nil, fakeexchange.Response{ContentType: "text/csv", Body: body})
+
+	drifts, err := drift.Verify(fixtures, s.HttpClient(), probes)
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(drifts)) {
+		assert.Equal(t, "twse-daily", drifts[0].Probe)
+		assert.Equal(t, "/exchangeReport/STOCK_DAY", drifts[0].Path)
+		assert.Equal(t, []drift.Change{
+			{Kind: drift.Modified, Path: "$.data[]", Old: "array[9]", New: "array[10]"},
+			{Kind: drift.Modified, Path: "$.fields", Old: m["twse-daily"].Fingerprint["$.fields"],
+				New: `["日期","成交股數","成交金額","開盤價","最高價","最低價","收盤價","漲跌價差","成交筆數","本益比"]`},
+		}, drifts[0].Changes)
+
+		assert.Equal(t, "tpex-yearly", drifts[1].Probe)
+		assert.Equal(t, 1, len(drifts[1].Changes))
+		assert.Equal(t, "csv.Year", drifts[1].Changes[0].Path)
+		assert.Contains(t, drifts[1].Changes[0].New, `"Average closing price"`)
+	}
+}
+
+func TestVerify_NotRecorded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drift")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = drift.Verify(dir, nil, probes)
+	assert.NotNil(t, err)
+}
+
+func TestRecord(t *testing.T) {
+	m, err := drift.LoadManifest(fixtures)
+	assert.Nil(t, err)
+
+	s := newServer(t, m)
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "drift")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	recorded, err := drift.Record(dir, s.HttpClient(), probes[:2])
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(recorded))
+
+	recorded, err = drift.Record(dir, s.HttpClient(), probes[2:])
+	assert.Nil(t, err)
+	assert.Equal(t, m.Names(), recorded.Names(), "recording again should keep the other probes")
+
+	loaded, err := drift.LoadManifest(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, recorded, loaded)
+	for _, name := range m.Names() {
+		assert.Equal(t, m[name].Path, loaded[name].Path)
+		assert.Equal(t, m[name].Fingerprint, loaded[name].Fingerprint)
+	}
+	assert.Equal(t, "tpex-monthly.csv.gz", loaded["tpex-monthly"].Fixture)
+
+	body, err := drift.ReadFixture(dir, loaded["tpex-monthly"])
+	assert.Nil(t, err)
+	expected, err := drift.ReadFixture(fixtures, m["tpex-monthly"])
+	assert.Nil(t, err)
+	assert.Equal(t, expected, body)
+
+	// Failures of the exchanges are not recorded.
+	s.SetFault("", fakeexchange.FaultBan)
+	_, err = drift.Record(dir, s.HttpClient(), probes)
+	assert.NotNil(t, err)
+}
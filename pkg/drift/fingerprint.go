@@ -0,0 +1,262 @@
+package drift
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Fingerprint is the schema of a response, mapping the paths of the keys, the columns and the rows to their
+// shapes, e.g.
+//
+//     "format":         "json"
+//     "$.stat":         "string"
+//     "$.fields9":      `["證券代號","證券名稱",...]`
+//     "$.data9[]":      "array[16]"
+//     "csv.Year":       `["Year","Number of thousand shares traded",...]`
+//     "csv.Year[]":     "array[9]"
+//
+// Values of the responses are dropped except the names of the columns, so the fingerprints of responses
+// of different dates and stocks are equal unless the exchange changes the schema.
+type Fingerprint map[string]string
+
+// keyFormat is the path of the format of the body in fingerprints.
+const keyFormat = "format"
+
+// Formats of the bodies of responses.
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+	formatHTML = "html"
+)
+
+// FingerprintOf returns the fingerprint of the body of a response. JSON bodies are fingerprinted by their
+// keys, the fields arrays and the column counts of the rows, CSV ones by their titles, labels, headers and
+// column counts of the rows, and HTML ones by the format only.
+//
+// The format is told by the body rather than the content type, which the exchanges do not keep, e.g. the
+// TPEx serves JSON as text/html.
+func FingerprintOf(body []byte) (Fingerprint, error) {
+	format := formatOf(body)
+
+	shapes := shapeSet{}
+	shapes.add(keyFormat, format)
+
+	switch format {
+	case formatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+
+		var v interface{}
+		if err := decoder.Decode(&v); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON: %w", err)
+		}
+		shapes.walkJSON("$", "", v)
+	case formatCSV:
+		shapes.walkCSV(string(body))
+	}
+
+	return shapes.fingerprint(), nil
+}
+
+// formatOf tells the format of body by its first character.
+func formatOf(body []byte) string {
+	body = bytes.TrimLeft(body, " \t\r\n")
+	switch {
+	case len(body) > 0 && (body[0] == '{' || body[0] == '['):
+		return formatJSON
+	case len(body) > 0 && body[0] == '<':
+		return formatHTML
+	default:
+		return formatCSV
+	}
+}
+
+// shapeSet collects the shapes of each path, which can be more than one, e.g. rows of different column
+// counts.
+type shapeSet map[string]map[string]bool
+
+func (s shapeSet) add(path, shape string) {
+	if s[path] == nil {
+		s[path] = map[string]bool{}
+	}
+	s[path][shape] = true
+}
+
+func (s shapeSet) fingerprint() Fingerprint {
+	fp := Fingerprint{}
+	for path, set := range s {
+		shapes := make([]string, 0, len(set))
+		for shape := range set {
+			shapes = append(shapes, shape)
+		}
+		sort.Strings(shapes)
+		fp[path] = strings.Join(shapes, "|")
+	}
+
+	return fp
+}
+
+func (s shapeSet) walkJSON(path, key string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		s.add(path, "object")
+		for k, e := range v {
+			s.walkJSON(path+"."+k, k, e)
+		}
+	case []interface{}:
+		// The names of the columns are a part of the schema, e.g. fields9 of MI_INDEX of the TWSE.
+		if names, ok := stringsOf(v); ok && strings.HasPrefix(key, "fields") {
+			s.add(path, quote(names))
+			return
+		}
+
+		s.add(path, "array")
+		for _, e := range v {
+			if row, ok := e.([]interface{}); ok {
+				s.add(path+"[]", fmt.Sprintf("array[%d]", len(row)))
+			} else {
+				s.walkJSON(path+"[]", key, e)
+			}
+		}
+	case string:
+		s.add(path, "string")
+	case json.Number:
+		s.add(path, "number")
+	case bool:
+		s.add(path, "bool")
+	case nil:
+		s.add(path, "null")
+	}
+}
+
+// walkCSV fingerprints the lines of text by the first fields. A line of a single field is a title, and of
+// two fields is a label with its value, e.g. "Stock code,8044". A line of more fields starting with letters
+// is a header, and the lines starting with others are the rows of the table of the last header.
+func (s shapeSet) walkCSV(text string) {
+	table := "csv"
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		fields := splitCSVLine(line)
+		if len(fields) == 1 && fields[0] == "" {
+			continue
+		}
+
+		if !hasLetter(fields[0]) {
+			s.add(table+"[]", fmt.Sprintf("array[%d]", len(fields)))
+			continue
+		}
+
+		path := "csv." + fields[0]
+		switch len(fields) {
+		case 1:
+			s.add(path, "title")
+		case 2:
+			s.add(path, "label")
+		default:
+			s.add(path, quote(fields))
+			table = path
+		}
+	}
+}
+
+// splitCSVLine splits line by the commas outside quotes, and trims the spaces and the quotes of the
+// fields. The TPEx quotes only parts of some fields, e.g. `"Trading Value (NTD, in thousands)" (A)`,
+// which the CSV package rejects.
+func splitCSVLine(line string) []string {
+	fields := make([]string, 0)
+	quoted, start := false, 0
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			fields = append(fields, line[start:i])
+			start = i + 1
+		}
+	}
+	fields = append(fields, line[start:])
+
+	for i, f := range fields {
+		fields[i] = strings.Join(strings.Fields(strings.ReplaceAll(f, `"`, "")), " ")
+	}
+
+	return fields
+}
+
+func hasLetter(s string) bool {
+	return strings.IndexFunc(s, unicode.IsLetter) >= 0
+}
+
+func stringsOf(vs []interface{}) ([]string, bool) {
+	ss := make([]string, 0, len(vs))
+	for _, v := range vs {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		ss = append(ss, s)
+	}
+
+	return ss, true
+}
+
+func quote(ss []string) string {
+	b, _ := json.Marshal(ss)
+	return string(b)
+}
+
+// ChangeKind tells how a path of a fingerprint changed.
+type ChangeKind int
+
+const (
+	// Added paths are in the new fingerprint only.
+	Added ChangeKind = iota
+	// Removed paths are in the old fingerprint only.
+	Removed
+	// Modified paths have different shapes.
+	Modified
+)
+
+// Change is a difference of a path between two fingerprints.
+type Change struct {
+	Kind ChangeKind
+	Path string
+	Old  string
+	New  string
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, c.New)
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, c.Old, c.New)
+	}
+}
+
+// Diff returns the changes from old to new in the order of the paths, or nil if they are equal.
+func Diff(old, new Fingerprint) []Change {
+	var changes []Change
+	for path, o := range old {
+		n, ok := new[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: Removed, Path: path, Old: o})
+		case n != o:
+			changes = append(changes, Change{Kind: Modified, Path: path, Old: o, New: n})
+		}
+	}
+	for path, n := range new {
+		if _, ok := old[path]; !ok {
+			changes = append(changes, Change{Kind: Added, Path: path, New: n})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
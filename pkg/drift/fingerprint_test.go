@@ -0,0 +1,92 @@
+package drift_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/drift"
+)
+
+func TestFingerprintOf_JSON(t *testing.T) {
+	body := `{"stat":"OK","total":2,"fields":["代號","收盤價"],"data":[["2330","611.00"],["0050","131.50"]],` +
+		`"notes":["a","b"],"groups":[{"start":0,"title":"(元)"}],"params":{"format":null,"ok":true}}`
+
+	fp, err := drift.FingerprintOf([]byte(body))
+	assert.Nil(t, err)
+	assert.Equal(t, drift.Fingerprint{
+		"format":           "json",
+		"$":                "object",
+		"$.stat":           "string",
+		"$.total":          "number",
+		"$.fields":         `["代號","收盤價"]`,
+		"$.data":           "array",
+		"$.data[]":         "array[2]",
+		"$.notes":          "array",
+		"$.notes[]":        "string",
+		"$.groups":         "array",
+		"$.groups[]":       "object",
+		"$.groups[].start": "number",
+		"$.groups[].title": "string",
+		"$.params":         "object",
+		"$.params.format":  "null",
+		"$.params.ok":      "bool",
+	}, fp)
+
+	_, err = drift.FingerprintOf([]byte(`{"stat":`))
+	assert.NotNil(t, err)
+}
+
+func TestFingerprintOf_CSV(t *testing.T) {
+	body := "Recent Trading Information by Stock\r\n" +
+		"Stock code,8044\r\n" +
+		"Year,Number of thousand shares traded ,\"Amount (NTD, in thousands)\" ,Highest price\r\n" +
+		"\"2021\",\"55,384\",\"4,779,448\",\"93.00\"\r\n" +
+		"\"2020\",\"392,843\",\"40,810,347\"\r\n" +
+		"\r\n" +
+		"Highest price in recent years ,Date ,Lowest price in recent years\r\n" +
+		"\"537.0000\",\"2015/05/04\",\"17.4000\"\r\n"
+
+	fp, err := drift.FingerprintOf([]byte(body))
+	assert.Nil(t, err)
+	assert.Equal(t, drift.Fingerprint{
+		"format": "csv",
+		"csv.Recent Trading Information by Stock": "title",
+		"csv.Stock code":                      "label",
+		"csv.Year":                            `["Year","Number of thousand shares traded","Amount (NTD, in thousands)","Highest price"]`,
+		"csv.Year[]":                          "array[3]|array[4]",
+		"csv.Highest price in recent years":   `["Highest price in recent years","Date","Lowest price in recent years"]`,
+		"csv.Highest price in recent years[]": "array[3]",
+	}, fp)
+}
+
+func TestFingerprintOf_HTML(t *testing.T) {
+	fp, err := drift.FingerprintOf([]byte("<html></html>"))
+	assert.Nil(t, err)
+	assert.Equal(t, drift.Fingerprint{"format": "html"}, fp)
+}
+
+// TestFingerprintOf_JSONAsHTML checks that the format is told by the body, since the TPEx serves JSON as
+// text/html.
+func TestFingerprintOf_JSONAsHTML(t *testing.T) {
+	fp, err := drift.FingerprintOf([]byte(" {\"stat\":\"OK\"}"))
+	assert.Nil(t, err)
+	assert.Equal(t, drift.Fingerprint{"format": "json", "$": "object", "$.stat": "string"}, fp)
+}
+
+func TestDiff(t *testing.T) {
+	old := drift.Fingerprint{"$.stat": "string", "$.data[]": "array[9]", "$.notes": "array"}
+	new := drift.Fingerprint{"$.stat": "string", "$.data[]": "array[10]", "$.hints": "array"}
+
+	changes := drift.Diff(old, new)
+	assert.Equal(t, []drift.Change{
+		{Kind: drift.Modified, Path: "$.data[]", Old: "array[9]", New: "array[10]"},
+		{Kind: drift.Added, Path: "$.hints", New: "array"},
+		{Kind: drift.Removed, Path: "$.notes", Old: "array"},
+	}, changes)
+	assert.Equal(t, "~ $.data[]: array[9] -> array[10]", changes[0].String())
+	assert.Equal(t, "+ $.hints: array", changes[1].String())
+	assert.Equal(t, "- $.notes: array", changes[2].String())
+
+	assert.Nil(t, drift.Diff(old, old))
+}
@@ -0,0 +1,69 @@
+// Package exchangeerr defines the errors shared by the twse and tpex clients, so that the errors of both
+// can be checked by errors.Is without depending on either package:
+//
+//     if errors.Is(err, exchangeerr.ErrQuotaExceeded) {
+//         // Banned by the TWSE or the TPEx; wait for about an hour.
+//     }
+//
+// The error types of each package, e.g. twse.QuotaExceededError and tpex.QuotaExceededError, match the
+// corresponding sentinels below, and carry the details with errors.As.
+package exchangeerr
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrConnection is matched when the exchange cannot be connected, or the connection fails midway.
+	ErrConnection = errors.New("failed to connect to the exchange")
+	// ErrQuotaExceeded is matched when the exchange blocks clients querying too frequently.
+	ErrQuotaExceeded = errors.New("quota of the exchange exceeded")
+	// ErrUnexpectedContent is matched when the exchange responds with unexpected status codes or content,
+	// e.g. HTML pages instead of JSON.
+	ErrUnexpectedContent = errors.New("unexpected content from the exchange")
+	// ErrMaintenance is matched, in addition to ErrUnexpectedContent, when the exchange shows a
+	// maintenance page.
+	ErrMaintenance = errors.New("exchange under maintenance")
+	// ErrNoData is matched when nothing matches the query. The clients only return it in strict mode,
+	// with a NoDataReason; otherwise, they return empty results.
+	ErrNoData = errors.New("no data matched")
+	// ErrParse is matched when the response cannot be parsed, which is likely due to an API change.
+	ErrParse = errors.New("failed to parse the response")
+	// ErrSchemaMismatch is matched, in addition to ErrParse, when the layout of the response differs from
+	// the known one, e.g. a column is inserted.
+	ErrSchemaMismatch = errors.New("schema of the response mismatched")
+)
+
+// NoDataReason tells why nothing matches a query.
+type NoDataReason int
+
+const (
+	// ReasonUndetermined is for the cases not told apart, e.g. stocks suspended or delisted.
+	ReasonUndetermined NoDataReason = iota
+	// ReasonUnknownCode is for codes of no quotes at all.
+	ReasonUnknownCode
+	// ReasonBeforeListing is for periods before the stock was listed.
+	ReasonBeforeListing
+	// ReasonNonTradingDay is for weekends and holidays.
+	ReasonNonTradingDay
+	// ReasonNotYetPublished is for future dates, and today before the exchange publishes the quotes.
+	ReasonNotYetPublished
+)
+
+func (r NoDataReason) String() string {
+	switch r {
+	case ReasonUndetermined:
+		return "undetermined"
+	case ReasonUnknownCode:
+		return "unknown code"
+	case ReasonBeforeListing:
+		return "before listing"
+	case ReasonNonTradingDay:
+		return "non-trading day"
+	case ReasonNotYetPublished:
+		return "not yet published"
+	default:
+		return fmt.Sprintf("NoDataReason(%d)", int(r))
+	}
+}
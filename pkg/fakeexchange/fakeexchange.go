@@ -0,0 +1,383 @@
+// Package fakeexchange provides an HTTP server mimicking the endpoints of the TWSE and the TPEx, so that
+// code built on the twse and tpex clients can be tested offline.
+//
+// The server answers the paths and query parameters the clients use with quotes added to it, or with
+// canned responses, and can simulate failures of the exchanges:
+//
+//     s := fakeexchange.NewServer()
+//     defer s.Close()
+//
+//     s.AddDayQuotes(fakeexchange.MarketTWSE, quote.Day{Code: "2330", Date: calendar.Date(2021, 3, 24), ...})
+//     client := &twse.Client{HttpClient: s.HttpClient()}
+//     qs, _ := client.FetchDayQuotes(calendar.Date(2021, 3, 24))
+//
+//     s.SetFault("", fakeexchange.FaultBan)
+//     _, err := client.FetchDayQuotes(calendar.Date(2021, 3, 24)) // *twse.QuotaExceededError
+//
+// HttpClient redirects the requests to the exchanges to the server, so the clients need no changes.
+// Alternatively, point BaseURL of the clients to the server:
+//
+//     client := &tpex.Client{HttpClient: s.Client(), BaseURL: s.URL}
+package fakeexchange
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// Markets served by Server.
+const (
+	MarketTWSE = "twse"
+	MarketTPEx = "tpex"
+)
+
+// Fault is a failure of an exchange simulated by Server.
+type Fault int
+
+const (
+	// FaultNone serves the requests normally.
+	FaultNone Fault = iota
+	// FaultBan serves the HTML page the TWSE shows to clients querying too frequently.
+	FaultBan
+	// FaultEmptyReply closes the connection without replying, which the TWSE also does to banned clients.
+	FaultEmptyReply
+	// FaultHTMLError serves an HTML error page with 503 Service Unavailable, e.g. during maintenance.
+	FaultHTMLError
+	// FaultMalformedJSON serves truncated JSON as application/json.
+	FaultMalformedJSON
+)
+
+// Response is a canned response served by Server.
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// ResponseFromGzipFile returns a Response with the content of a gzipped file, e.g. the fixtures in
+// testdata of the twse and tpex packages.
+func ResponseFromGzipFile(filepath string, contentType string) (Response, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return Response{}, err
+	}
+	defer f.Close()
+
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to create GZIP reader: %w", err)
+	}
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to read %s: %w", filepath, err)
+	}
+
+	return Response{StatusCode: http.StatusOK, ContentType: contentType, Body: body}, nil
+}
+
+// Request is a request received by Server.
+type Request struct {
+	Time   time.Time
+	Method string
+	Path   string
+	Header http.Header
+	// Params are the query parameters merged with the form values.
+	Params url.Values
+}
+
+type fixture struct {
+	path     string
+	params   url.Values
+	response Response
+}
+
+type dayKey struct {
+	market string
+	date   string
+}
+
+// Server is an httptest.Server mimicking the TWSE and the TPEx. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	days     map[dayKey]map[string]quote.Day
+	monthly  map[string][]quote.Monthly
+	yearly   map[string][]quote.Yearly
+	fixtures []fixture
+	faults   map[string]Fault
+	requests []Request
+}
+
+// NewServer starts and returns a new Server without any quotes. Call Close when finished.
+func NewServer() *Server {
+	s := &Server{
+		days:    map[dayKey]map[string]quote.Day{},
+		monthly: map[string][]quote.Monthly{},
+		yearly:  map[string][]quote.Yearly{},
+		faults:  map[string]Fault{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// AddDayQuotes adds quotes served by both the day and the daily quotes endpoints of the market. A quote
+// replaces the one of the same code and date.
+func (s *Server) AddDayQuotes(market string, qs ...quote.Day) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, q := range qs {
+		key := dayKey{market, q.Date.Format("20060102")}
+		if s.days[key] == nil {
+			s.days[key] = map[string]quote.Day{}
+		}
+		s.days[key][q.Code] = q
+	}
+}
+
+// AddMonthlyQuotes adds quotes served by the monthly quotes endpoint of the market.
+func (s *Server) AddMonthlyQuotes(market string, qs ...quote.Monthly) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, q := range qs {
+		key := market + "/" + q.Code
+		s.monthly[key] = append(s.monthly[key], q)
+	}
+}
+
+// AddYearlyQuotes adds quotes served by the yearly quotes endpoint of the market.
+func (s *Server) AddYearlyQuotes(market string, qs ...quote.Yearly) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, q := range qs {
+		key := market + "/" + q.Code
+		s.yearly[key] = append(s.yearly[key], q)
+	}
+}
+
+// Handle serves resp to the requests of the path whose parameters contain params, in preference to the
+// quotes added. The latest matching response wins.
+//
+//     s.Handle("/exchangeReport/MI_INDEX", url.Values{"date": {"20210324"}}, resp)
+func (s *Server) Handle(path string, params url.Values, resp Response) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.fixtures = append(s.fixtures, fixture{path, params, resp})
+}
+
+// SetFault makes the requests of the path fail with f until it is set to FaultNone. An empty path
+// applies to all the paths without their own faults.
+func (s *Server) SetFault(path string, f Fault) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if f == FaultNone {
+		delete(s.faults, path)
+	} else {
+		s.faults[path] = f
+	}
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// HttpClient returns a client for twse.Client and tpex.Client, which sends the requests to the exchanges
+// to s instead.
+func (s *Server) HttpClient() tkthttp.Client {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		panic(err)
+	}
+
+	return &redirectingClient{target: u, client: s.Client()}
+}
+
+type redirectingClient struct {
+	target *url.URL
+	client *http.Client
+}
+
+func (c *redirectingClient) Do(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Host = req.URL.Host
+	req.URL.Scheme = c.target.Scheme
+	req.URL.Host = c.target.Host
+	return c.client.Do(req)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	s.requests = append(s.requests, Request{
+		Time:   time.Now(),
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Header: r.Header,
+		Params: r.Form,
+	})
+	fault, ok := s.faults[r.URL.Path]
+	if !ok {
+		fault = s.faults[""]
+	}
+	resp, ok := s.match(r.URL.Path, r.Form)
+	s.mutex.Unlock()
+
+	switch {
+	case fault != FaultNone:
+		serveFault(w, fault)
+	case ok:
+		write(w, resp)
+	case strings.HasPrefix(r.URL.Path, "/web/"):
+		s.serveTPEx(w, r)
+	default:
+		s.serveTWSE(w, r)
+	}
+}
+
+func (s *Server) match(path string, params url.Values) (Response, bool) {
+	for i := len(s.fixtures) - 1; i >= 0; i-- {
+		f := s.fixtures[i]
+		if f.path == path && containsParams(params, f.params) {
+			return f.response, true
+		}
+	}
+
+	return Response{}, false
+}
+
+func containsParams(params, subset url.Values) bool {
+	for k := range subset {
+		if params.Get(k) != subset.Get(k) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func write(w http.ResponseWriter, resp Response) {
+	if resp.ContentType != "" {
+		w.Header().Set("Content-Type", resp.ContentType)
+	}
+	if resp.StatusCode == 0 {
+		resp.StatusCode = http.StatusOK
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(resp.Body)
+}
+
+const banPage = `<html>
+<head>
+<meta http-equiv="Content-Type" content="text/html; charset=utf-8">
+</head>
+<body>
+TWSE revised the official website on 23rd May, 2017. The URL address you were trying to reach seem to be
+incorrect or outdated. Please update your browser bookmark(s), thank you.<BR>
+</body>
+</html>
+`
+
+const errorPage = `<html>
+<head><title>503 Service Unavailable</title></head>
+<body>系統維護中，請稍後再試。 The system is under maintenance. Please try again later.</body>
+</html>
+`
+
+func serveFault(w http.ResponseWriter, f Fault) {
+	switch f {
+	case FaultBan:
+		write(w, Response{http.StatusOK, "text/html; charset=utf-8", []byte(banPage)})
+	case FaultEmptyReply:
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			panic("the response writer does not support hijacking")
+		}
+		conn, _, err := hijacker.Hijack()
+		if err != nil {
+			panic(err)
+		}
+		_ = conn.Close()
+	case FaultHTMLError:
+		write(w, Response{http.StatusServiceUnavailable, "text/html; charset=utf-8", []byte(errorPage)})
+	case FaultMalformedJSON:
+		write(w, Response{http.StatusOK, "application/json; charset=utf-8", []byte(`{"stat":"OK","data":[["`)})
+	default:
+		panic(fmt.Sprintf("unknown fault %d", f))
+	}
+}
+
+// dayQuotes returns the day quotes of the market on the date, or of the code in the month of the date if
+// code is not empty, in the order of codes or dates.
+func (s *Server) dayQuotes(market string, date time.Time, code string) []quote.Day {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	qs := make([]quote.Day, 0)
+	if code == "" {
+		for _, q := range s.days[dayKey{market, date.Format("20060102")}] {
+			qs = append(qs, q)
+		}
+		sort.Slice(qs, func(i, j int) bool { return qs[i].Code < qs[j].Code })
+		return qs
+	}
+
+	for key, m := range s.days {
+		if key.market != market || !strings.HasPrefix(key.date, date.Format("200601")) {
+			continue
+		}
+		if q, ok := m[code]; ok {
+			qs = append(qs, q)
+		}
+	}
+	sort.Slice(qs, func(i, j int) bool { return qs[i].Date.Before(qs[j].Date) })
+	return qs
+}
+
+func (s *Server) monthlyQuotes(market, code string, year int) []quote.Monthly {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	qs := make([]quote.Monthly, 0)
+	for _, q := range s.monthly[market+"/"+code] {
+		if q.Year == year {
+			qs = append(qs, q)
+		}
+	}
+	sort.Slice(qs, func(i, j int) bool { return qs[i].Month < qs[j].Month })
+	return qs
+}
+
+func (s *Server) yearlyQuotes(market, code string) []quote.Yearly {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	qs := append([]quote.Yearly{}, s.yearly[market+"/"+code]...)
+	sort.Slice(qs, func(i, j int) bool { return qs[i].Year < qs[j].Year })
+	return qs
+}
@@ -0,0 +1,224 @@
+package fakeexchange_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+	"github.com/chehsunliu/tshakutshai/pkg/fakeexchange"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+func dayQuote(code, name string, date time.Time, open, high, low, close string) quote.Day {
+	return quote.Day{
+		Code:         code,
+		Name:         name,
+		Date:         date,
+		Open:         price.MustParse(open),
+		High:         price.MustParse(high),
+		Low:          price.MustParse(low),
+		Close:        price.MustParse(close),
+		Volume:       70_161_000,
+		Transactions: 81_346,
+		Value:        42_004_241_000,
+	}
+}
+
+func TestServer_TWSE(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	q1 := dayQuote("2330", "台積電", calendar.Date(2021, 2, 1), "595.00", "612.00", "587.00", "611.00")
+	q2 := dayQuote("2330", "台積電", calendar.Date(2021, 2, 2), "629.00", "638.00", "622.00", "632.00")
+	q3 := dayQuote("0050", "元大台灣50", calendar.Date(2021, 2, 1), "131.80", "132.45", "131.30", "131.50")
+	q4 := quote.Day{Code: "9958", Name: "世紀鋼", Date: calendar.Date(2021, 2, 1)}
+	s.AddDayQuotes(fakeexchange.MarketTWSE, q1, q2, q3, q4)
+
+	m := quote.Monthly{Code: "2454", Year: 2020, Month: time.January, High: price.MustParse("446.00"),
+		Low: price.MustParse("382.00"), Volume: 180_078_596, Transactions: 122_991, Value: 75_206_245_078}
+	s.AddMonthlyQuotes(fakeexchange.MarketTWSE, m)
+
+	y := quote.Yearly{Code: "0050", Year: 2003, High: price.MustParse("49.00"), Low: price.MustParse("36.92"),
+		DateOfHigh: calendar.Date(2003, 10, 30), DateOfLow: calendar.Date(2003, 6, 30), Volume: 779_721_000,
+		Transactions: 87_359, Value: 34_119_437_990}
+	s.AddYearlyQuotes(fakeexchange.MarketTWSE, y)
+
+	client := &twse.Client{HttpClient: s.HttpClient()}
+
+	dayQuotes, err := client.FetchDayQuotes(calendar.Date(2021, 2, 1))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]quote.Day{"2330": q1, "0050": q3, "9958": q4}, dayQuotes)
+
+	dayQuotes, err = client.FetchDayQuotes(calendar.Date(2021, 2, 3))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]quote.Day{}, dayQuotes)
+
+	// The TWSE leaves the names empty in daily quotes.
+	q1.Name, q2.Name = "", ""
+	dailyQuotes, err := client.FetchDailyQuotes("2330", 2021, time.February)
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Day{q1, q2}, dailyQuotes)
+
+	monthlyQuotes, err := client.FetchMonthlyQuotes("2454", 2020)
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Monthly{m}, monthlyQuotes)
+
+	yearlyQuotes, err := client.FetchYearlyQuotes("0050")
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Yearly{y}, yearlyQuotes)
+
+	requests := s.Requests()
+	assert.Equal(t, 5, len(requests))
+	assert.Equal(t, "/exchangeReport/STOCK_DAY", requests[2].Path)
+	assert.Equal(t, "2330", requests[2].Params.Get("stockNo"))
+}
+
+func TestServer_TPEx(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	q1 := dayQuote("8044", "網家", calendar.Date(2021, 2, 1), "86.60", "87.30", "86.00", "86.10")
+	q2 := quote.Day{Code: "8044", Name: "網家", Date: calendar.Date(2021, 2, 2)}
+	s.AddDayQuotes(fakeexchange.MarketTPEx, q1, q2)
+
+	m := quote.Monthly{Code: "8044", Year: 2020, Month: time.January, High: price.MustParse("96.40"),
+		Low: price.MustParse("88.70"), Volume: 6_092_000, Transactions: 5_274, Value: 564_646_000}
+	s.AddMonthlyQuotes(fakeexchange.MarketTPEx, m)
+
+	y1 := quote.Yearly{Code: "8044", Year: 2020, High: price.MustParse("147.00"), Low: price.MustParse("64.10"),
+		DateOfHigh: calendar.Date(2020, 7, 8), DateOfLow: calendar.Date(2020, 3, 19), Volume: 392_843_000,
+		Transactions: 305_000, Value: 40_810_347_000}
+	y2 := y1
+	y2.Year, y2.DateOfHigh, y2.DateOfLow = 2021, calendar.Date(2021, 3, 22), calendar.Date(2021, 3, 5)
+	s.AddYearlyQuotes(fakeexchange.MarketTPEx, y1, y2)
+
+	client := &tpex.Client{HttpClient: s.HttpClient()}
+
+	dayQuotes, err := client.FetchDayQuotes(calendar.Date(2021, 2, 1))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]quote.Day{"8044": q1}, dayQuotes)
+
+	dailyQuotes, err := client.FetchDailyQuotes("8044", 2021, time.February)
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Day{q1, q2}, dailyQuotes)
+
+	monthlyQuotes, err := client.FetchMonthlyQuotes("8044", 2020)
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Monthly{m}, monthlyQuotes)
+
+	yearlyQuotes, err := client.FetchYearlyQuotes("8044")
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Yearly{y2, y1}, yearlyQuotes)
+
+	yearlyQuotes, err = client.FetchYearlyQuotes("28044")
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Yearly{}, yearlyQuotes)
+}
+
+func TestServer_Handle(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	resp, err := fakeexchange.ResponseFromGzipFile("../client/twse/testdata/quotes-tw-20210324.json.gz", "application/json")
+	assert.Nil(t, err)
+	s.Handle("/exchangeReport/MI_INDEX", url.Values{"date": {"20210324"}}, resp)
+
+	client := &twse.Client{HttpClient: s.HttpClient()}
+
+	qs, err := client.FetchDayQuotes(calendar.Date(2021, 3, 24))
+	assert.Nil(t, err)
+	assert.Equal(t, price.MustParse("576.00"), qs["2330"].Close)
+
+	qs, err = client.FetchDayQuotes(calendar.Date(2021, 3, 25))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(qs))
+
+	_, err = fakeexchange.ResponseFromGzipFile("./testdata/missing.json.gz", "application/json")
+	assert.NotNil(t, err)
+}
+
+func TestServer_SetFault(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	client := &twse.Client{HttpClient: s.HttpClient()}
+	date := calendar.Date(2021, 3, 24)
+
+	for _, tc := range []struct {
+		fault fakeexchange.Fault
+		err   error
+	}{
+		{fakeexchange.FaultBan, exchangeerr.ErrQuotaExceeded},
+		{fakeexchange.FaultEmptyReply, exchangeerr.ErrQuotaExceeded},
+		{fakeexchange.FaultHTMLError, exchangeerr.ErrMaintenance},
+		{fakeexchange.FaultMalformedJSON, exchangeerr.ErrParse},
+	} {
+		s.SetFault("", tc.fault)
+		_, err := client.FetchDayQuotes(date)
+		assert.Truef(t, errors.Is(err, tc.err), "fault %d: %v", tc.fault, err)
+	}
+
+	// Faults of paths take precedence over the one of all paths.
+	s.SetFault("", fakeexchange.FaultNone)
+	s.SetFault("/exchangeReport/FMNPTK", fakeexchange.FaultBan)
+	_, err := client.FetchDayQuotes(date)
+	assert.Nil(t, err)
+	_, err = client.FetchYearlyQuotes("0050")
+	assert.NotNil(t, err)
+
+	s.SetFault("/exchangeReport/FMNPTK", fakeexchange.FaultNone)
+	_, err = client.FetchYearlyQuotes("0050")
+	assert.Nil(t, err)
+
+	tpexClient := &tpex.Client{HttpClient: s.HttpClient()}
+	for _, tc := range []struct {
+		fault fakeexchange.Fault
+		err   error
+	}{
+		{fakeexchange.FaultBan, exchangeerr.ErrUnexpectedContent},
+		{fakeexchange.FaultEmptyReply, exchangeerr.ErrConnection},
+		{fakeexchange.FaultHTMLError, exchangeerr.ErrMaintenance},
+		{fakeexchange.FaultMalformedJSON, exchangeerr.ErrParse},
+	} {
+		s.SetFault("", tc.fault)
+		_, err = tpexClient.FetchDailyQuotes("8044", 2021, time.February)
+		assert.Truef(t, errors.Is(err, tc.err), "fault %d: %v", tc.fault, err)
+	}
+}
+
+func TestServer_BaseURL(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	q := dayQuote("8044", "網家", calendar.Date(2021, 2, 1), "86.60", "87.30", "86.00", "86.10")
+	s.AddDayQuotes(fakeexchange.MarketTPEx, q)
+
+	client := &tpex.Client{HttpClient: s.Client(), BaseURL: s.URL}
+	qs, err := client.FetchDayQuotes(calendar.Date(2021, 2, 1))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]quote.Day{"8044": q}, qs)
+}
+
+func TestServer_HttpClient(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	req, err := http.NewRequest("GET", "https://www.twse.com.tw/exchangeReport/FMNPTK?response=json&stockNo=0050", nil)
+	assert.Nil(t, err)
+
+	resp, err := s.HttpClient().Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "https", req.URL.Scheme, "the request must not be modified")
+}
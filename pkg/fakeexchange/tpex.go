@@ -0,0 +1,148 @@
+package fakeexchange
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// serveTPEx serves the TPEx endpoints. The TPEx reports volumes and values in thousands in daily, monthly
+// and yearly quotes, so the remainders of the quotes added are dropped as the TPEx does.
+func (s *Server) serveTPEx(w http.ResponseWriter, r *http.Request) {
+	// The English day and daily quotes give the English names instead.
+	name := func(q quote.Day) string { return q.Name }
+	if r.Form.Get("l") == "en-us" {
+		name = func(q quote.Day) string { return q.EnglishName }
+	}
+
+	code := r.Form.Get("stkno")
+	if code == "" {
+		code = r.Form.Get("stk_no")
+	}
+
+	var date time.Time
+	if rawDate := r.Form.Get("d"); rawDate != "" {
+		var err error
+		if date, err = parseROCDate(rawDate); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch r.URL.Path {
+	case "/web/stock/aftertrading/daily_close_quotes/stk_quote_result.php":
+		data := make([][]string, 0)
+		for _, q := range s.dayQuotes(MarketTPEx, date, "") {
+			data = append(data, []string{
+				q.Code, name(q), tpexPrice(q.Close), "0.00", tpexPrice(q.Open), tpexPrice(q.High), tpexPrice(q.Low),
+				tpexPrice(q.Close), formatUint(q.Volume), formatUint(q.Value), formatUint(q.Transactions),
+				"", "", "", "", "", "", "", "",
+			})
+		}
+		writeJSON(w, map[string]interface{}{
+			"reportDate":    formatROCDate(date),
+			"iTotalRecords": len(data),
+			"mmData":        []string{},
+			"aaData":        data,
+		})
+	case "/web/stock/aftertrading/daily_trading_info/st43_result.php":
+		stkName := ""
+		data := make([][]string, 0)
+		for _, q := range s.dayQuotes(MarketTPEx, date, code) {
+			if name(q) != "" {
+				stkName = name(q)
+			}
+			data = append(data, []string{
+				formatROCDate(q.Date), formatUint(q.Volume / 1000), formatUint(q.Value / 1000),
+				tpexPrice(q.Open), tpexPrice(q.High), tpexPrice(q.Low), tpexPrice(q.Close),
+				"0.00", formatUint(q.Transactions),
+			})
+		}
+		writeJSON(w, map[string]interface{}{
+			"stkNo":         code,
+			"stkName":       stkName,
+			"reportDate":    fmt.Sprintf("%d/%s", date.Year()-1911, date.Format("01")),
+			"iTotalRecords": len(data),
+			"aaData":        data,
+		})
+	case "/web/stock/statistics/monthly/download_st44.php":
+		year, _ := strconv.Atoi(r.Form.Get("yy"))
+
+		var b strings.Builder
+		b.WriteString("Monthly Trading Value/Volume of Individual Securities\n")
+		fmt.Fprintf(&b, "Stock code,%s\nDate,%d\n", code, year)
+		b.WriteString(`Year,Month,Highest price,Lowest price,Average closing price,Number of transactions,` +
+			`"Trading Value (NTD, in thousands)" (A),Number shares (in thousands) (B),Turnover ratio (%)` + "\n")
+		for _, q := range s.monthlyQuotes(MarketTPEx, code, year) {
+			writeCSVLine(&b, strconv.Itoa(q.Year), strconv.Itoa(int(q.Month)), q.High.String(), q.Low.String(),
+				"0.00", formatUint(q.Transactions), formatUint(q.Value/1000), formatUint(q.Volume/1000), "0.00")
+		}
+		write(w, Response{http.StatusOK, "text/csv; charset=utf-8", []byte(b.String())})
+	case "/web/stock/statistics/monthly/download_st42.php":
+		var b strings.Builder
+		b.WriteString("Recent Trading Information by Stock\n")
+		fmt.Fprintf(&b, "Stock code,%s\n", code)
+		b.WriteString(`Year,Number of thousand shares traded ,"Amount (NTD, in thousands)" ,` +
+			`Number of transactions (in thousands) ,Highest price ,Date ,Lowest price ,Date ,Average price` + "\n")
+		qs := s.yearlyQuotes(MarketTPEx, code)
+		// The TPEx lists the latest year first.
+		for i := len(qs) - 1; i >= 0; i-- {
+			q := qs[i]
+			writeCSVLine(&b, strconv.Itoa(q.Year), formatUint(q.Volume/1000), formatUint(q.Value/1000),
+				formatUint(q.Transactions/1000), q.High.String(), q.DateOfHigh.Format("01/02"), q.Low.String(),
+				q.DateOfLow.Format("01/02"), "0.00")
+		}
+		b.WriteString("\n\nHighest price in recent years ,Date ,Lowest price in recent years ,Date\n")
+		write(w, Response{http.StatusOK, "text/csv; charset=utf-8", []byte(b.String())})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeCSVLine(b *strings.Builder, values ...string) {
+	for i, v := range values {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(b, `"%s"`, v)
+	}
+	b.WriteString("\n")
+}
+
+// tpexPrice formats p as the TPEx does, which denotes prices of no transactions as "---".
+func tpexPrice(p price.Price) string {
+	if p.IsZero() {
+		return "---"
+	}
+	return p.String()
+}
+
+// parseROCDate parses dates in the ROC calendar like 110/03/30.
+func parseROCDate(s string) (time.Time, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return time.Time{}, fmt.Errorf("'%s' is not a date like 110/03/30", s)
+	}
+
+	year, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is not a date like 110/03/30", s)
+	}
+
+	t, err := time.Parse("01/02", parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is not a date like 110/03/30", s)
+	}
+
+	return calendar.Date(year+1911, t.Month(), t.Day()), nil
+}
+
+func formatROCDate(date time.Time) string {
+	return fmt.Sprintf("%d/%s", date.Year()-1911, date.Format("01/02"))
+}
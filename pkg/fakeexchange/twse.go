@@ -0,0 +1,145 @@
+package fakeexchange
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+)
+
+// twseNoData is the stat the TWSE replies if nothing matches the query.
+const twseNoData = "很抱歉，沒有符合條件的資料!"
+
+var (
+	twseDayFields     = []string{"證券代號", "證券名稱", "成交股數", "成交筆數", "成交金額", "開盤價", "最高價", "最低價", "收盤價", "漲跌(+/-)", "漲跌價差", "最後揭示買價", "最後揭示買量", "最後揭示賣價", "最後揭示賣量", "本益比"}
+	twseDailyFields   = []string{"日期", "成交股數", "成交金額", "開盤價", "最高價", "最低價", "收盤價", "漲跌價差", "成交筆數"}
+	twseMonthlyFields = []string{"年度", "月份", "最高價", "最低價", "加權(A/B)平均價", "成交筆數", "成交金額(A)", "成交股數(B)", "週轉率(%)"}
+	twseYearlyFields  = []string{"年度", "成交股數", "成交金額", "成交筆數", "最高價", "日期", "最低價", "日期", "收盤平均價"}
+
+	// The English responses give no stock names in day quotes, and years in the Gregorian calendar.
+	twseEnglishDayFields     = []string{"Security Code", "Trade Volume", "Transaction", "Trade Value", "Opening Price", "Highest Price", "Lowest Price", "Closing Price", "Dir(+/-)", "Change", "Last Best Bid Price", "Last Best Bid Volume", "Last Best Ask Price", "Last Best Ask Volume", "Price-Earning ratio"}
+	twseEnglishDailyFields   = []string{"Date", "Trade Volume", "Trade Value", "Opening Price", "Highest Price", "Lowest Price", "Closing Price", "Change", "Transaction"}
+	twseEnglishMonthlyFields = []string{"Year", "Month", "Highest Price", "Lowest Price", "Weighted Avg. Price (A/B)", "Transaction", "Trade Value (A)", "Trade Volume (B)", "Turnover Ratio (%)"}
+	twseEnglishYearlyFields  = []string{"Year", "Trade Volume", "Trade Value", "Transaction", "Highest Price", "Date", "Lowest Price", "Date", "Avg. Closing Price"}
+)
+
+func (s *Server) serveTWSE(w http.ResponseWriter, r *http.Request) {
+	code := r.Form.Get("stockNo")
+	english := r.Form.Get("lang") == "en"
+
+	var date time.Time
+	if rawDate := r.Form.Get("date"); rawDate != "" {
+		t, err := time.Parse("20060102", rawDate)
+		if err != nil {
+			writeJSON(w, map[string]interface{}{"stat": "查詢日期有誤"})
+			return
+		}
+		date = calendar.Date(t.Year(), t.Month(), t.Day())
+	}
+
+	switch r.URL.Path {
+	case "/exchangeReport/MI_INDEX":
+		fields, data := twseDayFields, make([][]interface{}, 0)
+		if english {
+			fields = twseEnglishDayFields
+		}
+		for _, q := range s.dayQuotes(MarketTWSE, date, "") {
+			item := []interface{}{
+				q.Code, q.Name, formatUint(q.Volume), formatUint(q.Transactions), formatUint(q.Value),
+				twsePrice(q.Open), twsePrice(q.High), twsePrice(q.Low), twsePrice(q.Close),
+				"<p> </p>", "0.00", "", "", "", "", "0.00",
+			}
+			if english {
+				item = append(item[:1], item[2:]...)
+			}
+			data = append(data, item)
+		}
+		writeTWSE(w, date, map[string]interface{}{"fields9": fields, "data9": data})
+	case "/exchangeReport/STOCK_DAY":
+		fields, data := twseDailyFields, make([][]interface{}, 0)
+		formatDate := formatROCDate
+		if english {
+			fields, formatDate = twseEnglishDailyFields, func(t time.Time) string { return t.Format("2006/01/02") }
+		}
+		for _, q := range s.dayQuotes(MarketTWSE, date, code) {
+			data = append(data, []interface{}{
+				formatDate(q.Date), formatUint(q.Volume), formatUint(q.Value),
+				twsePrice(q.Open), twsePrice(q.High), twsePrice(q.Low), twsePrice(q.Close),
+				"0.00", formatUint(q.Transactions),
+			})
+		}
+		writeTWSE(w, date, map[string]interface{}{"fields": fields, "data": data})
+	case "/exchangeReport/FMSRFK":
+		fields, data, yearOffset := twseMonthlyFields, make([][]interface{}, 0), 1911
+		if english {
+			fields, yearOffset = twseEnglishMonthlyFields, 0
+		}
+		for _, q := range s.monthlyQuotes(MarketTWSE, code, date.Year()) {
+			data = append(data, []interface{}{
+				q.Year - yearOffset, int(q.Month), twsePrice(q.High), twsePrice(q.Low), "0.00",
+				formatUint(q.Transactions), formatUint(q.Value), formatUint(q.Volume), "0.00",
+			})
+		}
+		writeTWSE(w, date, map[string]interface{}{"fields": fields, "data": data})
+	case "/exchangeReport/FMNPTK":
+		fields, data, yearOffset := twseYearlyFields, make([][]interface{}, 0), 1911
+		if english {
+			fields, yearOffset = twseEnglishYearlyFields, 0
+		}
+		for _, q := range s.yearlyQuotes(MarketTWSE, code) {
+			data = append(data, []interface{}{
+				q.Year - yearOffset, formatUint(q.Volume), formatUint(q.Value), formatUint(q.Transactions),
+				twsePrice(q.High), q.DateOfHigh.Format("1/02"), twsePrice(q.Low), q.DateOfLow.Format("1/02"), "0.00",
+			})
+		}
+		writeTWSE(w, date, map[string]interface{}{"fields": fields, "data": data})
+	default:
+		// Trades and holidays are only served by Handle.
+		writeJSON(w, map[string]interface{}{"stat": twseNoData})
+	}
+}
+
+// writeTWSE replies the fields and the data in rawData, or the no-data stat if the data are empty as the
+// TWSE does.
+func writeTWSE(w http.ResponseWriter, date time.Time, rawData map[string]interface{}) {
+	for _, v := range rawData {
+		if data, ok := v.([][]interface{}); ok && len(data) == 0 {
+			writeJSON(w, map[string]interface{}{"stat": twseNoData})
+			return
+		}
+	}
+
+	rawData["stat"] = "OK"
+	if !date.IsZero() {
+		rawData["date"] = date.Format("20060102")
+	}
+	writeJSON(w, rawData)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	write(w, Response{http.StatusOK, "application/json; charset=utf-8", body})
+}
+
+// twsePrice formats p as the TWSE does, which denotes prices of no transactions as "--".
+func twsePrice(p price.Price) string {
+	if p.IsZero() {
+		return "--"
+	}
+	return p.String()
+}
+
+// formatUint formats v with thousands separators, e.g. 1,234,567.
+func formatUint(v uint64) string {
+	s := strconv.FormatUint(v, 10)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
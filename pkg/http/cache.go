@@ -0,0 +1,178 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"time"
+)
+
+// Cache stores serialized responses by keys.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// MemoryCache is a Cache in memory, which is safe for concurrent use. It evicts the least recently used
+// entries beyond its size.
+type MemoryCache struct {
+	mutex sync.Mutex
+	ttl   time.Duration
+	size  int
+	// lru holds *memoryCacheEntry, the most recently used at the front.
+	lru     *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+type memoryCacheEntry struct {
+	key   string
+	value []byte
+	// expires is zero if the entry never expires.
+	expires time.Time
+}
+
+// NewMemoryCache returns a MemoryCache whose entries expire after ttl, or never if ttl is zero, and which
+// keeps at most size entries, or is unbounded if size is not positive. Note that quotes of the current day
+// or month change until the market closes.
+func NewMemoryCache(ttl time.Duration, size int) *MemoryCache {
+	return &MemoryCache{ttl: ttl, size: size, lru: list.New(), entries: map[string]*list.Element{}, now: time.Now}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*memoryCacheEntry)
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *MemoryCache) Set(key string, value []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry := &memoryCacheEntry{key: key, value: value}
+	if c.ttl > 0 {
+		entry.expires = c.now().Add(c.ttl)
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.size > 0 && c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// CachingClient serves responses from its Cache, and caches successful responses. Responses other than
+// 200 OK, HTML pages, which the exchanges show on errors and bans, and JSON replies of no data are never
+// cached, since the exchanges reply no data now and then for days which do have quotes, and for the
+// current day before the market closes.
+type CachingClient struct {
+	client Client
+	cache  Cache
+}
+
+func NewCachingClient(client Client, cache Cache) *CachingClient {
+	return &CachingClient{client: client, cache: cache}
+}
+
+func (c *CachingClient) Do(req *http.Request) (*http.Response, error) {
+	key, err := cacheKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := c.cache.Get(key); ok {
+		if resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(value)), req); err == nil {
+			return resp, nil
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil || !cacheable(resp) {
+		return resp, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if isNoData(body) {
+		return resp, nil
+	}
+
+	value, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.Set(key, value)
+
+	return resp, nil
+}
+
+// cacheKey returns the method, the URL and the body of req, restoring the body for sending.
+func cacheKey(req *http.Request) (string, error) {
+	key := req.Method + " " + req.URL.String()
+	if req.Body == nil {
+		return key, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	_ = req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return key + "\n" + string(body), nil
+}
+
+func cacheable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return contentType != "text/html"
+}
+
+// isNoData tells the JSON replies of no data, i.e. a stat other than OK of the TWSE, or zero
+// iTotalRecords of the TPEx.
+func isNoData(body []byte) bool {
+	var reply struct {
+		Stat          *string `json:"stat"`
+		ITotalRecords *int    `json:"iTotalRecords"`
+	}
+	if err := json.Unmarshal(body, &reply); err != nil {
+		return false
+	}
+
+	return (reply.Stat != nil && *reply.Stat != "OK") || (reply.ITotalRecords != nil && *reply.ITotalRecords == 0)
+}
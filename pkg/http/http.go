@@ -26,6 +26,7 @@ func NewThrottledClient(client Client, minInterval time.Duration) *ThrottledClie
 }
 
 func (c *ThrottledClient) Do(req *http.Request) (*http.Response, error) {
+	t0 := time.Now()
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -34,6 +35,10 @@ func (c *ThrottledClient) Do(req *http.Request) (*http.Response, error) {
 		time.Sleep(c.minInterval - elapsed)
 	}
 
+	if o := ObserverFromContext(req.Context()); o != nil {
+		o.Observe(Event{Kind: EventThrottle, Method: req.Method, URL: req.URL.String(), Wait: time.Since(t0)})
+	}
+
 	resp, err := c.client.Do(req)
 	c.last = time.Now()
 	return resp, err
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Limiter decides when a request can be sent, e.g. *rate.Limiter of golang.org/x/time/rate.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// LimitedClient waits for its Limiter before sending each request.
+type LimitedClient struct {
+	client  Client
+	limiter Limiter
+}
+
+func NewLimitedClient(client Client, limiter Limiter) *LimitedClient {
+	return &LimitedClient{client: client, limiter: limiter}
+}
+
+func (c *LimitedClient) Do(req *http.Request) (*http.Response, error) {
+	if err := c.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	return c.client.Do(req)
+}
+
+// Logger is implemented by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// LoggingClient logs the method, the URL, the result and the elapsed time of each request.
+type LoggingClient struct {
+	client Client
+	logger Logger
+}
+
+func NewLoggingClient(client Client, logger Logger) *LoggingClient {
+	return &LoggingClient{client: client, logger: logger}
+}
+
+func (c *LoggingClient) Do(req *http.Request) (*http.Response, error) {
+	t0 := time.Now()
+	resp, err := c.client.Do(req)
+	elapsed := time.Since(t0).Round(time.Millisecond)
+
+	if err != nil {
+		c.logger.Printf("%s %s: %s (%s)", req.Method, req.URL, err, elapsed)
+	} else {
+		c.logger.Printf("%s %s: %s (%s)", req.Method, req.URL, resp.Status, elapsed)
+	}
+
+	return resp, err
+}
+
+// RequestFunc modifies an outgoing request, e.g. to set headers or cookies.
+type RequestFunc func(req *http.Request)
+
+// MutatingClient applies its RequestFuncs in order to a copy of each request before sending it.
+type MutatingClient struct {
+	client Client
+	funcs  []RequestFunc
+}
+
+func NewMutatingClient(client Client, funcs ...RequestFunc) *MutatingClient {
+	return &MutatingClient{client: client, funcs: funcs}
+}
+
+func (c *MutatingClient) Do(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for _, f := range c.funcs {
+		f(req)
+	}
+
+	return c.client.Do(req)
+}
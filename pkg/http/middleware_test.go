@@ -0,0 +1,275 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newResponse(statusCode int, contentType, body string) *http.Response {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+
+	return &http.Response{
+		Status:        http.StatusText(statusCode),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
+
+// peekBody reads the body of req without consuming it, since a mock can match a request more than once.
+func peekBody(req *http.Request) string {
+	body, _ := ioutil.ReadAll(req.Body)
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return string(body)
+}
+
+type countingLimiter struct {
+	waits int
+	err   error
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits++
+	return l.err
+}
+
+func TestLimitedClient_Do(t *testing.T) {
+	mockHttpClient := &MockHttpClient{}
+	mockHttpClient.On("Do", mock.Anything).Return(newResponse(200, "application/json", "{}"), nil)
+
+	limiter := &countingLimiter{}
+	client := NewLimitedClient(mockHttpClient, limiter)
+
+	req, _ := http.NewRequest("GET", "https://www.twse.com.tw/", nil)
+	_, err := client.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, limiter.waits)
+
+	limiter.err = context.Canceled
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, context.Canceled)
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
+}
+
+func TestLoggingClient_Do(t *testing.T) {
+	mockHttpClient := &MockHttpClient{}
+	mockHttpClient.On("Do", mock.Anything).Return(newResponse(200, "application/json", "{}"), nil).Once()
+	mockHttpClient.On("Do", mock.Anything).Return(nil, errors.New("connection refused")).Once()
+
+	var b bytes.Buffer
+	client := NewLoggingClient(mockHttpClient, log.New(&b, "", 0))
+
+	req, _ := http.NewRequest("GET", "https://www.twse.com.tw/exchangeReport/MI_INDEX", nil)
+	_, _ = client.Do(req)
+	_, _ = client.Do(req)
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], "GET https://www.twse.com.tw/exchangeReport/MI_INDEX: OK ("))
+	assert.True(t, strings.HasPrefix(lines[1], "GET https://www.twse.com.tw/exchangeReport/MI_INDEX: connection refused ("))
+}
+
+func TestMutatingClient_Do(t *testing.T) {
+	mockHttpClient := &MockHttpClient{}
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.Header.Get("User-Agent") == "tshakutshai" && req.Header.Get("X-Order") == "1,2"
+	})).Return(newResponse(200, "application/json", "{}"), nil)
+
+	client := NewMutatingClient(mockHttpClient,
+		func(req *http.Request) { req.Header.Set("User-Agent", "tshakutshai") },
+		func(req *http.Request) { req.Header.Set("X-Order", "1") },
+		func(req *http.Request) { req.Header.Set("X-Order", req.Header.Get("X-Order")+",2") },
+	)
+
+	req, _ := http.NewRequest("GET", "https://www.twse.com.tw/", nil)
+	_, err := client.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "", req.Header.Get("User-Agent"), "the original request must not be modified")
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
+}
+
+func TestRetryingClient_Do(t *testing.T) {
+	mockHttpClient := &MockHttpClient{}
+	mockHttpClient.On("Do", mock.Anything).Return(newResponse(503, "text/html", "maintenance"), nil).Twice()
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return peekBody(req) == "stk_no=8044"
+	})).Return(newResponse(200, "text/csv", "ok"), nil).Once()
+
+	client := NewRetryingClient(mockHttpClient, RetryPolicy{MaxAttempts: 3, Backoff: time.Second})
+	var sleeps []time.Duration
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+
+	req, _ := http.NewRequest("POST", "https://www.tpex.org.tw/", strings.NewReader("stk_no=8044"))
+	resp, err := client.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, []time.Duration{time.Second, time.Second * 2}, sleeps)
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 3)
+}
+
+func TestRetryingClient_DoWithoutRetrying(t *testing.T) {
+	mockHttpClient := &MockHttpClient{}
+	mockHttpClient.On("Do", mock.Anything).Return(nil, io.EOF)
+
+	client := NewRetryingClient(mockHttpClient, RetryPolicy{MaxAttempts: 3})
+	client.sleep = func(context.Context, time.Duration) error { return nil }
+
+	req, _ := http.NewRequest("GET", "https://www.twse.com.tw/", nil)
+	_, err := client.Do(req)
+	assert.ErrorIs(t, err, io.EOF)
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
+
+	client = NewRetryingClient(mockHttpClient, RetryPolicy{
+		MaxAttempts: 2,
+		Retryable:   func(resp *http.Response, err error) bool { return err != nil },
+	})
+	client.sleep = func(context.Context, time.Duration) error { return nil }
+
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, io.EOF)
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 3)
+}
+
+func TestRetryingClient_DoWithCanceledContext(t *testing.T) {
+	mockHttpClient := &MockHttpClient{}
+	mockHttpClient.On("Do", mock.Anything).Return(newResponse(503, "text/html", "maintenance"), nil)
+
+	client := NewRetryingClient(mockHttpClient, RetryPolicy{MaxAttempts: 3, Backoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://www.twse.com.tw/", nil)
+	_, err := client.Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 1)
+}
+
+func TestCachingClient_Do(t *testing.T) {
+	mockHttpClient := &MockHttpClient{}
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.URL.Query().Get("date") == "20210324"
+	})).Return(newResponse(200, "application/json; charset=utf-8", `{"stat":"OK"}`), nil).Once()
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.URL.Query().Get("date") == "20210328"
+	})).Return(newResponse(200, "text/html", "banned"), nil).Twice()
+
+	cache := NewMemoryCache(time.Minute, 0)
+	client := NewCachingClient(mockHttpClient, cache)
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "https://www.twse.com.tw/exchangeReport/MI_INDEX?date=20210324", nil)
+		resp, err := client.Do(req)
+		assert.Nil(t, err)
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, `{"stat":"OK"}`, string(body))
+		assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+	}
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "https://www.twse.com.tw/exchangeReport/MI_INDEX?date=20210328", nil)
+		_, err := client.Do(req)
+		assert.Nil(t, err)
+	}
+
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 3)
+
+	// Entries expire after the TTL.
+	cache.now = func() time.Time { return time.Now().Add(time.Hour) }
+	_, ok := cache.Get("GET https://www.twse.com.tw/exchangeReport/MI_INDEX?date=20210324")
+	assert.False(t, ok)
+}
+
+func TestMemoryCache_Size(t *testing.T) {
+	cache := NewMemoryCache(0, 2)
+	cache.Set("a", []byte("1"))
+	cache.Set("b", []byte("2"))
+
+	// Getting a makes b the least recently used.
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+	cache.Set("c", []byte("3"))
+
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	for key, expected := range map[string]string{"a": "1", "c": "3"} {
+		value, ok := cache.Get(key)
+		assert.True(t, ok)
+		assert.Equal(t, expected, string(value))
+	}
+
+	// Replacing an entry evicts nothing.
+	cache.Set("a", []byte("4"))
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "4", string(value))
+	_, ok = cache.Get("c")
+	assert.True(t, ok)
+}
+
+func TestCachingClient_DoWithNoData(t *testing.T) {
+	mockHttpClient := &MockHttpClient{}
+	for _, body := range []string{
+		`{"stat":"很抱歉，沒有符合條件的資料!"}`,
+		`{"stat":"很抱歉，沒有符合條件的資料!"}`,
+		`{"reportDate":"80/03/30","iTotalRecords":0,"aaData":[]}`,
+		`{"reportDate":"80/03/30","iTotalRecords":0,"aaData":[]}`,
+	} {
+		mockHttpClient.On("Do", mock.Anything).Return(newResponse(200, "application/json", body), nil).Once()
+	}
+
+	client := NewCachingClient(mockHttpClient, NewMemoryCache(0, 0))
+
+	for i := 0; i < 4; i++ {
+		req, _ := http.NewRequest("GET", "https://www.twse.com.tw/exchangeReport/MI_INDEX?date=20210330", nil)
+		resp, err := client.Do(req)
+		assert.Nil(t, err)
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Contains(t, string(body), "{")
+	}
+
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 4)
+}
+
+func TestCachingClient_DoWithBody(t *testing.T) {
+	mockHttpClient := &MockHttpClient{}
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return peekBody(req) == "stk_no=8044"
+	})).Return(newResponse(200, "text/csv", "8044"), nil).Once()
+	mockHttpClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return peekBody(req) == "stk_no=5483"
+	})).Return(newResponse(200, "text/csv", "5483"), nil).Once()
+
+	client := NewCachingClient(mockHttpClient, NewMemoryCache(0, 0))
+
+	for _, code := range []string{"8044", "5483", "8044", "5483"} {
+		req, _ := http.NewRequest("POST", "https://www.tpex.org.tw/", strings.NewReader("stk_no="+code))
+		resp, err := client.Do(req)
+		assert.Nil(t, err)
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, code, string(body))
+	}
+
+	mockHttpClient.AssertNumberOfCalls(t, "Do", 2)
+}
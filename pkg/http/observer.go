@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventKind is the kind of Event.
+type EventKind int
+
+const (
+	// EventRequest is reported before a request is sent.
+	EventRequest EventKind = iota
+	// EventThrottle is reported by ThrottledClient after waiting for the interval, with Wait.
+	EventThrottle
+	// EventResponse is reported when the response headers are received, with StatusCode and ContentType,
+	// or when the request fails, with Err.
+	EventResponse
+	// EventParse is reported after the response body is parsed, with Bytes and Err if parsing failed or
+	// nothing matched the query. Error pages, e.g. of bans and maintenance, are reported as parsed with
+	// the error they are told as.
+	EventParse
+	// EventEnd is reported last when the request is done, with Bytes and Err of its outcome, including
+	// failures to connect, which end the request without EventParse.
+	EventEnd
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventRequest:
+		return "request"
+	case EventThrottle:
+		return "throttle"
+	case EventResponse:
+		return "response"
+	case EventParse:
+		return "parse"
+	case EventEnd:
+		return "end"
+	default:
+		return fmt.Sprintf("EventKind(%d)", int(k))
+	}
+}
+
+// Event is an observation of a request to an exchange.
+type Event struct {
+	Kind   EventKind
+	Method string
+	URL    string
+	// Elapsed is the time since the request started, including throttling. It is not set in EventThrottle.
+	Elapsed time.Duration
+	// Wait is the time spent waiting for throttling.
+	Wait        time.Duration
+	StatusCode  int
+	ContentType string
+	// Bytes is the number of bytes read from the response body.
+	Bytes int64
+	Err   error
+}
+
+// Observer receives the events of the requests to the exchanges, e.g. to log or trace them. It must be
+// safe for concurrent use if the clients are used concurrently.
+type Observer interface {
+	Observe(e Event)
+}
+
+// ObserverFunc adapts a function to Observer.
+type ObserverFunc func(e Event)
+
+func (f ObserverFunc) Observe(e Event) {
+	f(e)
+}
+
+type observerKey struct{}
+
+// ContextWithObserver returns a copy of ctx carrying o, through which clients like ThrottledClient report
+// events of the request.
+func ContextWithObserver(ctx context.Context, o Observer) context.Context {
+	return context.WithValue(ctx, observerKey{}, o)
+}
+
+// ObserverFromContext returns the Observer carried by ctx, or nil.
+func ObserverFromContext(ctx context.Context) Observer {
+	o, _ := ctx.Value(observerKey{}).(Observer)
+	return o
+}
+
+// LogObserver logs each event as a line of key=value pairs:
+//
+//     event=response method=GET url=https://www.twse.com.tw/... elapsed=312ms status=200 content_type=application/json
+type LogObserver struct {
+	logger Logger
+}
+
+func NewLogObserver(logger Logger) *LogObserver {
+	return &LogObserver{logger: logger}
+}
+
+func (o *LogObserver) Observe(e Event) {
+	fields := []string{
+		"event=" + e.Kind.String(),
+		"method=" + e.Method,
+		"url=" + e.URL,
+		"elapsed=" + e.Elapsed.Round(time.Millisecond).String(),
+	}
+
+	switch e.Kind {
+	case EventThrottle:
+		fields = append(fields, "wait="+e.Wait.Round(time.Millisecond).String())
+	case EventResponse:
+		if e.Err == nil {
+			fields = append(fields, fmt.Sprintf("status=%d", e.StatusCode), "content_type="+e.ContentType)
+		}
+	case EventParse, EventEnd:
+		fields = append(fields, fmt.Sprintf("bytes=%d", e.Bytes))
+	}
+
+	if e.Err != nil {
+		fields = append(fields, fmt.Sprintf("error=%q", e.Err.Error()))
+	}
+
+	o.logger.Printf("%s", strings.Join(fields, " "))
+}
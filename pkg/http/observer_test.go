@@ -0,0 +1,57 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestThrottledClient_DoWithObserver(t *testing.T) {
+	mockHttpClient := &MockHttpClient{}
+	mockHttpClient.On("Do", mock.Anything).Return(nil, nil)
+
+	var events []Event
+	ctx := ContextWithObserver(context.Background(), ObserverFunc(func(e Event) { events = append(events, e) }))
+
+	client := NewThrottledClient(mockHttpClient, time.Millisecond*100)
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequestWithContext(ctx, "GET", "https://www.twse.com.tw/exchangeReport/MI_INDEX", nil)
+		_, _ = client.Do(req)
+	}
+
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, EventThrottle, events[1].Kind)
+	assert.Equal(t, "https://www.twse.com.tw/exchangeReport/MI_INDEX", events[1].URL)
+	assert.True(t, events[0].Wait < time.Millisecond*50)
+	assert.True(t, events[1].Wait >= time.Millisecond*50)
+}
+
+func TestLogObserver_Observe(t *testing.T) {
+	var b bytes.Buffer
+	o := NewLogObserver(log.New(&b, "", 0))
+
+	e := Event{Method: "GET", URL: "https://www.twse.com.tw/", Elapsed: time.Millisecond * 312}
+
+	for _, tc := range []struct {
+		event    Event
+		expected string
+	}{
+		{Event{Kind: EventThrottle, Wait: time.Second}, "event=throttle method=GET url=https://www.twse.com.tw/ elapsed=312ms wait=1s\n"},
+		{Event{Kind: EventResponse, StatusCode: 200, ContentType: "text/html"}, "event=response method=GET url=https://www.twse.com.tw/ elapsed=312ms status=200 content_type=text/html\n"},
+		{Event{Kind: EventResponse, Err: errors.New("EOF")}, "event=response method=GET url=https://www.twse.com.tw/ elapsed=312ms error=\"EOF\"\n"},
+		{Event{Kind: EventParse, Bytes: 1024}, "event=parse method=GET url=https://www.twse.com.tw/ elapsed=312ms bytes=1024\n"},
+		{Event{Kind: EventEnd, Bytes: 1024, Err: errors.New("banned")}, "event=end method=GET url=https://www.twse.com.tw/ elapsed=312ms bytes=1024 error=\"banned\"\n"},
+	} {
+		b.Reset()
+		tc.event.Method, tc.event.URL, tc.event.Elapsed = e.Method, e.URL, e.Elapsed
+		o.Observe(tc.event)
+		assert.Equal(t, tc.expected, b.String())
+	}
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy decides whether and when RetryingClient sends a request again.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one. Values less than 2 disable
+	// retrying.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, which doubles after each retry.
+	Backoff time.Duration
+	// Retryable reports whether an attempt should be retried. DefaultRetryable is used if nil.
+	Retryable func(resp *http.Response, err error) bool
+}
+
+// DefaultRetryable retries connection failures and 5xx responses. Empty replies are not retried since the
+// TWSE closes connections of banned clients, and retrying only prolongs the ban.
+func DefaultRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, io.EOF)
+	}
+
+	return resp.StatusCode >= 500
+}
+
+// RetryingClient sends a request again according to its RetryPolicy. Requests with bodies must be
+// created with http.NewRequest or have GetBody set, so that the bodies can be sent again. Waiting for a
+// retry stops with the error of the context of the request once it is done.
+type RetryingClient struct {
+	client Client
+	policy RetryPolicy
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+func NewRetryingClient(client Client, policy RetryPolicy) *RetryingClient {
+	if policy.Retryable == nil {
+		policy.Retryable = DefaultRetryable
+	}
+
+	return &RetryingClient{client: client, policy: policy, sleep: sleep}
+}
+
+// sleep waits for d, or returns the error of ctx if it is done earlier.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *RetryingClient) Do(req *http.Request) (*http.Response, error) {
+	backoff := c.policy.Backoff
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.Body != nil && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := c.client.Do(attemptReq)
+		if attempt >= c.policy.MaxAttempts || !c.policy.Retryable(resp, err) {
+			return resp, err
+		}
+
+		// The body has been consumed and cannot be sent again.
+		if req.Body != nil && req.GetBody == nil {
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		if err := c.sleep(req.Context(), backoff); err != nil {
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,41 @@
+// Package baseurl resolves the endpoints of the exchanges against configurable base URLs.
+package baseurl
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Parse validates raw as the base URL of an exchange, which must be an absolute http or https URL
+// without queries or fragments. It may have a path, e.g. the prefix of a proxy, which is prepended to the
+// paths of the endpoints.
+func Parse(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL '%s': %w", raw, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL '%s': scheme must be http or https", raw)
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL '%s': host is missing", raw)
+	}
+
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("invalid base URL '%s': only scheme, host, port and path are allowed", raw)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+
+	return u, nil
+}
+
+// Resolve returns the URL of the endpoint at path p with the query under base.
+func Resolve(base *url.URL, p string, query url.Values) string {
+	u := url.URL{Scheme: base.Scheme, Host: base.Host, Path: base.Path + p, RawQuery: query.Encode()}
+	return u.String()
+}
@@ -0,0 +1,85 @@
+// Package clientopt builds the HTTP clients of the twse and tpex clients from their options.
+package clientopt
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// DefaultTimeout is the timeout of each request unless configured.
+const DefaultTimeout = time.Second * 30
+
+// DefaultUserAgent is sent since the exchanges sometimes reject the default one of Go.
+const DefaultUserAgent = "Mozilla/5.0 (compatible; tshakutshai; +https://github.com/chehsunliu/tshakutshai)"
+
+// Languages of the responses requested from the exchanges.
+const (
+	LanguageChinese = iota
+	LanguageEnglish
+)
+
+// Options are the options shared by twse.Option and tpex.Option.
+type Options struct {
+	Timeout   time.Duration
+	UserAgent string
+	Header    http.Header
+	BaseURL   string
+	Limiter   tkthttp.Limiter
+	Cache     tkthttp.Cache
+	Retry     *tkthttp.RetryPolicy
+	Logger    tkthttp.Logger
+	Observer  tkthttp.Observer
+	Mutators  []tkthttp.RequestFunc
+	Strict    bool
+	Language  int
+	Names     quote.NameResolver
+	Calendar  *calendar.Calendar
+}
+
+// New returns the default options.
+func New() *Options {
+	return &Options{Timeout: DefaultTimeout, UserAgent: DefaultUserAgent, Header: http.Header{}}
+}
+
+// HttpClient builds the client sending requests in the order below, so that cached responses skip
+// throttling, and every retry is throttled and logged:
+//
+//     cache -> retry -> throttle -> limiter -> logger -> headers and mutators -> http.Client
+func (o *Options) HttpClient(minInterval time.Duration) tkthttp.Client {
+	var client tkthttp.Client = &http.Client{Timeout: o.Timeout}
+
+	funcs := []tkthttp.RequestFunc{o.setHeader}
+	client = tkthttp.NewMutatingClient(client, append(funcs, o.Mutators...)...)
+
+	if o.Logger != nil {
+		client = tkthttp.NewLoggingClient(client, o.Logger)
+	}
+	if o.Limiter != nil {
+		client = tkthttp.NewLimitedClient(client, o.Limiter)
+	}
+	client = tkthttp.NewThrottledClient(client, minInterval)
+	if o.Retry != nil {
+		client = tkthttp.NewRetryingClient(client, *o.Retry)
+	}
+	if o.Cache != nil {
+		client = tkthttp.NewCachingClient(client, o.Cache)
+	}
+
+	return client
+}
+
+func (o *Options) setHeader(req *http.Request) {
+	if o.UserAgent != "" {
+		req.Header.Set("User-Agent", o.UserAgent)
+	}
+	for k, vs := range o.Header {
+		req.Header.Del(k)
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+}
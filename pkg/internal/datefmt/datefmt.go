@@ -0,0 +1,36 @@
+// Package datefmt formats and parses the dates in the JSON and CSV representations of quotes, e.g.
+// 2021-03-24.
+package datefmt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+)
+
+// Layout is the layout of the dates, i.e. YYYY-MM-DD.
+const Layout = "2006-01-02"
+
+// Format returns the date of t normalized by calendar.Normalize, or an empty string if t is zero.
+func Format(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return calendar.Normalize(t).Format(Layout)
+}
+
+// Parse returns the midnight in Asia/Taipei of the date s, see calendar.Date, or the zero time if s is
+// empty.
+func Parse(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(Layout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is not a date like 2021-03-24: %w", s, err)
+	}
+
+	return calendar.Date(t.Year(), t.Month(), t.Day()), nil
+}
@@ -0,0 +1,22 @@
+// Package htmlpage recognizes the HTML pages the exchanges serve instead of data, e.g. on errors, bans
+// and maintenance.
+package htmlpage
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+)
+
+var maintenanceKeywords = regexp.MustCompile(`(?i)維護|maintenance|temporarily unavailable`)
+
+// Is reports whether body looks like an HTML page rather than JSON or CSV.
+func Is(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	return len(body) > 0 && body[0] == '<'
+}
+
+// IsMaintenance reports whether an HTML page served with the status code is a maintenance page.
+func IsMaintenance(statusCode int, body []byte) bool {
+	return statusCode == http.StatusServiceUnavailable || maintenanceKeywords.Match(body)
+}
@@ -0,0 +1,91 @@
+// Package jsonstream walks JSON documents by the tokens of json.Decoder, so that the twse and tpex clients
+// can decode the rows of large responses one at a time instead of materializing the whole documents.
+package jsonstream
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Object reads an object from dec and calls fn with the key of each member in order. fn either decodes
+// the value from dec and returns true, or returns false to have it skipped.
+func Object(dec *json.Decoder, fn func(key string) (bool, error)) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		key, ok := t.(string)
+		if !ok {
+			return fmt.Errorf("expected a key but got %v", t)
+		}
+
+		consumed, err := fn(key)
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			if err := Skip(dec); err != nil {
+				return err
+			}
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// Array reads an array from dec and calls fn for each element in order, which must decode the element
+// from dec.
+func Array(dec *json.Decoder, fn func() error) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, ']')
+}
+
+// Skip reads and drops the next value from dec.
+func Skip(dec *json.Decoder) error {
+	depth := 0
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if t != delim {
+		return fmt.Errorf("expected '%s' but got %v", delim, t)
+	}
+
+	return nil
+}
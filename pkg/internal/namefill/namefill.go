@@ -0,0 +1,43 @@
+// Package namefill fills the empty names of quotes by quote.NameResolver for the twse and tpex clients.
+package namefill
+
+import (
+	"fmt"
+
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// Day fills the empty names of q by r. Nothing is done if r is nil.
+func Day(r quote.NameResolver, q *quote.Day) error {
+	return fill(r, q.Code, &q.Name, &q.EnglishName)
+}
+
+// Monthly fills the names of q by r. Nothing is done if r is nil.
+func Monthly(r quote.NameResolver, q *quote.Monthly) error {
+	return fill(r, q.Code, &q.Name, &q.EnglishName)
+}
+
+// Yearly fills the names of q by r. Nothing is done if r is nil.
+func Yearly(r quote.NameResolver, q *quote.Yearly) error {
+	return fill(r, q.Code, &q.Name, &q.EnglishName)
+}
+
+func fill(r quote.NameResolver, code string, name, englishName *string) error {
+	if r == nil || (*name != "" && *englishName != "") {
+		return nil
+	}
+
+	names, err := r.ResolveNames(code)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the names of %s: %w", code, err)
+	}
+
+	if *name == "" {
+		*name = names.Chinese
+	}
+	if *englishName == "" {
+		*englishName = names.English
+	}
+
+	return nil
+}
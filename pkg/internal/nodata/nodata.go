@@ -0,0 +1,64 @@
+// Package nodata tells the reasons of no quotes for the strict mode of the twse and tpex clients, which
+// the exchanges do not tell themselves.
+package nodata
+
+import (
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+)
+
+// OfDay returns the reason of no quotes of all the stocks on date at now. Holidays and make-up trading
+// days are told by cal, or only weekends are known to be closed if cal is nil or fails. Trading days in
+// the past without quotes are undetermined, e.g. closed by typhoons.
+func OfDay(date, now time.Time, cal *calendar.Calendar) exchangeerr.NoDataReason {
+	open := date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+	if cal != nil {
+		if ok, err := cal.IsTradingDay(date); err == nil {
+			open = ok
+		}
+	}
+
+	switch {
+	case !open:
+		return exchangeerr.ReasonNonTradingDay
+	case !date.Before(calendar.Today(now)):
+		return exchangeerr.ReasonNotYetPublished
+	default:
+		return exchangeerr.ReasonUndetermined
+	}
+}
+
+// OfPeriod returns the reason of no quotes of a stock from from until to, exclusively, at now. years
+// returns the years the stock has quotes of, and is only called for periods not in the future, since it
+// takes one more request.
+func OfPeriod(from, to, now time.Time, years func() ([]int, error)) (exchangeerr.NoDataReason, error) {
+	if !from.Before(calendar.Today(now)) {
+		return exchangeerr.ReasonNotYetPublished, nil
+	}
+
+	ys, err := years()
+	if err != nil {
+		return exchangeerr.ReasonUndetermined, err
+	}
+	if len(ys) == 0 {
+		return exchangeerr.ReasonUnknownCode, nil
+	}
+
+	first := ys[0]
+	for _, y := range ys {
+		if y < first {
+			first = y
+		}
+	}
+
+	switch {
+	case from.Year() < first:
+		return exchangeerr.ReasonBeforeListing, nil
+	case now.Before(to):
+		return exchangeerr.ReasonNotYetPublished, nil
+	default:
+		return exchangeerr.ReasonUndetermined, nil
+	}
+}
@@ -0,0 +1,116 @@
+// Package quotejson implements the JSON representation shared by twse.Quote and tpex.Quote. Both have
+// the same underlying type as Quote, so they can be converted to it directly.
+package quotejson
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/internal/datefmt"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+)
+
+// Quote has the same fields as twse.Quote and tpex.Quote.
+type Quote struct {
+	Code         string
+	Name         string
+	Date         time.Time
+	Volume       uint64
+	Transactions uint64
+	Value        uint64
+	High         price.Price
+	Low          price.Price
+	Open         price.Price
+	Close        price.Price
+	DateOfHigh   time.Time
+	DateOfLow    time.Time
+}
+
+// wire is Quote with dates formatted as YYYY-MM-DD.
+type wire struct {
+	Code         string      `json:"code"`
+	Name         string      `json:"name,omitempty"`
+	Date         string      `json:"date"`
+	Open         price.Price `json:"open"`
+	High         price.Price `json:"high"`
+	Low          price.Price `json:"low"`
+	Close        price.Price `json:"close"`
+	Volume       uint64      `json:"volume"`
+	Transactions uint64      `json:"transactions"`
+	Value        uint64      `json:"value"`
+	DateOfHigh   *string     `json:"date_of_high"`
+	DateOfLow    *string     `json:"date_of_low"`
+}
+
+// optionalDate returns the date of t formatted, or nil for null if t is zero.
+func optionalDate(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+
+	s := datefmt.Format(t)
+	return &s
+}
+
+func derefDate(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// Marshal returns the JSON encoding of q. All the keys are present whatever the values and the
+// granularity of q are, so that consumers can rely on them: Open and Close are zeros in monthly and yearly
+// quotes and in day quotes without transactions, and DateOfHigh and DateOfLow, which are only set in
+// yearly quotes, are null if zero.
+func Marshal(q Quote) ([]byte, error) {
+	return json.Marshal(wire{
+		Code:         q.Code,
+		Name:         q.Name,
+		Date:         datefmt.Format(q.Date),
+		Open:         q.Open,
+		High:         q.High,
+		Low:          q.Low,
+		Close:        q.Close,
+		Volume:       q.Volume,
+		Transactions: q.Transactions,
+		Value:        q.Value,
+		DateOfHigh:   optionalDate(q.DateOfHigh),
+		DateOfLow:    optionalDate(q.DateOfLow),
+	})
+}
+
+// Unmarshal parses the JSON encoding produced by Marshal into q. Missing fields are left zero.
+func Unmarshal(data []byte, q *Quote) error {
+	var w wire
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+
+	decoded := Quote{
+		Code:         w.Code,
+		Name:         w.Name,
+		Open:         w.Open,
+		High:         w.High,
+		Low:          w.Low,
+		Close:        w.Close,
+		Volume:       w.Volume,
+		Transactions: w.Transactions,
+		Value:        w.Value,
+	}
+
+	var err error
+	if decoded.Date, err = datefmt.Parse(w.Date); err != nil {
+		return fmt.Errorf("invalid date: %w", err)
+	}
+	if decoded.DateOfHigh, err = datefmt.Parse(derefDate(w.DateOfHigh)); err != nil {
+		return fmt.Errorf("invalid date_of_high: %w", err)
+	}
+	if decoded.DateOfLow, err = datefmt.Parse(derefDate(w.DateOfLow)); err != nil {
+		return fmt.Errorf("invalid date_of_low: %w", err)
+	}
+
+	*q = decoded
+	return nil
+}
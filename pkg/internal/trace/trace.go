@@ -0,0 +1,92 @@
+// Package trace reports the events of the requests of the twse and tpex clients to tkthttp.Observer.
+package trace
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	tkthttp "github.com/chehsunliu/tshakutshai/pkg/http"
+)
+
+// Trace reports the events of a request. All its methods do nothing if the observer is nil.
+type Trace struct {
+	observer tkthttp.Observer
+	method   string
+	url      string
+	start    time.Time
+	bytes    int64
+}
+
+// Start reports tkthttp.EventRequest and returns req carrying o, so that the clients wrapped by the
+// twse and tpex clients, e.g. tkthttp.ThrottledClient, can report events as well.
+func Start(o tkthttp.Observer, req *http.Request) (*Trace, *http.Request) {
+	t := &Trace{observer: o, method: req.Method, url: req.URL.String(), start: time.Now()}
+	if o == nil {
+		return t, req
+	}
+
+	req = req.WithContext(tkthttp.ContextWithObserver(req.Context(), o))
+	t.observe(tkthttp.Event{Kind: tkthttp.EventRequest})
+	return t, req
+}
+
+func (t *Trace) observe(e tkthttp.Event) {
+	e.Method = t.method
+	e.URL = t.url
+	e.Elapsed = time.Since(t.start)
+	t.observer.Observe(e)
+}
+
+// Response reports tkthttp.EventResponse.
+func (t *Trace) Response(resp *http.Response, err error) {
+	if t.observer == nil {
+		return
+	}
+
+	if err != nil {
+		t.observe(tkthttp.Event{Kind: tkthttp.EventResponse, Err: err})
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	t.observe(tkthttp.Event{Kind: tkthttp.EventResponse, StatusCode: resp.StatusCode, ContentType: contentType})
+}
+
+// Body returns body counting the bytes read for Parse.
+func (t *Trace) Body(body io.Reader) io.Reader {
+	return &countingReader{reader: body, n: &t.bytes}
+}
+
+// Parse reports tkthttp.EventParse with the outcome of parsing, i.e. nil on success, and then
+// tkthttp.EventEnd, since parsing is the last step of a request.
+func (t *Trace) Parse(err error) {
+	if t.observer == nil {
+		return
+	}
+
+	t.observe(tkthttp.Event{Kind: tkthttp.EventParse, Bytes: t.bytes, Err: err})
+	t.observe(tkthttp.Event{Kind: tkthttp.EventEnd, Bytes: t.bytes, Err: err})
+}
+
+// End reports tkthttp.EventEnd with err of a request ending before its body is parsed, e.g. failing to
+// connect.
+func (t *Trace) End(err error) {
+	if t.observer == nil {
+		return
+	}
+
+	t.observe(tkthttp.Event{Kind: tkthttp.EventEnd, Bytes: t.bytes, Err: err})
+}
+
+type countingReader struct {
+	reader io.Reader
+	n      *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	*r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,268 @@
+// Package names builds a directory of the Chinese and English names of the stocks of both exchanges from
+// their day quotes, which fills the names the exchanges leave empty in the other quotes:
+//
+//     twseClient, tpexClient := twse.NewClient(time.Second*2), tpex.NewClient(time.Second)
+//     d := names.NewDirectoryWithClients(twseClient, tpexClient)
+//     twseClient.NameResolver, tpexClient.NameResolver = d, d
+//
+//     qs, _ := twseClient.FetchMonthlyQuotes("2330", 2020)
+//     fmt.Println(qs[0].Name) // 台積電
+//
+// The directory is built on the first lookup, and rebuilt once it expires. Failing to build it leaves the
+// names empty rather than failing the lookups, and it is built again after RetryInterval.
+package names
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/exchangeerr"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// DefaultTTL is how long Directory keeps the names unless configured.
+const DefaultTTL = time.Hour * 24
+
+// DefaultRetryInterval is how long Directory waits to build the names again after failing to, unless
+// configured.
+const DefaultRetryInterval = time.Minute * 10
+
+// lookback is the number of days searched backwards for a trading day, which covers the Lunar New Year
+// holidays.
+const lookback = 14
+
+// DayFetcher fetches the day quotes of all the stocks on a date. Both twse.Client and tpex.Client
+// implement it.
+type DayFetcher interface {
+	FetchDayQuotes(date time.Time) (map[string]quote.Day, error)
+}
+
+// Source is a market to build the directory from.
+type Source struct {
+	// Chinese fetches the quotes with the Chinese names.
+	Chinese DayFetcher
+	// English fetches the quotes with the English names, e.g. a client with LanguageEnglish. It is
+	// optional.
+	English DayFetcher
+}
+
+// Directory maps stock codes to their names, and implements quote.NameResolver. It is safe for
+// concurrent use.
+type Directory struct {
+	// Sources are looked up in order, so the names of a code in the former sources win.
+	Sources []Source
+	// TTL is how long the names are kept before rebuilt. Zero means DefaultTTL.
+	TTL time.Duration
+	// RetryInterval is how long the names are kept, or left empty, after failing to rebuild them. Zero
+	// means DefaultRetryInterval.
+	RetryInterval time.Duration
+
+	mutex    sync.Mutex
+	names    map[string]quote.Names
+	loadedAt time.Time
+	// failedAt is when rebuilding the names last failed, or zero if it has not failed since loaded.
+	failedAt time.Time
+	lastErr  error
+	// loading is closed when the names being built are loaded, or nil if none are being built.
+	loading chan struct{}
+}
+
+// NewDirectory returns a Directory of the stocks of the TWSE and the TPEx queried by clients of their own,
+// whose intervals between each query are not less than minInterval. Prefer NewDirectoryWithClients to
+// share the throttling with the clients using the directory.
+func NewDirectory(minInterval time.Duration) *Directory {
+	return NewDirectoryWithClients(twse.NewClient(minInterval), tpex.NewClient(minInterval))
+}
+
+// NewDirectoryWithClients returns a Directory of the stocks of the TWSE and the TPEx queried by copies of
+// twseClient and tpexClient, which share their HTTP clients and so their throttling. The TWSE provides no
+// names in English, so only the TPEx is queried in English. Either client can be nil to skip the market.
+//
+// The copies resolve no names themselves, so the directory can be set as the NameResolver of the clients
+// afterwards, without looking up the directory while building it.
+func NewDirectoryWithClients(twseClient *twse.Client, tpexClient *tpex.Client) *Directory {
+	d := &Directory{Sources: make([]Source, 0, 2), TTL: DefaultTTL, RetryInterval: DefaultRetryInterval}
+
+	if twseClient != nil {
+		chinese := *twseClient
+		chinese.NameResolver = nil
+		chinese.Language = twse.LanguageChinese
+		d.Sources = append(d.Sources, Source{Chinese: &chinese})
+	}
+
+	if tpexClient != nil {
+		chinese, english := *tpexClient, *tpexClient
+		chinese.NameResolver, english.NameResolver = nil, nil
+		chinese.Language, english.Language = tpex.LanguageChinese, tpex.LanguageEnglish
+		d.Sources = append(d.Sources, Source{Chinese: &chinese, English: &english})
+	}
+
+	return d
+}
+
+// ResolveNames returns the names of the code, or zero Names if the code is unknown. The directory is
+// built from the latest trading day first if it is not yet or expired. It never fails: if the directory
+// cannot be built, the names loaded before are kept, or zero Names are returned, until RetryInterval
+// passes, see Err.
+//
+// The directory is built without blocking the other lookups, which are served with the names loaded
+// before, or wait for the directory being built if there are none.
+func (d *Directory) ResolveNames(code string) (quote.Names, error) {
+	for {
+		d.mutex.Lock()
+		expired := d.names == nil || time.Since(d.loadedAt) >= d.ttl()
+		retrying := d.failedAt.IsZero() || time.Since(d.failedAt) >= d.retryInterval()
+		if !expired || !retrying || (d.loading != nil && d.names != nil) {
+			names := d.names[code]
+			d.mutex.Unlock()
+			return names, nil
+		}
+
+		if done := d.loading; done != nil {
+			d.mutex.Unlock()
+			<-done
+			continue
+		}
+
+		done := make(chan struct{})
+		d.loading = done
+		d.mutex.Unlock()
+
+		names, err := d.build(calendar.Today(time.Now()))
+
+		d.mutex.Lock()
+		d.loading = nil
+		close(done)
+		if err != nil {
+			d.failedAt, d.lastErr = time.Now(), err
+		} else {
+			d.store(names)
+		}
+		n := d.names[code]
+		d.mutex.Unlock()
+		return n, nil
+	}
+}
+
+func (d *Directory) ttl() time.Duration {
+	if d.TTL == 0 {
+		return DefaultTTL
+	}
+	return d.TTL
+}
+
+func (d *Directory) retryInterval() time.Duration {
+	if d.RetryInterval == 0 {
+		return DefaultRetryInterval
+	}
+	return d.RetryInterval
+}
+
+// Err returns the error of the last failure to build the directory, or nil if it has been built since.
+func (d *Directory) Err() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.lastErr
+}
+
+// Load builds the directory from the day quotes on the date, or the latest trading day before it within
+// two weeks, replacing the names loaded before. It is useful to build the directory in advance.
+func (d *Directory) Load(date time.Time) error {
+	names, err := d.build(date)
+	if err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.store(names)
+	return nil
+}
+
+// build queries the sources for the names without holding the mutex, since the queries are throttled.
+func (d *Directory) build(date time.Time) (map[string]quote.Names, error) {
+	names := map[string]quote.Names{}
+
+	for _, source := range d.Sources {
+		day, qs, err := latestDayQuotes(source.Chinese, calendar.Normalize(date))
+		if err != nil {
+			return nil, err
+		}
+
+		var englishQuotes map[string]quote.Day
+		if source.English != nil && len(qs) > 0 {
+			if englishQuotes, err = fetchDayQuotes(source.English, day); err != nil {
+				return nil, err
+			}
+		}
+
+		for code, q := range qs {
+			if _, ok := names[code]; ok {
+				continue
+			}
+
+			english := englishQuotes[code].EnglishName
+			if english == "" {
+				english = q.EnglishName
+			}
+			names[code] = newNames(q.Name, english)
+		}
+	}
+
+	return names, nil
+}
+
+func (d *Directory) store(names map[string]quote.Names) {
+	d.names = names
+	d.loadedAt = time.Now()
+	d.failedAt, d.lastErr = time.Time{}, nil
+}
+
+func newNames(chinese, english string) quote.Names {
+	short := chinese
+	if short == "" {
+		short = english
+	}
+
+	return quote.Names{Chinese: chinese, English: english, Short: short}
+}
+
+// latestDayQuotes returns the day quotes of the latest trading day not after date within lookback days,
+// or no quotes if none found.
+func latestDayQuotes(f DayFetcher, date time.Time) (time.Time, map[string]quote.Day, error) {
+	for i := 0; i < lookback; i, date = i+1, date.AddDate(0, 0, -1) {
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			continue
+		}
+
+		qs, err := fetchDayQuotes(f, date)
+		if err != nil {
+			return time.Time{}, nil, err
+		}
+		if len(qs) > 0 {
+			return date, qs, nil
+		}
+	}
+
+	return date, map[string]quote.Day{}, nil
+}
+
+// fetchDayQuotes returns no quotes for exchangeerr.ErrNoData, so that clients in strict mode work as well.
+func fetchDayQuotes(f DayFetcher, date time.Time) (map[string]quote.Day, error) {
+	qs, err := f.FetchDayQuotes(date)
+	if errors.Is(err, exchangeerr.ErrNoData) {
+		return map[string]quote.Day{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch day quotes on %s: %w", date.Format("2006-01-02"), err)
+	}
+
+	return qs, nil
+}
@@ -0,0 +1,184 @@
+package names_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/fakeexchange"
+	"github.com/chehsunliu/tshakutshai/pkg/names"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+func newDirectory(s *fakeexchange.Server) *names.Directory {
+	tpexClient := &tpex.Client{HttpClient: s.HttpClient(), Strict: true}
+	return &names.Directory{
+		Sources: []names.Source{
+			{Chinese: &twse.Client{HttpClient: s.HttpClient()}},
+			{Chinese: tpexClient, English: &tpex.Client{HttpClient: s.HttpClient(), Language: tpex.LanguageEnglish}},
+		},
+	}
+}
+
+func TestDirectory_Load(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	// Friday; the directory is loaded on the Monday after a holiday.
+	date := calendar.Date(2021, 2, 5)
+	s.AddDayQuotes(fakeexchange.MarketTWSE, quote.Day{Code: "2330", Name: "台積電", Date: date})
+	s.AddDayQuotes(fakeexchange.MarketTPEx, quote.Day{Code: "8044", Name: "網家", EnglishName: "PChome Online Inc.", Date: date})
+
+	d := newDirectory(s)
+	assert.Nil(t, d.Load(calendar.Date(2021, 2, 8)))
+
+	n, err := d.ResolveNames("2330")
+	assert.Nil(t, err)
+	assert.Equal(t, quote.Names{Chinese: "台積電", Short: "台積電"}, n)
+
+	n, err = d.ResolveNames("8044")
+	assert.Nil(t, err)
+	assert.Equal(t, quote.Names{Chinese: "網家", English: "PChome Online Inc.", Short: "網家"}, n)
+
+	n, err = d.ResolveNames("9999")
+	assert.Nil(t, err)
+	assert.Equal(t, quote.Names{}, n)
+
+	// 2021-02-08 of both, 2021-02-05 of both, and 2021-02-05 in English; none after loaded.
+	assert.Equal(t, 5, len(s.Requests()))
+}
+
+func TestDirectory_ResolveNamesFailure(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+	s.SetFault("", fakeexchange.FaultHTMLError)
+
+	d := newDirectory(s)
+	n, err := d.ResolveNames("2330")
+	assert.Nil(t, err)
+	assert.Equal(t, quote.Names{}, n)
+	assert.NotNil(t, d.Err())
+
+	// Not built again until RetryInterval passes.
+	requests := len(s.Requests())
+	_, err = d.ResolveNames("2330")
+	assert.Nil(t, err)
+	assert.Equal(t, requests, len(s.Requests()))
+}
+
+// blockingFetcher returns the quote of 2330 once release is closed.
+type blockingFetcher struct {
+	calls   int32
+	release chan struct{}
+}
+
+func (f *blockingFetcher) FetchDayQuotes(date time.Time) (map[string]quote.Day, error) {
+	atomic.AddInt32(&f.calls, 1)
+	<-f.release
+	return map[string]quote.Day{"2330": {Code: "2330", Name: "台積電", Date: date}}, nil
+}
+
+func (f *blockingFetcher) waitCalls(t *testing.T, n int32) {
+	deadline := time.Now().Add(time.Second * 5)
+	for atomic.LoadInt32(&f.calls) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d calls but got %d", n, atomic.LoadInt32(&f.calls))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDirectory_ResolveNamesConcurrently(t *testing.T) {
+	f := &blockingFetcher{release: make(chan struct{})}
+	d := &names.Directory{Sources: []names.Source{{Chinese: f}}}
+
+	results := make(chan quote.Names)
+	for i := 0; i < 3; i++ {
+		go func() {
+			n, _ := d.ResolveNames("2330")
+			results <- n
+		}()
+	}
+
+	// The directory is not locked while being built.
+	f.waitCalls(t, 1)
+	assert.Nil(t, d.Err())
+
+	close(f.release)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "台積電", (<-results).Chinese)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&f.calls))
+
+	// The names loaded before are served while rebuilding them.
+	d.TTL = time.Nanosecond
+	f.release = make(chan struct{})
+	go func() {
+		n, _ := d.ResolveNames("2330")
+		results <- n
+	}()
+	f.waitCalls(t, 2)
+
+	n, err := d.ResolveNames("2330")
+	assert.Nil(t, err)
+	assert.Equal(t, "台積電", n.Chinese)
+
+	close(f.release)
+	assert.Equal(t, "台積電", (<-results).Chinese)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&f.calls))
+}
+
+func TestNewDirectoryWithClients(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	date := calendar.Date(2021, 2, 5)
+	s.AddDayQuotes(fakeexchange.MarketTWSE, quote.Day{Code: "2330", Name: "台積電", Date: date})
+	s.AddDayQuotes(fakeexchange.MarketTPEx, quote.Day{Code: "8044", Name: "網家", EnglishName: "PChome Online Inc.", Date: date})
+	m := quote.Monthly{Code: "8044", Year: 2020, Month: time.January}
+	s.AddMonthlyQuotes(fakeexchange.MarketTPEx, m)
+
+	twseClient := twse.NewClient(0, twse.WithBaseURL(s.URL), twse.WithLanguage(twse.LanguageEnglish))
+	tpexClient := tpex.NewClient(0, tpex.WithBaseURL(s.URL), tpex.WithStrict())
+	d := names.NewDirectoryWithClients(twseClient, tpexClient)
+	twseClient.NameResolver, tpexClient.NameResolver = d, d
+	assert.Nil(t, d.Load(date))
+
+	n, err := d.ResolveNames("2330")
+	assert.Nil(t, err)
+	assert.Equal(t, "台積電", n.Chinese, "the TWSE should be queried in Chinese")
+
+	monthlyQuotes, err := tpexClient.FetchMonthlyQuotes("8044", 2020)
+	assert.Nil(t, err)
+	m.Name, m.EnglishName = "網家", "PChome Online Inc."
+	assert.Equal(t, []quote.Monthly{m}, monthlyQuotes)
+}
+
+func TestDirectory_WithClients(t *testing.T) {
+	s := fakeexchange.NewServer()
+	defer s.Close()
+
+	date := calendar.Date(2021, 2, 5)
+	s.AddDayQuotes(fakeexchange.MarketTPEx, quote.Day{Code: "8044", Name: "網家", EnglishName: "PChome Online Inc.", Date: date})
+	m := quote.Monthly{Code: "8044", Year: 2020, Month: time.January}
+	s.AddMonthlyQuotes(fakeexchange.MarketTPEx, m)
+	d := newDirectory(s)
+	assert.Nil(t, d.Load(date))
+
+	client := &tpex.Client{HttpClient: s.HttpClient(), NameResolver: d}
+
+	dayQuotes, err := client.FetchDayQuotes(date)
+	assert.Nil(t, err)
+	assert.Equal(t, "網家", dayQuotes["8044"].Name)
+	assert.Equal(t, "PChome Online Inc.", dayQuotes["8044"].EnglishName)
+
+	monthlyQuotes, err := client.FetchMonthlyQuotes("8044", 2020)
+	assert.Nil(t, err)
+	m.Name, m.EnglishName = "網家", "PChome Online Inc."
+	assert.Equal(t, []quote.Monthly{m}, monthlyQuotes)
+}
@@ -0,0 +1,255 @@
+// Package price provides Price, an exact fixed-point decimal used for prices quoted by the Taiwan
+// exchanges. Unlike float64, prices like 48.37 are represented exactly, so they can be summed and
+// compared with limit prices without rounding errors.
+//
+//     p := price.MustParse("48.37")
+//     fmt.Println(p.Add(price.MustParse("0.05"))) // 48.42
+package price
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Scale is the number of Price units in 1 dollar, i.e. Price is measured in ten-thousandths.
+const Scale = 10000
+
+// Decimals is the maximum number of decimal places Price can represent.
+const Decimals = 4
+
+// Price is a decimal price in ten-thousandths of a dollar, e.g. Price(483700) is 48.37. The zero
+// value is 0, which is also used for the prices of stocks having no transactions.
+type Price int64
+
+// New returns the Price of the given number of ten-thousandths, e.g. New(483700) is 48.37.
+func New(units int64) Price {
+	return Price(units)
+}
+
+// FromInt returns the Price of dollars, e.g. FromInt(48) is 48.00.
+func FromInt(dollars int64) Price {
+	return Price(dollars * Scale)
+}
+
+// FromFloat64 returns the Price closest to f.
+func FromFloat64(f float64) Price {
+	return Price(math.Round(f * Scale))
+}
+
+// Parse parses decimal strings like "48.37", "-0.5" and "1,234.5" as the exchanges write them. It
+// returns an error if s has more than Decimals decimal places.
+func Parse(s string) (Price, error) {
+	raw := strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	if raw == "" {
+		return 0, fmt.Errorf("failed to parse price '%s': empty string", s)
+	}
+
+	negative := false
+	switch raw[0] {
+	case '-':
+		negative = true
+		raw = raw[1:]
+	case '+':
+		raw = raw[1:]
+	}
+
+	integer, fraction := raw, ""
+	if i := strings.IndexByte(raw, '.'); i >= 0 {
+		integer, fraction = raw[:i], raw[i+1:]
+	}
+
+	if integer == "" && fraction == "" {
+		return 0, fmt.Errorf("failed to parse price '%s': no digits", s)
+	}
+	if len(fraction) > Decimals {
+		return 0, fmt.Errorf("failed to parse price '%s': more than %d decimal places", s, Decimals)
+	}
+
+	var units int64
+	for _, part := range []string{integer, fraction + strings.Repeat("0", Decimals-len(fraction))} {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return 0, fmt.Errorf("failed to parse price '%s': illegal character '%c'", s, r)
+			}
+			if units > (math.MaxInt64-9)/10 {
+				return 0, fmt.Errorf("failed to parse price '%s': out of range", s)
+			}
+			units = units*10 + int64(r-'0')
+		}
+	}
+
+	if negative {
+		units = -units
+	}
+
+	return Price(units), nil
+}
+
+// MustParse is like Parse but panics if s cannot be parsed. It is intended for constants and tests.
+func MustParse(s string) Price {
+	p, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// Units returns p in ten-thousandths.
+func (p Price) Units() int64 {
+	return int64(p)
+}
+
+// Float64 returns the nearest float64 of p. The result is subject to rounding errors and should be
+// used only for display or statistics.
+func (p Price) Float64() float64 {
+	return float64(p) / Scale
+}
+
+// IsZero reports whether p is 0.
+func (p Price) IsZero() bool {
+	return p == 0
+}
+
+// Add returns p+q.
+func (p Price) Add(q Price) Price {
+	return p + q
+}
+
+// Sub returns p-q.
+func (p Price) Sub(q Price) Price {
+	return p - q
+}
+
+// Mul returns p*n.
+func (p Price) Mul(n int64) Price {
+	return p * Price(n)
+}
+
+// MulRatio returns p*num/den rounded half away from zero, e.g. p.MulRatio(110, 100) is p raised by
+// 10%. It panics if den is 0.
+func (p Price) MulRatio(num, den int64) Price {
+	return Price(divRound(int64(p)*num, den))
+}
+
+// Div returns p/n rounded half away from zero. It panics if n is 0.
+func (p Price) Div(n int64) Price {
+	return Price(divRound(int64(p), n))
+}
+
+func divRound(a, b int64) int64 {
+	negative := (a < 0) != (b < 0)
+	a, b = abs(a), abs(b)
+
+	q := (2*a + b) / (2 * b)
+	if negative {
+		return -q
+	}
+	return q
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Cmp returns -1, 0 or +1 if p is less than, equal to or greater than q.
+func (p Price) Cmp(q Price) int {
+	switch {
+	case p < q:
+		return -1
+	case p > q:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// String formats p with at least 2 decimal places as the exchanges do, e.g. "48.37", "576.00" and
+// "0.0125".
+func (p Price) String() string {
+	units := int64(p)
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	fraction := fmt.Sprintf("%04d", units%Scale)
+	fraction = strings.TrimRight(fraction, "0")
+	for len(fraction) < 2 {
+		fraction += "0"
+	}
+
+	return fmt.Sprintf("%s%d.%s", sign, units/Scale, fraction)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p Price) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *Price) UnmarshalText(text []byte) error {
+	v, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+
+	*p = v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. Prices are written as JSON numbers with exact decimal
+// digits, e.g. 48.37.
+func (p Price) MarshalJSON() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Both JSON numbers and strings are accepted.
+func (p *Price) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	raw := string(data)
+	if strings.HasPrefix(raw, `"`) {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	}
+
+	return p.UnmarshalText([]byte(raw))
+}
+
+// Value implements driver.Valuer. Prices are stored as decimal strings, which both NUMERIC columns
+// and text columns keep exactly.
+func (p Price) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+// Scan implements sql.Scanner.
+func (p *Price) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*p = 0
+		return nil
+	case int64:
+		*p = FromInt(v)
+		return nil
+	case float64:
+		*p = FromFloat64(v)
+		return nil
+	case []byte:
+		return p.UnmarshalText(v)
+	case string:
+		return p.UnmarshalText([]byte(v))
+	default:
+		return fmt.Errorf("cannot scan %T into Price", src)
+	}
+}
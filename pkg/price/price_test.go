@@ -0,0 +1,109 @@
+package price_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		expected price.Price
+	}{
+		{"48.37", price.New(483_700)},
+		{"576.00", price.New(5_760_000)},
+		{"1,234.5", price.New(12_345_000)},
+		{" 0.0125 ", price.New(125)},
+		{"-0.5", price.New(-5_000)},
+		{"+2.10", price.New(21_000)},
+		{"9999.95", price.New(99_999_500)},
+		{".5", price.New(5_000)},
+		{"3", price.New(30_000)},
+	} {
+		p, err := price.Parse(tc.s)
+		assert.Nilf(t, err, "%s: %v", tc.s, err)
+		assert.Equalf(t, tc.expected, p, "%s", tc.s)
+	}
+
+	for _, s := range []string{"", "--", "---", "-", ".", "1.23456", "1e5", "abc", "99999999999999999999"} {
+		_, err := price.Parse(s)
+		assert.NotNilf(t, err, "%s", s)
+	}
+}
+
+func TestPrice_String(t *testing.T) {
+	assert.Equal(t, "48.37", price.MustParse("48.37").String())
+	assert.Equal(t, "576.00", price.MustParse("576").String())
+	assert.Equal(t, "0.0125", price.MustParse("0.0125").String())
+	assert.Equal(t, "-2.10", price.MustParse("-2.1").String())
+	assert.Equal(t, "0.00", price.Price(0).String())
+}
+
+func TestPrice_Arithmetic(t *testing.T) {
+	p := price.MustParse("48.37")
+
+	sum := price.Price(0)
+	for i := 0; i < 10; i++ {
+		sum = sum.Add(p)
+	}
+	assert.Equal(t, price.MustParse("483.70"), sum)
+
+	assert.Equal(t, price.MustParse("48.32"), p.Sub(price.MustParse("0.05")))
+	assert.Equal(t, price.MustParse("96.74"), p.Mul(2))
+	assert.Equal(t, price.MustParse("53.207"), p.MulRatio(110, 100))
+	assert.Equal(t, price.MustParse("16.1233"), p.Div(3))
+	assert.Equal(t, price.MustParse("-16.1233"), p.Mul(-1).Div(3))
+	assert.Equal(t, price.New(2), price.New(3).Div(2))
+	assert.Equal(t, price.New(-2), price.New(-3).Div(2))
+
+	assert.Equal(t, -1, p.Cmp(price.MustParse("48.38")))
+	assert.Equal(t, 0, p.Cmp(price.MustParse("48.370")))
+	assert.Equal(t, 1, p.Cmp(price.MustParse("48.36")))
+
+	assert.Equal(t, 48.37, p.Float64())
+	assert.Equal(t, p, price.FromFloat64(48.37))
+	assert.Equal(t, price.MustParse("48"), price.FromInt(48))
+}
+
+func TestPrice_JSON(t *testing.T) {
+	type wrapper struct {
+		Close price.Price `json:"close"`
+	}
+
+	data, err := json.Marshal(wrapper{Close: price.MustParse("48.37")})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"close":48.37}`, string(data))
+
+	var w wrapper
+	assert.Nil(t, json.Unmarshal([]byte(`{"close":131.5}`), &w))
+	assert.Equal(t, price.MustParse("131.50"), w.Close)
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"close":"19.49"}`), &w))
+	assert.Equal(t, price.MustParse("19.49"), w.Close)
+
+	assert.NotNil(t, json.Unmarshal([]byte(`{"close":true}`), &w))
+}
+
+func TestPrice_SQL(t *testing.T) {
+	v, err := price.MustParse("48.37").Value()
+	assert.Nil(t, err)
+	assert.Equal(t, "48.37", v)
+
+	var p price.Price
+	for _, src := range []interface{}{"48.37", []byte("48.37"), 48.37} {
+		assert.Nil(t, p.Scan(src))
+		assert.Equal(t, price.MustParse("48.37"), p)
+	}
+
+	assert.Nil(t, p.Scan(int64(576)))
+	assert.Equal(t, price.MustParse("576"), p)
+
+	assert.Nil(t, p.Scan(nil))
+	assert.Equal(t, price.Price(0), p)
+
+	assert.NotNil(t, p.Scan(true))
+}
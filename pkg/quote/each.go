@@ -0,0 +1,7 @@
+package quote
+
+import "errors"
+
+// ErrStop is returned by the callbacks of EachDayQuote of the twse and tpex clients to stop the iteration
+// early. EachDayQuote then returns nil rather than ErrStop.
+var ErrStop = errors.New("stop iteration")
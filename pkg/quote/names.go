@@ -0,0 +1,18 @@
+package quote
+
+// Names are the names of a stock.
+type Names struct {
+	// Chinese is the name in the Chinese quotes, e.g. 台積電.
+	Chinese string `json:"chinese,omitempty"`
+	// English is the name in the English quotes, e.g. PChome Online Inc.
+	English string `json:"english,omitempty"`
+	// Short is the name for display in limited width, which is Chinese, or English if Chinese is empty.
+	Short string `json:"short,omitempty"`
+}
+
+// NameResolver resolves the names of stocks by codes, e.g. names.Directory. The Fetch functions of the
+// twse and tpex clients fill the empty names of quotes with it if configured. Unknown codes have zero
+// Names.
+type NameResolver interface {
+	ResolveNames(code string) (Names, error)
+}
@@ -0,0 +1,211 @@
+// Package quote defines the quotes shared by the TWSE and the TPEx clients, one type per granularity, so
+// that only the fields meaningful to a granularity can be read:
+//
+//     Day      quotes of a stock on a trading day, returned by FetchDayQuotes and FetchDailyQuotes
+//     Monthly  aggregates of a stock in a month, returned by FetchMonthlyQuotes
+//     Yearly   aggregates of a stock in a year, returned by FetchYearlyQuotes
+//
+// twse.DayQuote and tpex.DayQuote, etc., are aliases of these types.
+//
+// All of them are encoded to JSON with snake_case keys, and dates are encoded as YYYY-MM-DD.
+package quote
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/internal/datefmt"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+)
+
+// Day is the quote of a stock on a trading day. If no transactions are made, i.e. Transactions equals to
+// zero, the prices are all zeros.
+type Day struct {
+	// Code/symbol of a stock, e.g. 0050 and 2330.
+	Code string `json:"code"`
+	// Name is the Chinese stock name. It is empty in the daily quotes of the TWSE, and in the quotes
+	// fetched in English, unless filled by a name resolver, see the names package.
+	Name string `json:"name,omitempty"`
+	// EnglishName is the English stock name, only available in the quotes of the TPEx fetched in English,
+	// or with a name resolver.
+	EnglishName string `json:"english_name,omitempty"`
+	// Date is the midnight in Asia/Taipei, see calendar.Date.
+	Date time.Time `json:"date"`
+
+	Open  price.Price `json:"open"`
+	High  price.Price `json:"high"`
+	Low   price.Price `json:"low"`
+	Close price.Price `json:"close"`
+
+	Volume       uint64 `json:"volume"`
+	Transactions uint64 `json:"transactions"`
+	Value        uint64 `json:"value"`
+}
+
+// Monthly is the aggregate of the day quotes of a stock in a month.
+type Monthly struct {
+	Code string `json:"code"`
+	// Name and EnglishName are only available with a name resolver, see the names package.
+	Name        string     `json:"name,omitempty"`
+	EnglishName string     `json:"english_name,omitempty"`
+	Year        int        `json:"year"`
+	Month       time.Month `json:"month"`
+
+	High price.Price `json:"high"`
+	Low  price.Price `json:"low"`
+
+	Volume       uint64 `json:"volume"`
+	Transactions uint64 `json:"transactions"`
+	Value        uint64 `json:"value"`
+}
+
+// Date returns the first day of the month.
+func (q Monthly) Date() time.Time {
+	return calendar.Date(q.Year, q.Month, 1)
+}
+
+// Yearly is the aggregate of the day quotes of a stock in a year.
+type Yearly struct {
+	Code string `json:"code"`
+	// Name and EnglishName are only available with a name resolver, see the names package.
+	Name        string `json:"name,omitempty"`
+	EnglishName string `json:"english_name,omitempty"`
+	Year        int    `json:"year"`
+
+	High price.Price `json:"high"`
+	Low  price.Price `json:"low"`
+	// DateOfHigh and DateOfLow are the dates High and Low were made, both the midnight in Asia/Taipei.
+	DateOfHigh time.Time `json:"date_of_high"`
+	DateOfLow  time.Time `json:"date_of_low"`
+
+	Volume       uint64 `json:"volume"`
+	Transactions uint64 `json:"transactions"`
+	Value        uint64 `json:"value"`
+}
+
+// Date returns the first day of the year.
+func (q Yearly) Date() time.Time {
+	return calendar.Date(q.Year, time.January, 1)
+}
+
+// dayJSON is Day with the date formatted as YYYY-MM-DD.
+type dayJSON struct {
+	Code         string      `json:"code"`
+	Name         string      `json:"name,omitempty"`
+	EnglishName  string      `json:"english_name,omitempty"`
+	Date         string      `json:"date"`
+	Open         price.Price `json:"open"`
+	High         price.Price `json:"high"`
+	Low          price.Price `json:"low"`
+	Close        price.Price `json:"close"`
+	Volume       uint64      `json:"volume"`
+	Transactions uint64      `json:"transactions"`
+	Value        uint64      `json:"value"`
+}
+
+func (q Day) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dayJSON{
+		Code:         q.Code,
+		Name:         q.Name,
+		EnglishName:  q.EnglishName,
+		Date:         datefmt.Format(q.Date),
+		Open:         q.Open,
+		High:         q.High,
+		Low:          q.Low,
+		Close:        q.Close,
+		Volume:       q.Volume,
+		Transactions: q.Transactions,
+		Value:        q.Value,
+	})
+}
+
+func (q *Day) UnmarshalJSON(data []byte) error {
+	var j dayJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	date, err := datefmt.Parse(j.Date)
+	if err != nil {
+		return fmt.Errorf("invalid date: %w", err)
+	}
+
+	*q = Day{
+		Code:         j.Code,
+		Name:         j.Name,
+		EnglishName:  j.EnglishName,
+		Date:         date,
+		Open:         j.Open,
+		High:         j.High,
+		Low:          j.Low,
+		Close:        j.Close,
+		Volume:       j.Volume,
+		Transactions: j.Transactions,
+		Value:        j.Value,
+	}
+	return nil
+}
+
+// yearlyJSON is Yearly with the dates formatted as YYYY-MM-DD.
+type yearlyJSON struct {
+	Code         string      `json:"code"`
+	Name         string      `json:"name,omitempty"`
+	EnglishName  string      `json:"english_name,omitempty"`
+	Year         int         `json:"year"`
+	High         price.Price `json:"high"`
+	Low          price.Price `json:"low"`
+	DateOfHigh   string      `json:"date_of_high,omitempty"`
+	DateOfLow    string      `json:"date_of_low,omitempty"`
+	Volume       uint64      `json:"volume"`
+	Transactions uint64      `json:"transactions"`
+	Value        uint64      `json:"value"`
+}
+
+func (q Yearly) MarshalJSON() ([]byte, error) {
+	return json.Marshal(yearlyJSON{
+		Code:         q.Code,
+		Name:         q.Name,
+		EnglishName:  q.EnglishName,
+		Year:         q.Year,
+		High:         q.High,
+		Low:          q.Low,
+		DateOfHigh:   datefmt.Format(q.DateOfHigh),
+		DateOfLow:    datefmt.Format(q.DateOfLow),
+		Volume:       q.Volume,
+		Transactions: q.Transactions,
+		Value:        q.Value,
+	})
+}
+
+func (q *Yearly) UnmarshalJSON(data []byte) error {
+	var j yearlyJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	dateOfHigh, err := datefmt.Parse(j.DateOfHigh)
+	if err != nil {
+		return fmt.Errorf("invalid date_of_high: %w", err)
+	}
+	dateOfLow, err := datefmt.Parse(j.DateOfLow)
+	if err != nil {
+		return fmt.Errorf("invalid date_of_low: %w", err)
+	}
+
+	*q = Yearly{
+		Code:         j.Code,
+		Name:         j.Name,
+		EnglishName:  j.EnglishName,
+		Year:         j.Year,
+		High:         j.High,
+		Low:          j.Low,
+		DateOfHigh:   dateOfHigh,
+		DateOfLow:    dateOfLow,
+		Volume:       j.Volume,
+		Transactions: j.Transactions,
+		Value:        j.Value,
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package quote_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+func TestDay_JSON(t *testing.T) {
+	q := quote.Day{
+		Code:         "2330",
+		Name:         "台積電",
+		Date:         calendar.Date(2021, time.March, 24),
+		Open:         price.MustParse("571.00"),
+		High:         price.MustParse("582.00"),
+		Low:          price.MustParse("571.00"),
+		Close:        price.MustParse("576.00"),
+		Volume:       115_318_351,
+		Transactions: 242_138,
+		Value:        66_559_451_738,
+	}
+
+	data, err := json.Marshal(q)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"code":"2330","name":"台積電","date":"2021-03-24","open":571.00,"high":582.00,"low":571.00,`+
+		`"close":576.00,"volume":115318351,"transactions":242138,"value":66559451738}`, string(data))
+
+	var decoded quote.Day
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, q, decoded)
+
+	assert.NotNil(t, json.Unmarshal([]byte(`{"code":"2330","date":"2021/03/24"}`), &decoded))
+
+	q.Name, q.EnglishName = "", "TSMC"
+	data, err = json.Marshal(q)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"code":"2330","english_name":"TSMC","date":"2021-03-24"`)
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, q, decoded)
+}
+
+func TestMonthly_JSON(t *testing.T) {
+	q := quote.Monthly{
+		Code:         "2454",
+		Year:         2020,
+		Month:        time.April,
+		High:         price.MustParse("415.50"),
+		Low:          price.MustParse("325.50"),
+		Volume:       218_553_058,
+		Transactions: 146_711,
+		Value:        80_262_421_295,
+	}
+
+	data, err := json.Marshal(q)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"code":"2454","year":2020,"month":4,"high":415.50,"low":325.50,"volume":218553058,`+
+		`"transactions":146711,"value":80262421295}`, string(data))
+
+	var decoded quote.Monthly
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, q, decoded)
+	assert.Equal(t, calendar.Date(2020, time.April, 1), decoded.Date())
+}
+
+func TestYearly_JSON(t *testing.T) {
+	q := quote.Yearly{
+		Code:         "0050",
+		Year:         2020,
+		High:         price.MustParse("122.40"),
+		Low:          price.MustParse("67.25"),
+		DateOfHigh:   calendar.Date(2020, time.December, 31),
+		DateOfLow:    calendar.Date(2020, time.March, 19),
+		Volume:       2_564_396_277,
+		Transactions: 1_413_186,
+		Value:        234_459_163_641,
+	}
+
+	data, err := json.Marshal(q)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"code":"0050","year":2020,"high":122.40,"low":67.25,"date_of_high":"2020-12-31",`+
+		`"date_of_low":"2020-03-19","volume":2564396277,"transactions":1413186,"value":234459163641}`, string(data))
+
+	var decoded quote.Yearly
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, q, decoded)
+	assert.Equal(t, calendar.Date(2020, time.January, 1), decoded.Date())
+
+	assert.NotNil(t, json.Unmarshal([]byte(`{"code":"0050","date_of_low":"2020-13-01"}`), &decoded))
+}
+
+func TestCombineTrades(t *testing.T) {
+	date := calendar.Date(2021, 3, 24)
+
+	q := quote.Day{Code: "2330", Date: date, Volume: 100, Transactions: 10, Value: 57_600}
+	combined := quote.CombineTrades(q,
+		quote.Trade{Kind: quote.TradeKindOddLot, Code: "2330", Date: date, Volume: 5, Transactions: 2, Value: 2_880},
+		quote.Trade{Kind: quote.TradeKindBlock, Code: "2330", Date: date.AddDate(0, 0, 1), Volume: 9, Transactions: 1, Value: 9},
+		quote.Trade{Kind: quote.TradeKindBlock, Code: "2454", Date: date, Volume: 9, Transactions: 1, Value: 9},
+	)
+
+	assert.Equal(t, quote.Day{Code: "2330", Date: date, Volume: 105, Transactions: 12, Value: 60_480}, combined)
+	assert.Equal(t, uint64(100), q.Volume, "q should not be modified")
+}
@@ -0,0 +1,51 @@
+package quote
+
+import (
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+)
+
+// Kinds of Trade.
+const (
+	TradeKindOddLot     = "OddLot"
+	TradeKindAfterHours = "AfterHours"
+	TradeKindBlock      = "Block"
+)
+
+// Trade is a trading record made outside the regular session, e.g. odd-lot trading, after-hours
+// fixed-price trading and block trading, returned by the Fetch functions of trades of the twse and tpex
+// clients. twse.Trade and tpex.Trade are aliases of it.
+type Trade struct {
+	// Kind is one of TradeKindOddLot, TradeKindAfterHours and TradeKindBlock.
+	Kind string
+	Code string
+	Name string
+	Date time.Time
+	// Method is the matching method of a block trade reported by the exchange, e.g. 配對交易 and
+	// 逐筆交易. It is empty for other kinds of trades.
+	Method string
+
+	Volume       uint64
+	Transactions uint64
+	Value        uint64
+	Price        price.Price
+}
+
+// CombineTrades returns a copy of q whose Volume, Transactions and Value are combined with those of the
+// trades of the same code and date, so that the totals match the ones reported by brokers. Trades of
+// other codes or dates are ignored. It is for the quotes of the TWSE only, since the ones of the TPEx
+// already include the trades; use twse.CombineTrades and tpex.CombineTrades to tell them apart.
+func CombineTrades(q Day, trades ...Trade) Day {
+	for _, t := range trades {
+		if t.Code != q.Code || !t.Date.Equal(q.Date) {
+			continue
+		}
+
+		q.Volume += t.Volume
+		q.Transactions += t.Transactions
+		q.Value += t.Value
+	}
+
+	return q
+}
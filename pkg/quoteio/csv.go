@@ -0,0 +1,173 @@
+package quoteio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+)
+
+func formatPrice(p price.Price) string {
+	if p == 0 {
+		return ""
+	}
+	return p.String()
+}
+
+func parsePrice(s string) (price.Price, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return price.Parse(s)
+}
+
+func (r record) csvRow() []string {
+	return []string{
+		r.Code,
+		r.Name,
+		r.EnglishName,
+		r.Date,
+		formatPrice(r.Open),
+		formatPrice(r.High),
+		formatPrice(r.Low),
+		formatPrice(r.Close),
+		strconv.FormatUint(r.Volume, 10),
+		strconv.FormatUint(r.Transactions, 10),
+		strconv.FormatUint(r.Value, 10),
+		r.DateOfHigh,
+		r.DateOfLow,
+	}
+}
+
+// WriteCSV writes quotes as CSV with a header line of Columns. quotes must be one of the types listed
+// in the package document.
+func WriteCSV(w io.Writer, quotes interface{}) error {
+	rows, err := toRows(quotes)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Columns); err != nil {
+		return err
+	}
+
+	for _, r := range rows {
+		if err := cw.Write(newRecord(r).csvRow()); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV reads the CSV written by WriteCSV into quotes, which must be a pointer to one of the types
+// listed in the package document. The columns are matched by the header line, so they can be in any
+// order, but all of Columns must be present except english_name.
+func ReadCSV(r io.Reader, quotes interface{}) error {
+	cr := csv.NewReader(r)
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("missing header line")
+		}
+		return err
+	}
+
+	indices := map[string]int{}
+	for i, column := range header {
+		indices[column] = i
+	}
+	known := 0
+	for _, column := range Columns {
+		if _, ok := indices[column]; ok {
+			known++
+		} else if !optionalColumns[column] {
+			return fmt.Errorf("missing column '%s' in header %v", column, header)
+		}
+	}
+	if len(header) != known {
+		return fmt.Errorf("header %v has unknown or duplicate columns", header)
+	}
+
+	rows := make([]row, 0)
+	for {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		rec, err := parseCSVRow(fields, indices)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", len(rows)+1, err)
+		}
+
+		r, err := rec.row()
+		if err != nil {
+			return fmt.Errorf("record %d: %w", len(rows)+1, err)
+		}
+		rows = append(rows, r)
+	}
+
+	return fromRows(rows, quotes)
+}
+
+func parseCSVRow(fields []string, indices map[string]int) (record, error) {
+	get := func(column string) string {
+		i, ok := indices[column]
+		if !ok {
+			return ""
+		}
+		return fields[i]
+	}
+
+	rec := record{
+		Code:        get("code"),
+		Name:        get("name"),
+		EnglishName: get("english_name"),
+		Date:        get("date"),
+		DateOfHigh:  get("date_of_high"),
+		DateOfLow:   get("date_of_low"),
+	}
+
+	for _, p := range []struct {
+		column string
+		target *price.Price
+	}{
+		{"open", &rec.Open},
+		{"high", &rec.High},
+		{"low", &rec.Low},
+		{"close", &rec.Close},
+	} {
+		v, err := parsePrice(get(p.column))
+		if err != nil {
+			return record{}, fmt.Errorf("column '%s': %w", p.column, err)
+		}
+		*p.target = v
+	}
+
+	for _, n := range []struct {
+		column string
+		target *uint64
+	}{
+		{"volume", &rec.Volume},
+		{"transactions", &rec.Transactions},
+		{"value", &rec.Value},
+	} {
+		v, err := strconv.ParseUint(get(n.column), 10, 64)
+		if err != nil {
+			return record{}, fmt.Errorf("column '%s': %w", n.column, err)
+		}
+		*n.target = v
+	}
+
+	return rec, nil
+}
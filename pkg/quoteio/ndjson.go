@@ -0,0 +1,61 @@
+package quoteio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// WriteNDJSON writes quotes as newline-delimited JSON, one object per line. quotes must be one of the
+// types listed in the package document.
+func WriteNDJSON(w io.Writer, quotes interface{}) error {
+	rows, err := toRows(quotes)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, r := range rows {
+		if err := encoder.Encode(newRecord(r)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadNDJSON reads the newline-delimited JSON written by WriteNDJSON into quotes, which must be a
+// pointer to one of the types listed in the package document. Blank lines are skipped and unknown keys
+// are rejected.
+func ReadNDJSON(r io.Reader, quotes interface{}) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	rows := make([]row, 0)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.DisallowUnknownFields()
+
+		var rec record
+		if err := decoder.Decode(&rec); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		r, err := rec.row()
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		rows = append(rows, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return fromRows(rows, quotes)
+}
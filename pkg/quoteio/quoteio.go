@@ -0,0 +1,398 @@
+// Package quoteio reads and writes quotes as CSV and newline-delimited JSON (NDJSON), so that datasets
+// can round-trip between crawlers and analysis tools.
+//
+// Any of []quote.Day, map[string]quote.Day, []quote.Monthly and []quote.Yearly can be written, and read
+// back into a pointer to any of them:
+//
+//     qs, _ := client.FetchDayQuotes(date)
+//     _ = quoteio.WriteCSV(f, qs)
+//
+//     var restored map[string]twse.DayQuote
+//     _ = quoteio.ReadCSV(f, &restored)
+//
+// The slices and maps of the deprecated twse.Quote and tpex.Quote are supported as well.
+//
+// Schema
+//
+// Both formats share the columns below, in this order. Maps are written in ascending order of codes.
+//
+//     code          stock code, e.g. 2330
+//     name          stock name, empty if unknown
+//     english_name  English stock name, empty if unknown
+//     date          YYYY-MM-DD
+//     open          decimal price, e.g. 576.00
+//     high          decimal price
+//     low           decimal price
+//     close         decimal price
+//     volume        number of shares
+//     transactions  number of transactions
+//     value         trade value in NTD
+//     date_of_high  YYYY-MM-DD, empty if not applicable
+//     date_of_low   YYYY-MM-DD, empty if not applicable
+//
+// A zero price, which means no transactions were made or the price is not applicable, e.g. the open of
+// a monthly quote, is written as an empty CSV cell and omitted in NDJSON. Empty names and dates are
+// treated the same way. english_name is optional when reading, since it was added later. Dates are read back as the midnight in Asia/Taipei, see calendar.Date. The date
+// of a monthly or yearly quote is the first day of the month or the year.
+package quoteio
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// Columns is the schema shared by CSV and NDJSON.
+var Columns = []string{
+	"code", "name", "english_name", "date", "open", "high", "low", "close", "volume", "transactions", "value",
+	"date_of_high", "date_of_low",
+}
+
+// optionalColumns are the columns of Columns that can be missing when reading.
+var optionalColumns = map[string]bool{"english_name": true}
+
+const dateLayout = "2006-01-02"
+
+// record is a row encoded in both formats.
+type record struct {
+	Code         string      `json:"code"`
+	Name         string      `json:"name,omitempty"`
+	EnglishName  string      `json:"english_name,omitempty"`
+	Date         string      `json:"date,omitempty"`
+	Open         price.Price `json:"open,omitempty"`
+	High         price.Price `json:"high,omitempty"`
+	Low          price.Price `json:"low,omitempty"`
+	Close        price.Price `json:"close,omitempty"`
+	Volume       uint64      `json:"volume"`
+	Transactions uint64      `json:"transactions"`
+	Value        uint64      `json:"value"`
+	DateOfHigh   string      `json:"date_of_high,omitempty"`
+	DateOfLow    string      `json:"date_of_low,omitempty"`
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(dateLayout)
+}
+
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is not a date like 2021-03-24: %w", s, err)
+	}
+
+	return calendar.Normalize(t), nil
+}
+
+// row is the quote of any granularity.
+type row struct {
+	Code         string
+	Name         string
+	EnglishName  string
+	Date         time.Time
+	Volume       uint64
+	Transactions uint64
+	Value        uint64
+	High         price.Price
+	Low          price.Price
+	Open         price.Price
+	Close        price.Price
+	DateOfHigh   time.Time
+	DateOfLow    time.Time
+}
+
+func rowFromDay(q quote.Day) row {
+	return row{
+		Code:         q.Code,
+		Name:         q.Name,
+		EnglishName:  q.EnglishName,
+		Date:         q.Date,
+		Volume:       q.Volume,
+		Transactions: q.Transactions,
+		Value:        q.Value,
+		High:         q.High,
+		Low:          q.Low,
+		Open:         q.Open,
+		Close:        q.Close,
+	}
+}
+
+// rowFromQuote converts the deprecated twse.Quote, which tpex.Quote can be converted to directly.
+func rowFromQuote(q twse.Quote) row {
+	return row{
+		Code:         q.Code,
+		Name:         q.Name,
+		Date:         q.Date,
+		Volume:       q.Volume,
+		Transactions: q.Transactions,
+		Value:        q.Value,
+		High:         q.High,
+		Low:          q.Low,
+		Open:         q.Open,
+		Close:        q.Close,
+		DateOfHigh:   q.DateOfHigh,
+		DateOfLow:    q.DateOfLow,
+	}
+}
+
+func rowFromMonthly(q quote.Monthly) row {
+	return row{
+		Code:         q.Code,
+		Name:         q.Name,
+		EnglishName:  q.EnglishName,
+		Date:         q.Date(),
+		Volume:       q.Volume,
+		Transactions: q.Transactions,
+		Value:        q.Value,
+		High:         q.High,
+		Low:          q.Low,
+	}
+}
+
+func rowFromYearly(q quote.Yearly) row {
+	return row{
+		Code:         q.Code,
+		Name:         q.Name,
+		EnglishName:  q.EnglishName,
+		Date:         q.Date(),
+		Volume:       q.Volume,
+		Transactions: q.Transactions,
+		Value:        q.Value,
+		High:         q.High,
+		Low:          q.Low,
+		DateOfHigh:   q.DateOfHigh,
+		DateOfLow:    q.DateOfLow,
+	}
+}
+
+func (r row) day() quote.Day {
+	return quote.Day{
+		Code:         r.Code,
+		Name:         r.Name,
+		EnglishName:  r.EnglishName,
+		Date:         r.Date,
+		Open:         r.Open,
+		High:         r.High,
+		Low:          r.Low,
+		Close:        r.Close,
+		Volume:       r.Volume,
+		Transactions: r.Transactions,
+		Value:        r.Value,
+	}
+}
+
+// quote converts r to the deprecated twse.Quote, which can be converted to tpex.Quote directly.
+func (r row) quote() twse.Quote {
+	return twse.Quote{
+		Code:         r.Code,
+		Name:         r.Name,
+		Date:         r.Date,
+		Volume:       r.Volume,
+		Transactions: r.Transactions,
+		Value:        r.Value,
+		High:         r.High,
+		Low:          r.Low,
+		Open:         r.Open,
+		Close:        r.Close,
+		DateOfHigh:   r.DateOfHigh,
+		DateOfLow:    r.DateOfLow,
+	}
+}
+
+func (r row) monthly() quote.Monthly {
+	return quote.Monthly{
+		Code:         r.Code,
+		Name:         r.Name,
+		EnglishName:  r.EnglishName,
+		Year:         r.Date.Year(),
+		Month:        r.Date.Month(),
+		High:         r.High,
+		Low:          r.Low,
+		Volume:       r.Volume,
+		Transactions: r.Transactions,
+		Value:        r.Value,
+	}
+}
+
+func (r row) yearly() quote.Yearly {
+	return quote.Yearly{
+		Code:         r.Code,
+		Name:         r.Name,
+		EnglishName:  r.EnglishName,
+		Year:         r.Date.Year(),
+		High:         r.High,
+		Low:          r.Low,
+		DateOfHigh:   r.DateOfHigh,
+		DateOfLow:    r.DateOfLow,
+		Volume:       r.Volume,
+		Transactions: r.Transactions,
+		Value:        r.Value,
+	}
+}
+
+func newRecord(r row) record {
+	return record{
+		Code:         r.Code,
+		Name:         r.Name,
+		EnglishName:  r.EnglishName,
+		Date:         formatDate(r.Date),
+		Open:         r.Open,
+		High:         r.High,
+		Low:          r.Low,
+		Close:        r.Close,
+		Volume:       r.Volume,
+		Transactions: r.Transactions,
+		Value:        r.Value,
+		DateOfHigh:   formatDate(r.DateOfHigh),
+		DateOfLow:    formatDate(r.DateOfLow),
+	}
+}
+
+func (rec record) row() (row, error) {
+	r := row{
+		Code:         rec.Code,
+		Name:         rec.Name,
+		EnglishName:  rec.EnglishName,
+		Open:         rec.Open,
+		High:         rec.High,
+		Low:          rec.Low,
+		Close:        rec.Close,
+		Volume:       rec.Volume,
+		Transactions: rec.Transactions,
+		Value:        rec.Value,
+	}
+
+	var err error
+	if r.Date, err = parseDate(rec.Date); err != nil {
+		return row{}, err
+	}
+	if r.DateOfHigh, err = parseDate(rec.DateOfHigh); err != nil {
+		return row{}, err
+	}
+	if r.DateOfLow, err = parseDate(rec.DateOfLow); err != nil {
+		return row{}, err
+	}
+
+	return r, nil
+}
+
+func sortByCode(rows []row) {
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Code < rows[j].Code })
+}
+
+// toRows converts the supported types to rows. Maps are converted in ascending order of codes.
+func toRows(v interface{}) ([]row, error) {
+	var rows []row
+
+	switch qs := v.(type) {
+	case []quote.Day:
+		rows = make([]row, len(qs))
+		for i := range qs {
+			rows[i] = rowFromDay(qs[i])
+		}
+	case map[string]quote.Day:
+		rows = make([]row, 0, len(qs))
+		for _, q := range qs {
+			rows = append(rows, rowFromDay(q))
+		}
+		sortByCode(rows)
+	case []quote.Monthly:
+		rows = make([]row, len(qs))
+		for i := range qs {
+			rows[i] = rowFromMonthly(qs[i])
+		}
+	case []quote.Yearly:
+		rows = make([]row, len(qs))
+		for i := range qs {
+			rows[i] = rowFromYearly(qs[i])
+		}
+	case []twse.Quote:
+		rows = make([]row, len(qs))
+		for i := range qs {
+			rows[i] = rowFromQuote(twse.Quote(qs[i]))
+		}
+	case []tpex.Quote:
+		rows = make([]row, len(qs))
+		for i := range qs {
+			rows[i] = rowFromQuote(twse.Quote(qs[i]))
+		}
+	case map[string]twse.Quote:
+		rows = make([]row, 0, len(qs))
+		for _, q := range qs {
+			rows = append(rows, rowFromQuote(twse.Quote(q)))
+		}
+		sortByCode(rows)
+	case map[string]tpex.Quote:
+		rows = make([]row, 0, len(qs))
+		for _, q := range qs {
+			rows = append(rows, rowFromQuote(twse.Quote(q)))
+		}
+		sortByCode(rows)
+	default:
+		return nil, fmt.Errorf("unsupported type %T", v)
+	}
+
+	return rows, nil
+}
+
+// fromRows stores rows into v, which is a pointer to one of the supported types.
+func fromRows(rows []row, v interface{}) error {
+	switch qs := v.(type) {
+	case *[]quote.Day:
+		*qs = make([]quote.Day, len(rows))
+		for i := range rows {
+			(*qs)[i] = rows[i].day()
+		}
+	case *map[string]quote.Day:
+		*qs = make(map[string]quote.Day, len(rows))
+		for _, r := range rows {
+			(*qs)[r.Code] = r.day()
+		}
+	case *[]quote.Monthly:
+		*qs = make([]quote.Monthly, len(rows))
+		for i := range rows {
+			(*qs)[i] = rows[i].monthly()
+		}
+	case *[]quote.Yearly:
+		*qs = make([]quote.Yearly, len(rows))
+		for i := range rows {
+			(*qs)[i] = rows[i].yearly()
+		}
+	case *[]twse.Quote:
+		*qs = make([]twse.Quote, len(rows))
+		for i := range rows {
+			(*qs)[i] = rows[i].quote()
+		}
+	case *[]tpex.Quote:
+		*qs = make([]tpex.Quote, len(rows))
+		for i := range rows {
+			(*qs)[i] = tpex.Quote(rows[i].quote())
+		}
+	case *map[string]twse.Quote:
+		*qs = make(map[string]twse.Quote, len(rows))
+		for _, r := range rows {
+			(*qs)[r.Code] = r.quote()
+		}
+	case *map[string]tpex.Quote:
+		*qs = make(map[string]tpex.Quote, len(rows))
+		for _, r := range rows {
+			(*qs)[r.Code] = tpex.Quote(r.quote())
+		}
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package quoteio_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quoteio"
+)
+
+var (
+	date = calendar.Date(2021, time.March, 24)
+
+	dayQuotes = map[string]twse.DayQuote{
+		"2330": {
+			Code:         "2330",
+			Name:         "台積電",
+			Date:         date,
+			Volume:       115_318_351,
+			Transactions: 242_138,
+			Value:        66_559_451_738,
+			Open:         price.MustParse("571.00"),
+			High:         price.MustParse("582.00"),
+			Low:          price.MustParse("571.00"),
+			Close:        price.MustParse("576.00"),
+		},
+		"00684R": {
+			Code: "00684R",
+			Name: "期元大美元指反1",
+			Date: date,
+		},
+	}
+
+	englishDayQuotes = []tpex.DayQuote{
+		{
+			Code:         "8044",
+			Name:         "網家",
+			EnglishName:  "PChome",
+			Date:         calendar.Date(2021, time.March, 30),
+			Volume:       766_431,
+			Transactions: 698,
+			Value:        67_759_797,
+			Open:         price.MustParse("89.40"),
+			High:         price.MustParse("90.00"),
+			Low:          price.MustParse("88.00"),
+			Close:        price.MustParse("88.00"),
+		},
+	}
+
+	monthlyQuotes = []tpex.MonthlyQuote{
+		{
+			Code:         "8044",
+			Year:         2021,
+			Month:        time.January,
+			Volume:       9_818_000,
+			Transactions: 6_713,
+			Value:        896_442_000,
+			High:         price.MustParse("98.60"),
+			Low:          price.MustParse("84.00"),
+		},
+	}
+
+	// Names of monthly and yearly quotes are filled by name resolvers.
+	namedMonthlyQuotes = []twse.MonthlyQuote{
+		{
+			Code:         "2454",
+			Name:         "聯發科",
+			EnglishName:  "MediaTek",
+			Year:         2020,
+			Month:        time.April,
+			Volume:       218_553_058,
+			Transactions: 146_711,
+			Value:        80_262_421_295,
+			High:         price.MustParse("415.50"),
+			Low:          price.MustParse("325.50"),
+		},
+	}
+
+	namedYearlyQuotes = []twse.YearlyQuote{
+		{
+			Code:         "0050",
+			Name:         "元大台灣50",
+			EnglishName:  "Yuanta Taiwan 50",
+			Year:         2020,
+			Volume:       2_564_396_277,
+			Transactions: 1_413_186,
+			Value:        234_459_163_641,
+			High:         price.MustParse("122.40"),
+			Low:          price.MustParse("67.25"),
+			DateOfHigh:   calendar.Date(2020, time.December, 31),
+			DateOfLow:    calendar.Date(2020, time.March, 19),
+		},
+	}
+
+	yearlyQuotes = []tpex.YearlyQuote{
+		{
+			Code:         "8044",
+			Year:         2005,
+			Volume:       296_356_000,
+			Transactions: 147_000,
+			Value:        14_075_258_000,
+			High:         price.MustParse("59.70"),
+			Low:          price.MustParse("28.20"),
+			DateOfHigh:   calendar.Date(2005, time.September, 16),
+			DateOfLow:    calendar.Date(2005, time.January, 24),
+		},
+	}
+)
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, quoteio.WriteCSV(&buf, dayQuotes))
+
+	assert.Equal(t, strings.Join([]string{
+		"code,name,english_name,date,open,high,low,close,volume,transactions,value,date_of_high,date_of_low",
+		"00684R,期元大美元指反1,,2021-03-24,,,,,0,0,0,,",
+		"2330,台積電,,2021-03-24,571.00,582.00,571.00,576.00,115318351,242138,66559451738,,",
+		"",
+	}, "\n"), buf.String())
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, quoteio.WriteNDJSON(&buf, yearlyQuotes))
+
+	assert.Equal(t, `{"code":"8044","date":"2005-01-01","high":59.70,"low":28.20,"volume":296356000,`+
+		`"transactions":147000,"value":14075258000,"date_of_high":"2005-09-16","date_of_low":"2005-01-24"}`+"\n",
+		buf.String())
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []struct {
+		name  string
+		write func(*bytes.Buffer, interface{}) error
+		read  func(*bytes.Buffer, interface{}) error
+	}{
+		{
+			"csv",
+			func(b *bytes.Buffer, v interface{}) error { return quoteio.WriteCSV(b, v) },
+			func(b *bytes.Buffer, v interface{}) error { return quoteio.ReadCSV(b, v) },
+		},
+		{
+			"ndjson",
+			func(b *bytes.Buffer, v interface{}) error { return quoteio.WriteNDJSON(b, v) },
+			func(b *bytes.Buffer, v interface{}) error { return quoteio.ReadNDJSON(b, v) },
+		},
+	} {
+		var buf bytes.Buffer
+		assert.Nil(t, format.write(&buf, dayQuotes))
+		var restoredDayQuotes map[string]twse.DayQuote
+		assert.Nil(t, format.read(&buf, &restoredDayQuotes))
+		assert.Equalf(t, dayQuotes, restoredDayQuotes, "%s", format.name)
+
+		buf.Reset()
+		assert.Nil(t, format.write(&buf, englishDayQuotes))
+		var restoredEnglishDayQuotes []tpex.DayQuote
+		assert.Nil(t, format.read(&buf, &restoredEnglishDayQuotes))
+		assert.Equalf(t, englishDayQuotes, restoredEnglishDayQuotes, "%s", format.name)
+
+		buf.Reset()
+		assert.Nil(t, format.write(&buf, monthlyQuotes))
+		var restoredMonthlyQuotes []tpex.MonthlyQuote
+		assert.Nil(t, format.read(&buf, &restoredMonthlyQuotes))
+		assert.Equalf(t, monthlyQuotes, restoredMonthlyQuotes, "%s", format.name)
+
+		buf.Reset()
+		assert.Nil(t, format.write(&buf, yearlyQuotes))
+		var restoredYearlyQuotes []tpex.YearlyQuote
+		assert.Nil(t, format.read(&buf, &restoredYearlyQuotes))
+		assert.Equalf(t, yearlyQuotes, restoredYearlyQuotes, "%s", format.name)
+
+		buf.Reset()
+		assert.Nil(t, format.write(&buf, namedMonthlyQuotes))
+		var restoredNamedMonthlyQuotes []twse.MonthlyQuote
+		assert.Nil(t, format.read(&buf, &restoredNamedMonthlyQuotes))
+		assert.Equalf(t, namedMonthlyQuotes, restoredNamedMonthlyQuotes, "%s", format.name)
+
+		buf.Reset()
+		assert.Nil(t, format.write(&buf, namedYearlyQuotes))
+		var restoredNamedYearlyQuotes []twse.YearlyQuote
+		assert.Nil(t, format.read(&buf, &restoredNamedYearlyQuotes))
+		assert.Equalf(t, namedYearlyQuotes, restoredNamedYearlyQuotes, "%s", format.name)
+
+		buf.Reset()
+		assert.Nil(t, format.write(&buf, []twse.DayQuote{}))
+		var restoredEmpty []twse.DayQuote
+		assert.Nil(t, format.read(&buf, &restoredEmpty))
+		assert.Equalf(t, []twse.DayQuote{}, restoredEmpty, "%s", format.name)
+	}
+}
+
+// TestReadCSVWithReorderedColumns also checks that english_name can be missing, as in the CSV written before
+// it was added.
+func TestReadCSVWithReorderedColumns(t *testing.T) {
+	input := strings.Join([]string{
+		"date,code,name,close,open,high,low,volume,transactions,value,date_of_high,date_of_low",
+		"2021-03-24,2330,台積電,576.00,571.00,582.00,571.00,115318351,242138,66559451738,,",
+	}, "\n")
+
+	var qs []twse.DayQuote
+	assert.Nil(t, quoteio.ReadCSV(strings.NewReader(input), &qs))
+	assert.Equal(t, []twse.DayQuote{dayQuotes["2330"]}, qs)
+}
+
+func TestDeprecatedQuote(t *testing.T) {
+	qs := map[string]twse.Quote{"2330": twse.QuoteFromDay(dayQuotes["2330"])}
+
+	var buf bytes.Buffer
+	assert.Nil(t, quoteio.WriteCSV(&buf, qs))
+
+	var restored []tpex.Quote
+	assert.Nil(t, quoteio.ReadCSV(&buf, &restored))
+	assert.Equal(t, []tpex.Quote{tpex.QuoteFromDay(dayQuotes["2330"])}, restored)
+}
+
+func TestReadErrors(t *testing.T) {
+	var qs []twse.DayQuote
+
+	assert.NotNil(t, quoteio.ReadCSV(strings.NewReader(""), &qs))
+	assert.NotNil(t, quoteio.ReadCSV(strings.NewReader("code,name,date\n"), &qs))
+	assert.NotNil(t, quoteio.ReadCSV(strings.NewReader(
+		"code,name,date,open,high,low,close,volume,transactions,value,date_of_high,date_of_low\n"+
+			"2330,台積電,2021/03/24,,,,,0,0,0,,\n"), &qs))
+	assert.NotNil(t, quoteio.ReadCSV(strings.NewReader(
+		"code,name,date,open,high,low,close,volume,transactions,value,date_of_high,date_of_low\n"+
+			"2330,台積電,2021-03-24,--,,,,0,0,0,,\n"), &qs))
+	assert.NotNil(t, quoteio.ReadNDJSON(strings.NewReader(`{"code":"2330","foo":1}`), &qs))
+	assert.NotNil(t, quoteio.ReadNDJSON(strings.NewReader(`{"code":"2330"}`), qs))
+	assert.NotNil(t, quoteio.WriteCSV(&bytes.Buffer{}, []string{"2330"}))
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+type memoryKey struct {
+	market string
+	code   string
+	date   string
+}
+
+// MemoryStore is a Store keeping quotes in memory. It is safe for concurrent use.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	days   map[memoryKey]quote.Day
+	months map[memoryKey]quote.Monthly
+	years  map[memoryKey]quote.Yearly
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		days:   map[memoryKey]quote.Day{},
+		months: map[memoryKey]quote.Monthly{},
+		years:  map[memoryKey]quote.Yearly{},
+	}
+}
+
+func (s *MemoryStore) PutDayQuotes(market string, qs []quote.Day) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, q := range qs {
+		q.Date = calendar.Normalize(q.Date)
+		k := memoryKey{market, q.Code, q.Date.Format(dateLayout)}
+		if stored, ok := s.days[k]; ok && q.Name == "" {
+			q.Name = stored.Name
+		}
+		s.days[k] = q
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) PutMonthlyQuotes(market string, qs []quote.Monthly) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, q := range qs {
+		s.months[memoryKey{market, q.Code, q.Date().Format(dateLayout)}] = q
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) PutYearlyQuotes(market string, qs []quote.Yearly) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, q := range qs {
+		s.years[memoryKey{market, q.Code, q.Date().Format(dateLayout)}] = q
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) DayQuotes(market, code string, from, to time.Time) ([]quote.Day, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	from, to = calendar.Normalize(from), calendar.Normalize(to)
+
+	qs := make([]quote.Day, 0)
+	for k, q := range s.days {
+		if k.market == market && k.code == code && !q.Date.Before(from) && !q.Date.After(to) {
+			qs = append(qs, q)
+		}
+	}
+	sort.Slice(qs, func(i, j int) bool { return qs[i].Date.Before(qs[j].Date) })
+
+	return qs, nil
+}
+
+func (s *MemoryStore) DayQuotesOn(market string, date time.Time) (map[string]quote.Day, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := calendar.Normalize(date).Format(dateLayout)
+
+	qs := map[string]quote.Day{}
+	for k, q := range s.days {
+		if k.market == market && k.date == d {
+			qs[q.Code] = q
+		}
+	}
+
+	return qs, nil
+}
+
+func (s *MemoryStore) MonthlyQuotes(market, code string, year int) ([]quote.Monthly, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	qs := make([]quote.Monthly, 0)
+	for k, q := range s.months {
+		if k.market == market && k.code == code && q.Year == year {
+			qs = append(qs, q)
+		}
+	}
+	sort.Slice(qs, func(i, j int) bool { return qs[i].Month < qs[j].Month })
+
+	return qs, nil
+}
+
+func (s *MemoryStore) YearlyQuotes(market, code string) ([]quote.Yearly, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	qs := make([]quote.Yearly, 0)
+	for k, q := range s.years {
+		if k.market == market && k.code == code {
+			qs = append(qs, q)
+		}
+	}
+	sort.Slice(qs, func(i, j int) bool { return qs[i].Year < qs[j].Year })
+
+	return qs, nil
+}
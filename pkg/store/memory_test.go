@@ -0,0 +1,101 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+	"github.com/chehsunliu/tshakutshai/pkg/store"
+)
+
+func dayQuote(code, name string, date time.Time, close string) quote.Day {
+	p := price.MustParse(close)
+	return quote.Day{
+		Code: code, Name: name, Date: date, Open: p, High: p, Low: p, Close: p, Volume: 1000, Transactions: 3,
+		Value: uint64(p.Units() / 10),
+	}
+}
+
+func TestMemoryStore_DayQuotes(t *testing.T) {
+	testDayQuotes(t, store.NewMemoryStore())
+}
+
+func TestMemoryStore_MonthlyAndYearlyQuotes(t *testing.T) {
+	testMonthlyAndYearlyQuotes(t, store.NewMemoryStore())
+}
+
+// testDayQuotes checks the day quotes of an empty s. It is shared by the tests of all Store
+// implementations.
+func testDayQuotes(t *testing.T, s store.Store) {
+	d1, d2, d3 := calendar.Date(2021, 3, 24), calendar.Date(2021, 3, 25), calendar.Date(2021, 3, 26)
+	assert.Nil(t, s.PutDayQuotes(store.MarketTWSE, []quote.Day{
+		dayQuote("2330", "台積電", d2, "580.00"),
+		dayQuote("2330", "台積電", d1, "576.00"),
+		dayQuote("0050", "元大台灣50", d1, "131.50"),
+	}))
+	assert.Nil(t, s.PutDayQuotes(store.MarketTPEx, []quote.Day{dayQuote("8044", "網家", d1, "82.30")}))
+
+	// Daily quotes of the TWSE come without names, which must not erase the stored ones.
+	assert.Nil(t, s.PutDayQuotes(store.MarketTWSE, []quote.Day{
+		dayQuote("2330", "", d2, "581.00"),
+		dayQuote("2330", "", d3, "590.00"),
+	}))
+
+	qs, err := s.DayQuotes(store.MarketTWSE, "2330", d1, d3)
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Day{
+		dayQuote("2330", "台積電", d1, "576.00"),
+		dayQuote("2330", "台積電", d2, "581.00"),
+		dayQuote("2330", "", d3, "590.00"),
+	}, qs)
+
+	qs, err = s.DayQuotes(store.MarketTWSE, "2330", time.Date(2021, 3, 25, 0, 0, 0, 0, time.UTC), d2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(qs))
+
+	m, err := s.DayQuotesOn(store.MarketTWSE, d1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(m))
+	assert.Equal(t, "元大台灣50", m["0050"].Name)
+
+	m, err = s.DayQuotesOn(store.MarketTPEx, d2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(m))
+}
+
+// testMonthlyAndYearlyQuotes checks the monthly and yearly quotes of an empty s.
+func testMonthlyAndYearlyQuotes(t *testing.T, s store.Store) {
+	assert.Nil(t, s.PutMonthlyQuotes(store.MarketTPEx, []quote.Monthly{
+		{Code: "8044", Year: 2021, Month: time.February, High: price.MustParse("99.00")},
+		{Code: "8044", Year: 2021, Month: time.January, High: price.MustParse("98.60")},
+		{Code: "8044", Year: 2020, Month: time.December, High: price.MustParse("97.00")},
+	}))
+	assert.Nil(t, s.PutMonthlyQuotes(store.MarketTPEx, []quote.Monthly{
+		{Code: "8044", Year: 2021, Month: time.February, High: price.MustParse("99.50")},
+	}))
+
+	monthly, err := s.MonthlyQuotes(store.MarketTPEx, "8044", 2021)
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Monthly{
+		{Code: "8044", Year: 2021, Month: time.January, High: price.MustParse("98.60")},
+		{Code: "8044", Year: 2021, Month: time.February, High: price.MustParse("99.50")},
+	}, monthly)
+
+	yearly := []quote.Yearly{
+		{Code: "0050", Year: 2019, DateOfHigh: calendar.Date(2019, 12, 18), DateOfLow: calendar.Date(2019, 1, 4)},
+		{Code: "0050", Year: 2020, DateOfHigh: calendar.Date(2020, 12, 31), DateOfLow: calendar.Date(2020, 3, 19)},
+	}
+	assert.Nil(t, s.PutYearlyQuotes(store.MarketTWSE, []quote.Yearly{yearly[1], yearly[0]}))
+
+	qs, err := s.YearlyQuotes(store.MarketTWSE, "0050")
+	assert.Nil(t, err)
+	assert.Equal(t, yearly, qs)
+
+	qs, err = s.YearlyQuotes(store.MarketTPEx, "0050")
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Yearly{}, qs)
+}
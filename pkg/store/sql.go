@@ -0,0 +1,369 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+const dateLayout = "2006-01-02"
+
+// Dialect is the SQL flavor of a database. Only the features shared by SQLite 3.24+ and PostgreSQL 9.5+
+// are used, e.g. INSERT ... ON CONFLICT DO UPDATE.
+type Dialect struct {
+	name        string
+	placeholder func(n int) string
+	dateType    string
+}
+
+// Dialects supported by SQLStore.
+var (
+	SQLite = Dialect{
+		name:        "sqlite",
+		placeholder: func(int) string { return "?" },
+		dateType:    "TEXT",
+	}
+	PostgreSQL = Dialect{
+		name:        "postgres",
+		placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		dateType:    "DATE",
+	}
+)
+
+func (d Dialect) String() string {
+	return d.name
+}
+
+// placeholders returns the placeholders of the n parameters of a statement, separated by commas.
+func (d Dialect) placeholders(n int) string {
+	ps := make([]string, n)
+	for i := range ps {
+		ps[i] = d.placeholder(i + 1)
+	}
+	return strings.Join(ps, ", ")
+}
+
+// SQLStore is a Store backed by database/sql. Call CreateSchema once before use.
+type SQLStore struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewSQLStore returns a SQLStore using db, which must be opened with a driver of dialect.
+func NewSQLStore(db *sql.DB, dialect Dialect) *SQLStore {
+	return &SQLStore{db: db, dialect: dialect}
+}
+
+func (s *SQLStore) schema() []string {
+	date := s.dialect.dateType
+	return []string{
+		`CREATE TABLE IF NOT EXISTS day_quotes (
+	market TEXT NOT NULL,
+	code TEXT NOT NULL,
+	date ` + date + ` NOT NULL,
+	name TEXT NOT NULL DEFAULT '',
+	open NUMERIC(18, 4) NOT NULL,
+	high NUMERIC(18, 4) NOT NULL,
+	low NUMERIC(18, 4) NOT NULL,
+	close NUMERIC(18, 4) NOT NULL,
+	volume BIGINT NOT NULL,
+	transactions BIGINT NOT NULL,
+	value BIGINT NOT NULL,
+	PRIMARY KEY (market, code, date)
+)`,
+		`CREATE INDEX IF NOT EXISTS day_quotes_market_date ON day_quotes (market, date)`,
+		`CREATE TABLE IF NOT EXISTS monthly_quotes (
+	market TEXT NOT NULL,
+	code TEXT NOT NULL,
+	year INTEGER NOT NULL,
+	month INTEGER NOT NULL,
+	high NUMERIC(18, 4) NOT NULL,
+	low NUMERIC(18, 4) NOT NULL,
+	volume BIGINT NOT NULL,
+	transactions BIGINT NOT NULL,
+	value BIGINT NOT NULL,
+	PRIMARY KEY (market, code, year, month)
+)`,
+		`CREATE TABLE IF NOT EXISTS yearly_quotes (
+	market TEXT NOT NULL,
+	code TEXT NOT NULL,
+	year INTEGER NOT NULL,
+	high NUMERIC(18, 4) NOT NULL,
+	low NUMERIC(18, 4) NOT NULL,
+	date_of_high ` + date + `,
+	date_of_low ` + date + `,
+	volume BIGINT NOT NULL,
+	transactions BIGINT NOT NULL,
+	value BIGINT NOT NULL,
+	PRIMARY KEY (market, code, year)
+)`,
+	}
+}
+
+// CreateSchema creates the tables and indexes if they do not exist.
+func (s *SQLStore) CreateSchema() error {
+	for _, stmt := range s.schema() {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// put executes query once per args in a transaction.
+func (s *SQLStore) put(query string, args [][]interface{}) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, a := range args {
+		if _, err := stmt.Exec(a...); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func dateValue(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return calendar.Normalize(t).Format(dateLayout)
+}
+
+// parseDateValue parses a date scanned as a string. PostgreSQL drivers return DATE as time.Time, which
+// database/sql formats as RFC 3339, so only the leading YYYY-MM-DD is considered.
+func parseDateValue(v sql.NullString) (time.Time, error) {
+	if !v.Valid || v.String == "" {
+		return time.Time{}, nil
+	}
+
+	s := v.String
+	if len(s) > len(dateLayout) {
+		s = s[:len(dateLayout)]
+	}
+
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is not a date: %w", v.String, err)
+	}
+
+	return calendar.Date(t.Year(), t.Month(), t.Day()), nil
+}
+
+func (s *SQLStore) PutDayQuotes(market string, qs []quote.Day) error {
+	query := `INSERT INTO day_quotes (market, code, date, name, open, high, low, close, volume, transactions, value)
+VALUES (` + s.dialect.placeholders(11) + `)
+ON CONFLICT (market, code, date) DO UPDATE SET
+	name = CASE WHEN excluded.name <> '' THEN excluded.name ELSE day_quotes.name END,
+	open = excluded.open,
+	high = excluded.high,
+	low = excluded.low,
+	close = excluded.close,
+	volume = excluded.volume,
+	transactions = excluded.transactions,
+	value = excluded.value`
+
+	args := make([][]interface{}, len(qs))
+	for i, q := range qs {
+		args[i] = []interface{}{
+			market, q.Code, dateValue(q.Date), q.Name, q.Open, q.High, q.Low, q.Close, q.Volume, q.Transactions,
+			q.Value,
+		}
+	}
+
+	if err := s.put(query, args); err != nil {
+		return fmt.Errorf("failed to put day quotes: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) PutMonthlyQuotes(market string, qs []quote.Monthly) error {
+	query := `INSERT INTO monthly_quotes (market, code, year, month, high, low, volume, transactions, value)
+VALUES (` + s.dialect.placeholders(9) + `)
+ON CONFLICT (market, code, year, month) DO UPDATE SET
+	high = excluded.high,
+	low = excluded.low,
+	volume = excluded.volume,
+	transactions = excluded.transactions,
+	value = excluded.value`
+
+	args := make([][]interface{}, len(qs))
+	for i, q := range qs {
+		args[i] = []interface{}{
+			market, q.Code, q.Year, int(q.Month), q.High, q.Low, q.Volume, q.Transactions, q.Value,
+		}
+	}
+
+	if err := s.put(query, args); err != nil {
+		return fmt.Errorf("failed to put monthly quotes: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) PutYearlyQuotes(market string, qs []quote.Yearly) error {
+	query := `INSERT INTO yearly_quotes (market, code, year, high, low, date_of_high, date_of_low, volume, transactions, value)
+VALUES (` + s.dialect.placeholders(10) + `)
+ON CONFLICT (market, code, year) DO UPDATE SET
+	high = excluded.high,
+	low = excluded.low,
+	date_of_high = excluded.date_of_high,
+	date_of_low = excluded.date_of_low,
+	volume = excluded.volume,
+	transactions = excluded.transactions,
+	value = excluded.value`
+
+	args := make([][]interface{}, len(qs))
+	for i, q := range qs {
+		args[i] = []interface{}{
+			market, q.Code, q.Year, q.High, q.Low, dateValue(q.DateOfHigh), dateValue(q.DateOfLow), q.Volume,
+			q.Transactions, q.Value,
+		}
+	}
+
+	if err := s.put(query, args); err != nil {
+		return fmt.Errorf("failed to put yearly quotes: %w", err)
+	}
+	return nil
+}
+
+const dayColumns = "code, name, date, open, high, low, close, volume, transactions, value"
+
+func (s *SQLStore) queryDayQuotes(query string, args ...interface{}) ([]quote.Day, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	qs := make([]quote.Day, 0)
+	for rows.Next() {
+		var q quote.Day
+		var date sql.NullString
+		if err := rows.Scan(
+			&q.Code, &q.Name, &date, &q.Open, &q.High, &q.Low, &q.Close, &q.Volume, &q.Transactions, &q.Value,
+		); err != nil {
+			return nil, err
+		}
+		if q.Date, err = parseDateValue(date); err != nil {
+			return nil, err
+		}
+		qs = append(qs, q)
+	}
+
+	return qs, rows.Err()
+}
+
+func (s *SQLStore) DayQuotes(market, code string, from, to time.Time) ([]quote.Day, error) {
+	p := s.dialect.placeholder
+	query := `SELECT ` + dayColumns + ` FROM day_quotes
+WHERE market = ` + p(1) + ` AND code = ` + p(2) + ` AND date >= ` + p(3) + ` AND date <= ` + p(4) + `
+ORDER BY date`
+
+	qs, err := s.queryDayQuotes(query, market, code, dateValue(from), dateValue(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query day quotes: %w", err)
+	}
+	return qs, nil
+}
+
+func (s *SQLStore) DayQuotesOn(market string, date time.Time) (map[string]quote.Day, error) {
+	p := s.dialect.placeholder
+	query := `SELECT ` + dayColumns + ` FROM day_quotes WHERE market = ` + p(1) + ` AND date = ` + p(2)
+
+	qs, err := s.queryDayQuotes(query, market, dateValue(date))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query day quotes: %w", err)
+	}
+
+	m := make(map[string]quote.Day, len(qs))
+	for _, q := range qs {
+		m[q.Code] = q
+	}
+	return m, nil
+}
+
+func (s *SQLStore) MonthlyQuotes(market, code string, year int) ([]quote.Monthly, error) {
+	p := s.dialect.placeholder
+	query := `SELECT code, year, month, high, low, volume, transactions, value FROM monthly_quotes
+WHERE market = ` + p(1) + ` AND code = ` + p(2) + ` AND year = ` + p(3) + `
+ORDER BY month`
+
+	rows, err := s.db.Query(query, market, code, year)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query monthly quotes: %w", err)
+	}
+	defer rows.Close()
+
+	qs := make([]quote.Monthly, 0)
+	for rows.Next() {
+		var q quote.Monthly
+		var month int
+		if err := rows.Scan(&q.Code, &q.Year, &month, &q.High, &q.Low, &q.Volume, &q.Transactions, &q.Value); err != nil {
+			return nil, fmt.Errorf("failed to query monthly quotes: %w", err)
+		}
+		q.Month = time.Month(month)
+		qs = append(qs, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query monthly quotes: %w", err)
+	}
+
+	return qs, nil
+}
+
+func (s *SQLStore) YearlyQuotes(market, code string) ([]quote.Yearly, error) {
+	p := s.dialect.placeholder
+	query := `SELECT code, year, high, low, date_of_high, date_of_low, volume, transactions, value FROM yearly_quotes
+WHERE market = ` + p(1) + ` AND code = ` + p(2) + `
+ORDER BY year`
+
+	rows, err := s.db.Query(query, market, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query yearly quotes: %w", err)
+	}
+	defer rows.Close()
+
+	qs := make([]quote.Yearly, 0)
+	for rows.Next() {
+		var q quote.Yearly
+		var dateOfHigh, dateOfLow sql.NullString
+		if err := rows.Scan(
+			&q.Code, &q.Year, &q.High, &q.Low, &dateOfHigh, &dateOfLow, &q.Volume, &q.Transactions, &q.Value,
+		); err != nil {
+			return nil, fmt.Errorf("failed to query yearly quotes: %w", err)
+		}
+		if q.DateOfHigh, err = parseDateValue(dateOfHigh); err != nil {
+			return nil, fmt.Errorf("failed to query yearly quotes: %w", err)
+		}
+		if q.DateOfLow, err = parseDateValue(dateOfLow); err != nil {
+			return nil, fmt.Errorf("failed to query yearly quotes: %w", err)
+		}
+		qs = append(qs, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query yearly quotes: %w", err)
+	}
+
+	return qs, nil
+}
+
@@ -0,0 +1,53 @@
+//go:build sqlite
+// +build sqlite
+
+// The tests against a real SQLite engine, run with
+//
+//     go get github.com/mattn/go-sqlite3
+//     go test -tags sqlite ./pkg/store/...
+
+package store_test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/store"
+)
+
+func newSQLiteStore(t *testing.T) *store.SQLStore {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "quotes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	s := store.NewSQLStore(db, store.SQLite)
+	if err := s.CreateSchema(); err != nil {
+		t.Fatal(err)
+	}
+	// Creating the schema again is a no-op.
+	assert.Nil(t, s.CreateSchema())
+
+	return s
+}
+
+func TestSQLStore_SQLiteDayQuotes(t *testing.T) {
+	testDayQuotes(t, newSQLiteStore(t))
+}
+
+func TestSQLStore_SQLiteMonthlyAndYearlyQuotes(t *testing.T) {
+	testMonthlyAndYearlyQuotes(t, newSQLiteStore(t))
+}
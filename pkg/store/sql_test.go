@@ -0,0 +1,248 @@
+package store_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+	"github.com/chehsunliu/tshakutshai/pkg/store"
+)
+
+// fakeDriver records the statements executed and answers queries with canned rows, since no SQL driver
+// is vendored.
+type fakeDriver struct {
+	mu      sync.Mutex
+	execs   []fakeCall
+	queries []fakeCall
+	results [][][]driver.Value
+	commits int
+}
+
+type fakeCall struct {
+	query string
+	args  []driver.Value
+}
+
+var (
+	fakeDrivers     = map[string]*fakeDriver{}
+	fakeDriversLock sync.Mutex
+)
+
+func init() {
+	sql.Register("store-fake", fakeConnector{})
+}
+
+type fakeConnector struct{}
+
+func (fakeConnector) Open(name string) (driver.Conn, error) {
+	fakeDriversLock.Lock()
+	defer fakeDriversLock.Unlock()
+	return &fakeConn{d: fakeDrivers[name]}, nil
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{d: c.d, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.commits++
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	return nil
+}
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.execs = append(s.d.execs, fakeCall{s.query, args})
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.queries = append(s.d.queries, fakeCall{s.query, args})
+
+	rows := s.d.results[0]
+	s.d.results = s.d.results[1:]
+	return &fakeRows{rows: rows}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return []string{}
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func newFakeStore(t *testing.T, dialect store.Dialect, results ...[][]driver.Value) (*store.SQLStore, *fakeDriver) {
+	d := &fakeDriver{results: results}
+
+	fakeDriversLock.Lock()
+	fakeDrivers[t.Name()] = d
+	fakeDriversLock.Unlock()
+
+	db, err := sql.Open("store-fake", t.Name())
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return store.NewSQLStore(db, dialect), d
+}
+
+func TestSQLStore_CreateSchema(t *testing.T) {
+	s, d := newFakeStore(t, store.PostgreSQL)
+
+	assert.Nil(t, s.CreateSchema())
+	assert.Equal(t, 4, len(d.execs))
+	assert.Contains(t, d.execs[0].query, "CREATE TABLE IF NOT EXISTS day_quotes")
+	assert.Contains(t, d.execs[0].query, "date DATE NOT NULL")
+	assert.Contains(t, d.execs[0].query, "PRIMARY KEY (market, code, date)")
+	assert.Contains(t, d.execs[2].query, "PRIMARY KEY (market, code, year, month)")
+	assert.Contains(t, d.execs[3].query, "PRIMARY KEY (market, code, year)")
+
+	s, d = newFakeStore(t, store.SQLite)
+	assert.Nil(t, s.CreateSchema())
+	assert.Contains(t, d.execs[0].query, "date TEXT NOT NULL")
+}
+
+func TestSQLStore_PutDayQuotes(t *testing.T) {
+	s, d := newFakeStore(t, store.PostgreSQL)
+
+	q := dayQuote("2330", "台積電", calendar.Date(2021, 3, 24), "576.00")
+	assert.Nil(t, s.PutDayQuotes(store.MarketTWSE, []quote.Day{q, q}))
+
+	assert.Equal(t, 1, d.commits)
+	assert.Equal(t, 2, len(d.execs))
+	assert.Contains(t, d.execs[0].query, "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)")
+	assert.Contains(t, d.execs[0].query, "ON CONFLICT (market, code, date) DO UPDATE SET")
+	assert.Equal(t, []driver.Value{
+		"twse", "2330", "2021-03-24", "台積電", "576.00", "576.00", "576.00", "576.00", int64(1000), int64(3),
+		int64(576000),
+	}, d.execs[0].args)
+
+	assert.Nil(t, s.PutDayQuotes(store.MarketTWSE, nil))
+	assert.Equal(t, 1, d.commits)
+}
+
+func TestSQLStore_PutYearlyQuotes(t *testing.T) {
+	s, d := newFakeStore(t, store.SQLite)
+
+	assert.Nil(t, s.PutYearlyQuotes(store.MarketTPEx, []quote.Yearly{{
+		Code:       "8044",
+		Year:       2005,
+		High:       price.MustParse("59.70"),
+		Low:        price.MustParse("28.20"),
+		DateOfHigh: calendar.Date(2005, time.September, 16),
+	}}))
+
+	assert.Contains(t, d.execs[0].query, "VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	assert.Equal(t, []driver.Value{
+		"tpex", "8044", int64(2005), "59.70", "28.20", "2005-09-16", nil, int64(0), int64(0), int64(0),
+	}, d.execs[0].args)
+}
+
+func TestSQLStore_DayQuotes(t *testing.T) {
+	s, d := newFakeStore(t, store.SQLite, [][]driver.Value{
+		// SQLite returns numeric columns as integers if they have no fractions.
+		{"2330", "台積電", "2021-03-24", int64(576), float64(576), "576", []byte("576.0000"), int64(1000), int64(3), int64(576000)},
+		{"2330", "", []byte("2021-03-25"), []byte("580.0000"), "585.5", "579", "583", int64(10), int64(1), int64(5830)},
+	})
+
+	qs, err := s.DayQuotes(store.MarketTWSE, "2330", calendar.Date(2021, 3, 24), calendar.Date(2021, 3, 25))
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Day{
+		dayQuote("2330", "台積電", calendar.Date(2021, 3, 24), "576.00"),
+		{
+			Code:         "2330",
+			Date:         calendar.Date(2021, 3, 25),
+			Open:         price.MustParse("580.00"),
+			High:         price.MustParse("585.50"),
+			Low:          price.MustParse("579.00"),
+			Close:        price.MustParse("583.00"),
+			Volume:       10,
+			Transactions: 1,
+			Value:        5830,
+		},
+	}, qs)
+
+	assert.True(t, strings.HasPrefix(d.queries[0].query, "SELECT code, name, date,"))
+	assert.Contains(t, d.queries[0].query, "WHERE market = ? AND code = ? AND date >= ? AND date <= ?")
+	assert.Equal(t, []driver.Value{"twse", "2330", "2021-03-24", "2021-03-25"}, d.queries[0].args)
+}
+
+func TestSQLStore_YearlyQuotes(t *testing.T) {
+	// PostgreSQL drivers return DATE as time.Time.
+	s, d := newFakeStore(t, store.PostgreSQL, [][]driver.Value{
+		{"0050", int64(2020), []byte("122.4000"), []byte("67.2500"),
+			time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC), nil, int64(1), int64(2), int64(3)},
+	})
+
+	qs, err := s.YearlyQuotes(store.MarketTWSE, "0050")
+	assert.Nil(t, err)
+	assert.Equal(t, []quote.Yearly{{
+		Code:         "0050",
+		Year:         2020,
+		High:         price.MustParse("122.40"),
+		Low:          price.MustParse("67.25"),
+		DateOfHigh:   calendar.Date(2020, time.December, 31),
+		Volume:       1,
+		Transactions: 2,
+		Value:        3,
+	}}, qs)
+	assert.Contains(t, d.queries[0].query, "WHERE market = $1 AND code = $2")
+}
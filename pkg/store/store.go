@@ -0,0 +1,46 @@
+// Package store persists quotes of the TWSE and the TPEx, and keeps them up to date incrementally.
+//
+// Store is implemented by SQLStore, backed by database/sql, and MemoryStore, which is handy in tests:
+//
+//     db, _ := sql.Open("sqlite3", "quotes.db")
+//     s := store.NewSQLStore(db, store.SQLite)
+//     _ = s.CreateSchema()
+//
+//     syncer := store.NewSyncer(s, twse.NewClient(time.Second*2), tpex.NewClient(time.Second))
+//     n, _ := syncer.Sync("2330", from, to)
+//
+// Quotes are keyed by market, code and date, so putting the same quotes again updates them in place.
+// Day quotes of all stocks, from FetchDayQuotes, and daily quotes of a stock, from FetchDailyQuotes,
+// share the same table, since they are both quote.Day.
+package store
+
+import (
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// Markets.
+const (
+	MarketTWSE = "twse"
+	MarketTPEx = "tpex"
+)
+
+// Store saves and loads quotes. Puts are idempotent; a quote replaces the stored one of the same market,
+// code and date, except that an empty Name of a day quote keeps the stored name, since the daily quotes
+// of the TWSE come without names.
+type Store interface {
+	PutDayQuotes(market string, qs []quote.Day) error
+	PutMonthlyQuotes(market string, qs []quote.Monthly) error
+	PutYearlyQuotes(market string, qs []quote.Yearly) error
+
+	// DayQuotes returns the day quotes of a stock between from and to inclusively, in ascending order of
+	// dates.
+	DayQuotes(market, code string, from, to time.Time) ([]quote.Day, error)
+	// DayQuotesOn returns the day quotes of all stocks on date, keyed by codes.
+	DayQuotesOn(market string, date time.Time) (map[string]quote.Day, error)
+	// MonthlyQuotes returns the monthly quotes of a stock in the year, in ascending order of months.
+	MonthlyQuotes(market, code string, year int) ([]quote.Monthly, error)
+	// YearlyQuotes returns the yearly quotes of a stock, in ascending order of years.
+	YearlyQuotes(market, code string) ([]quote.Yearly, error)
+}
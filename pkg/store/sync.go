@@ -0,0 +1,115 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/tpex"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// DailyFetcher fetches the daily quotes of a stock in a month. Both twse.Client and tpex.Client
+// implement it.
+type DailyFetcher interface {
+	FetchDailyQuotes(code string, year int, month time.Month) ([]quote.Day, error)
+}
+
+// Source is a market to sync quotes from.
+type Source struct {
+	Market  string
+	Fetcher DailyFetcher
+}
+
+// Syncer fills the gaps of the daily quotes in a Store.
+type Syncer struct {
+	Store Store
+	// Calendar decides which days are expected to have quotes.
+	Calendar *calendar.Calendar
+	// Sources are tried in order until one of them has quotes of the code.
+	Sources []Source
+}
+
+// NewSyncer returns a Syncer syncing from the TWSE and then the TPEx, with the holiday schedule of the
+// TWSE.
+func NewSyncer(s Store, twseClient *twse.Client, tpexClient *tpex.Client) *Syncer {
+	return &Syncer{
+		Store:    s,
+		Calendar: calendar.New(twseClient),
+		Sources: []Source{
+			{Market: MarketTWSE, Fetcher: twseClient},
+			{Market: MarketTPEx, Fetcher: tpexClient},
+		},
+	}
+}
+
+// Sync fetches the daily quotes of a stock in the months having trading days between from and to
+// inclusively but no stored quotes, and puts them into the store. It returns the number of quotes put.
+//
+// The market is the one already having quotes of the code in the store, or the first source returning
+// quotes. Since quotes are fetched by month, quotes outside the range may be put as well. Days on which
+// the stock was suspended from trading have no quotes, so their months are fetched again on every
+// sync.
+func (s *Syncer) Sync(code string, from, to time.Time) (int, error) {
+	days, err := s.Calendar.TradingDaysBetween(from, to)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list trading days: %w", err)
+	}
+	if len(days) == 0 {
+		return 0, nil
+	}
+
+	sources := s.Sources
+	stored := map[string]bool{}
+	for i, source := range s.Sources {
+		qs, err := s.Store.DayQuotes(source.Market, code, days[0], days[len(days)-1])
+		if err != nil {
+			return 0, err
+		}
+		if len(qs) == 0 {
+			continue
+		}
+
+		sources = s.Sources[i : i+1]
+		for _, q := range qs {
+			stored[q.Date.Format(dateLayout)] = true
+		}
+		break
+	}
+
+	months := make([]time.Time, 0)
+	for _, day := range days {
+		if stored[day.Format(dateLayout)] {
+			continue
+		}
+
+		month := calendar.Date(day.Year(), day.Month(), 1)
+		if len(months) == 0 || !months[len(months)-1].Equal(month) {
+			months = append(months, month)
+		}
+	}
+
+	n := 0
+	for _, month := range months {
+		for i, source := range sources {
+			qs, err := source.Fetcher.FetchDailyQuotes(code, month.Year(), month.Month())
+			if err != nil {
+				return n, fmt.Errorf("failed to fetch daily quotes of %s in %s from %s: %w",
+					code, month.Format("2006-01"), source.Market, err)
+			}
+			if len(qs) == 0 {
+				continue
+			}
+
+			if err := s.Store.PutDayQuotes(source.Market, qs); err != nil {
+				return n, err
+			}
+			n += len(qs)
+			sources = sources[i : i+1]
+			break
+		}
+	}
+
+	return n, nil
+}
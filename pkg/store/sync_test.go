@@ -0,0 +1,121 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+	"github.com/chehsunliu/tshakutshai/pkg/store"
+)
+
+type fakeFetcher struct {
+	quotes map[string][]quote.Day
+	err    error
+	calls  []string
+}
+
+func (f *fakeFetcher) FetchDailyQuotes(code string, year int, month time.Month) ([]quote.Day, error) {
+	key := code + "/" + calendar.Date(year, month, 1).Format("200601")
+	f.calls = append(f.calls, key)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.quotes[key], nil
+}
+
+func newSyncer(s store.Store, twseFetcher, tpexFetcher *fakeFetcher) *store.Syncer {
+	cal := calendar.New(nil)
+	cal.Load(2021, []calendar.Holiday{
+		{Date: calendar.Date(2021, 2, 10), Name: "春節"},
+		{Date: calendar.Date(2021, 2, 11), Name: "春節"},
+		{Date: calendar.Date(2021, 2, 12), Name: "春節"},
+		{Date: calendar.Date(2021, 2, 15), Name: "春節"},
+		{Date: calendar.Date(2021, 2, 16), Name: "春節"},
+	})
+
+	return &store.Syncer{
+		Store:    s,
+		Calendar: cal,
+		Sources: []store.Source{
+			{Market: store.MarketTWSE, Fetcher: twseFetcher},
+			{Market: store.MarketTPEx, Fetcher: tpexFetcher},
+		},
+	}
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	february := make([]quote.Day, 0)
+	for _, day := range []int{1, 2, 3, 4, 5, 8, 9, 17, 18, 19, 22, 23, 24, 25, 26} {
+		february = append(february, dayQuote("2330", "", calendar.Date(2021, 2, day), "600.00"))
+	}
+
+	twseFetcher := &fakeFetcher{quotes: map[string][]quote.Day{
+		"2330/202102": february,
+		"2330/202103": {dayQuote("2330", "", calendar.Date(2021, 3, 1), "606.00")},
+	}}
+	tpexFetcher := &fakeFetcher{}
+	s := store.NewMemoryStore()
+	syncer := newSyncer(s, twseFetcher, tpexFetcher)
+
+	n, err := syncer.Sync("2330", calendar.Date(2021, 2, 9), calendar.Date(2021, 2, 17))
+	assert.Nil(t, err)
+	assert.Equal(t, 15, n)
+	assert.Equal(t, []string{"2330/202102"}, twseFetcher.calls)
+	assert.Equal(t, 0, len(tpexFetcher.calls))
+
+	// All the trading days are stored, so nothing is fetched.
+	n, err = syncer.Sync("2330", calendar.Date(2021, 2, 9), calendar.Date(2021, 2, 17))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, []string{"2330/202102"}, twseFetcher.calls)
+
+	// Only March is missing.
+	n, err = syncer.Sync("2330", calendar.Date(2021, 2, 17), calendar.Date(2021, 3, 1))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"2330/202102", "2330/202103"}, twseFetcher.calls)
+
+	qs, err := s.DayQuotes(store.MarketTWSE, "2330", calendar.Date(2021, 2, 1), calendar.Date(2021, 3, 31))
+	assert.Nil(t, err)
+	assert.Equal(t, 16, len(qs))
+}
+
+func TestSyncer_SyncFallsBackToTPEx(t *testing.T) {
+	twseFetcher := &fakeFetcher{}
+	tpexFetcher := &fakeFetcher{quotes: map[string][]quote.Day{
+		"8044/202101": {dayQuote("8044", "網家", calendar.Date(2021, 1, 29), "90.00")},
+		"8044/202102": {dayQuote("8044", "網家", calendar.Date(2021, 2, 1), "86.10")},
+	}}
+	s := store.NewMemoryStore()
+	syncer := newSyncer(s, twseFetcher, tpexFetcher)
+
+	n, err := syncer.Sync("8044", calendar.Date(2021, 1, 29), calendar.Date(2021, 2, 1))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"8044/202101"}, twseFetcher.calls)
+	assert.Equal(t, []string{"8044/202101", "8044/202102"}, tpexFetcher.calls)
+
+	// The market is known from the stored quotes now.
+	n, err = syncer.Sync("8044", calendar.Date(2021, 1, 29), calendar.Date(2021, 2, 2))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"8044/202101"}, twseFetcher.calls)
+	assert.Equal(t, []string{"8044/202101", "8044/202102", "8044/202102"}, tpexFetcher.calls)
+}
+
+func TestSyncer_SyncWithError(t *testing.T) {
+	twseFetcher := &fakeFetcher{err: errors.New("banned")}
+	syncer := newSyncer(store.NewMemoryStore(), twseFetcher, &fakeFetcher{})
+
+	_, err := syncer.Sync("2330", calendar.Date(2021, 2, 9), calendar.Date(2021, 2, 17))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "banned")
+
+	n, err := syncer.Sync("2330", calendar.Date(2021, 2, 13), calendar.Date(2021, 2, 14))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+}
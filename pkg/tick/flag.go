@@ -0,0 +1,63 @@
+package tick
+
+import (
+	"time"
+
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/quote"
+)
+
+// Bar is the prices of a security in a trading day.
+type Bar struct {
+	Date  time.Time
+	High  price.Price
+	Low   price.Price
+	Close price.Price
+}
+
+// BarsFromDayQuotes converts the quotes returned by FetchDailyQuotes of twse.Client and tpex.Client to
+// bars.
+func BarsFromDayQuotes(qs []quote.Day) []Bar {
+	bars := make([]Bar, len(qs))
+	for i, q := range qs {
+		bars[i] = Bar{Date: q.Date, High: q.High, Low: q.Low, Close: q.Close}
+	}
+
+	return bars
+}
+
+// Flag is the Touch of a Bar against the limits of its trading day.
+type Flag struct {
+	Date   time.Time
+	Limits Limits
+	Touch
+}
+
+// FlagBars returns the flags of consecutive daily bars, whose limits are derived from the close of the
+// previous bar. The first bar only serves as the reference of the second one, so len(bars)-1 flags are
+// returned. A bar without transactions has no close and carries the reference price forward.
+//
+// The reference price is not adjusted for ex-rights and ex-dividend days, on which the exchanges
+// lower it, so flags on those days may be inaccurate.
+func FlagBars(bars []Bar, t SecurityType) []Flag {
+	flags := make([]Flag, 0)
+	if len(bars) == 0 {
+		return flags
+	}
+
+	reference := bars[0].Close
+	for _, bar := range bars[1:] {
+		if reference != 0 {
+			l := LimitsOf(reference, t)
+			flags = append(flags, Flag{Date: bar.Date, Limits: l, Touch: l.Touch(bar.High, bar.Low, bar.Close)})
+		} else {
+			flags = append(flags, Flag{Date: bar.Date})
+		}
+
+		if bar.Close != 0 {
+			reference = bar.Close
+		}
+	}
+
+	return flags
+}
@@ -0,0 +1,39 @@
+package tick_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/calendar"
+	"github.com/chehsunliu/tshakutshai/pkg/client/twse"
+	"github.com/chehsunliu/tshakutshai/pkg/tick"
+)
+
+func TestFlagBars(t *testing.T) {
+	day := func(d int) time.Time { return calendar.Date(2021, time.March, d) }
+
+	bars := tick.BarsFromDayQuotes([]twse.DayQuote{
+		{Date: day(1), High: p("10.00"), Low: p("9.50"), Close: p("10.00")},
+		{Date: day(2), High: p("11.00"), Low: p("10.00"), Close: p("11.00")},
+		{Date: day(3)},
+		{Date: day(4), High: p("12.00"), Low: p("9.90"), Close: p("10.00")},
+	})
+
+	flags := tick.FlagBars(bars, tick.Stock)
+	assert.Equal(t, 3, len(flags))
+
+	assert.Equal(t, day(2), flags[0].Date)
+	assert.Equal(t, p("11.00"), flags[0].Limits.Up)
+	assert.Equal(t, tick.Touch{TouchedUp: true, ClosedUp: true}, flags[0].Touch)
+
+	assert.False(t, flags[1].Any())
+
+	assert.Equal(t, p("11.00"), flags[2].Limits.Reference)
+	assert.Equal(t, p("12.10"), flags[2].Limits.Up)
+	assert.Equal(t, p("9.90"), flags[2].Limits.Down)
+	assert.Equal(t, tick.Touch{TouchedDown: true}, flags[2].Touch)
+
+	assert.Equal(t, []tick.Flag{}, tick.FlagBars(nil, tick.Stock))
+}
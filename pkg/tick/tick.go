@@ -0,0 +1,212 @@
+// Package tick models the tick-size and price-limit rules of the Taiwan exchanges, which are shared by
+// the TWSE and the TPEx.
+//
+// Compute the limit prices of the next trading day from a previous close:
+//
+//     qs, _ := client.FetchDailyQuotes("2330", 2021, time.February)
+//     l := tick.LimitsOf(qs[len(qs)-1].Close, tick.Stock)
+//     fmt.Println(l.Up, l.Down)
+//
+// and move a limit order by ticks:
+//
+//     p := tick.Up(price.MustParse("9.99"), tick.Stock) // 10.00
+//     p = tick.Up(p, tick.Stock)                         // 10.05
+package tick
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+)
+
+// SecurityType determines the tick-size table of a security.
+type SecurityType int
+
+const (
+	// Stock includes common stocks, preferred stocks and depositary receipts.
+	Stock SecurityType = iota
+	// ETF includes exchange-traded funds and exchange-traded notes.
+	ETF
+)
+
+func (t SecurityType) String() string {
+	switch t {
+	case Stock:
+		return "Stock"
+	case ETF:
+		return "ETF"
+	default:
+		return fmt.Sprintf("SecurityType(%d)", int(t))
+	}
+}
+
+// LimitPercent is the daily price limit in percent of the reference price.
+const LimitPercent = 10
+
+// band is a price range [previous band's below, below) sharing the same tick size.
+type band struct {
+	below price.Price
+	size  price.Price
+}
+
+var (
+	stockBands = []band{
+		{price.FromInt(10), price.MustParse("0.01")},
+		{price.FromInt(50), price.MustParse("0.05")},
+		{price.FromInt(100), price.MustParse("0.1")},
+		{price.FromInt(500), price.MustParse("0.5")},
+		{price.FromInt(1000), price.FromInt(1)},
+		{price.Price(math.MaxInt64), price.FromInt(5)},
+	}
+	etfBands = []band{
+		{price.FromInt(50), price.MustParse("0.01")},
+		{price.Price(math.MaxInt64), price.MustParse("0.05")},
+	}
+)
+
+func bandsOf(t SecurityType) []band {
+	switch t {
+	case Stock:
+		return stockBands
+	case ETF:
+		return etfBands
+	default:
+		panic(fmt.Sprintf("unknown security type %v", t))
+	}
+}
+
+// Size returns the tick size of p, i.e. the price step for prices at or above p in the same band. For
+// example, the tick size of a stock priced at 10.00 is 0.05.
+func Size(p price.Price, t SecurityType) price.Price {
+	for _, b := range bandsOf(t) {
+		if p < b.below {
+			return b.size
+		}
+	}
+
+	panic("unreachable")
+}
+
+// sizeBelow returns the tick size of prices right below p, e.g. 0.01 for a stock priced at 10.00.
+func sizeBelow(p price.Price, t SecurityType) price.Price {
+	for _, b := range bandsOf(t) {
+		if p <= b.below {
+			return b.size
+		}
+	}
+
+	panic("unreachable")
+}
+
+// IsValid reports whether p is a positive price on the tick grid.
+func IsValid(p price.Price, t SecurityType) bool {
+	return p > 0 && p%Size(p, t) == 0
+}
+
+// RoundDown returns the greatest valid price not greater than p.
+func RoundDown(p price.Price, t SecurityType) price.Price {
+	size := Size(p, t)
+	return p - p%size
+}
+
+// RoundUp returns the least valid price not less than p.
+func RoundUp(p price.Price, t SecurityType) price.Price {
+	size := Size(p, t)
+	if r := p % size; r != 0 {
+		return p - r + size
+	}
+
+	return p
+}
+
+// Up returns the next valid price above p.
+func Up(p price.Price, t SecurityType) price.Price {
+	q := RoundUp(p, t)
+	if q != p {
+		return q
+	}
+
+	return p + Size(p, t)
+}
+
+// Down returns the next valid price below p. The result is not positive if there is no valid price
+// below p.
+func Down(p price.Price, t SecurityType) price.Price {
+	q := RoundDown(p, t)
+	if q != p {
+		return q
+	}
+
+	return p - sizeBelow(p, t)
+}
+
+// Limits is the range of valid prices in a trading day.
+type Limits struct {
+	// Reference is the reference price, which is usually the close of the previous trading day.
+	Reference price.Price
+	// Up is the limit-up price, the highest valid price.
+	Up price.Price
+	// Down is the limit-down price, the lowest valid price.
+	Down price.Price
+
+	securityType SecurityType
+}
+
+// LimitsOf returns the limits of the trading day whose reference price is reference. The limit prices
+// are LimitPercent away from reference and rounded towards reference onto the tick grid.
+func LimitsOf(reference price.Price, t SecurityType) Limits {
+	return Limits{
+		Reference:    reference,
+		Up:           RoundDown(reference.MulRatio(100+LimitPercent, 100), t),
+		Down:         RoundUp(reference.MulRatio(100-LimitPercent, 100), t),
+		securityType: t,
+	}
+}
+
+// Contains reports whether p is within the limits, inclusively.
+func (l Limits) Contains(p price.Price) bool {
+	return l.Down <= p && p <= l.Up
+}
+
+// Validate returns an error if a limit order at p is rejected for not being on the tick grid or out of
+// the limits.
+func (l Limits) Validate(p price.Price) error {
+	if !IsValid(p, l.securityType) {
+		return fmt.Errorf("%v is not a multiple of the tick size %v", p, Size(p, l.securityType))
+	}
+
+	if !l.Contains(p) {
+		return fmt.Errorf("%v is out of the limits [%v, %v]", p, l.Down, l.Up)
+	}
+
+	return nil
+}
+
+// Touch tells how the prices of a trading day reached the limits.
+type Touch struct {
+	// TouchedUp is true if the highest price reached the limit-up price.
+	TouchedUp bool
+	// TouchedDown is true if the lowest price reached the limit-down price.
+	TouchedDown bool
+	// ClosedUp is true if the close is the limit-up price.
+	ClosedUp bool
+	// ClosedDown is true if the close is the limit-down price.
+	ClosedDown bool
+}
+
+// Any reports whether any limit is touched.
+func (t Touch) Any() bool {
+	return t.TouchedUp || t.TouchedDown
+}
+
+// Touch returns how the high, low and close of a trading day reached the limits. Zero prices, which
+// mean no transactions were made, never touch the limits.
+func (l Limits) Touch(high, low, close price.Price) Touch {
+	return Touch{
+		TouchedUp:   high != 0 && high >= l.Up,
+		TouchedDown: low != 0 && low <= l.Down,
+		ClosedUp:    close != 0 && close >= l.Up,
+		ClosedDown:  close != 0 && close <= l.Down,
+	}
+}
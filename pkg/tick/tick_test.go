@@ -0,0 +1,101 @@
+package tick_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chehsunliu/tshakutshai/pkg/price"
+	"github.com/chehsunliu/tshakutshai/pkg/tick"
+)
+
+var p = price.MustParse
+
+func TestSize(t *testing.T) {
+	for _, tc := range []struct {
+		p        string
+		t        tick.SecurityType
+		expected string
+	}{
+		{"9.99", tick.Stock, "0.01"},
+		{"10", tick.Stock, "0.05"},
+		{"49.95", tick.Stock, "0.05"},
+		{"50", tick.Stock, "0.1"},
+		{"100", tick.Stock, "0.5"},
+		{"576", tick.Stock, "1"},
+		{"1000", tick.Stock, "5"},
+		{"49.99", tick.ETF, "0.01"},
+		{"131.5", tick.ETF, "0.05"},
+	} {
+		assert.Equalf(t, p(tc.expected), tick.Size(p(tc.p), tc.t), "%s %v", tc.p, tc.t)
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	assert.True(t, tick.IsValid(p("48.35"), tick.Stock))
+	assert.False(t, tick.IsValid(p("48.37"), tick.Stock))
+	assert.True(t, tick.IsValid(p("48.37"), tick.ETF))
+	assert.False(t, tick.IsValid(p("576.5"), tick.Stock))
+	assert.False(t, tick.IsValid(p("0"), tick.Stock))
+}
+
+func TestUpAndDown(t *testing.T) {
+	for _, tc := range []struct {
+		p, up, down string
+	}{
+		{"9.99", "10.00", "9.98"},
+		{"10.00", "10.05", "9.99"},
+		{"10.03", "10.05", "10.00"},
+		{"50.00", "50.10", "49.95"},
+		{"100.00", "100.50", "99.90"},
+		{"500.00", "501.00", "499.50"},
+		{"1000.00", "1005.00", "999.00"},
+	} {
+		assert.Equalf(t, p(tc.up), tick.Up(p(tc.p), tick.Stock), "up %s", tc.p)
+		assert.Equalf(t, p(tc.down), tick.Down(p(tc.p), tick.Stock), "down %s", tc.p)
+	}
+
+	assert.Equal(t, p("50.05"), tick.Up(p("50.00"), tick.ETF))
+	assert.Equal(t, p("49.99"), tick.Down(p("50.00"), tick.ETF))
+}
+
+func TestLimitsOf(t *testing.T) {
+	for _, tc := range []struct {
+		reference string
+		t         tick.SecurityType
+		up, down  string
+	}{
+		{"576.00", tick.Stock, "633.00", "519.00"},
+		{"9.50", tick.Stock, "10.45", "8.55"},
+		{"48.37", tick.Stock, "53.20", "43.55"},
+		{"131.50", tick.ETF, "144.65", "118.35"},
+		{"19.49", tick.ETF, "21.43", "17.55"},
+		{"39.90", tick.ETF, "43.89", "35.91"},
+	} {
+		l := tick.LimitsOf(p(tc.reference), tc.t)
+		assert.Equalf(t, p(tc.up), l.Up, "up of %s", tc.reference)
+		assert.Equalf(t, p(tc.down), l.Down, "down of %s", tc.reference)
+		assert.True(t, tick.IsValid(l.Up, tc.t))
+		assert.True(t, tick.IsValid(l.Down, tc.t))
+	}
+}
+
+func TestLimits_Validate(t *testing.T) {
+	l := tick.LimitsOf(p("576.00"), tick.Stock)
+
+	assert.Nil(t, l.Validate(p("600")))
+	assert.Nil(t, l.Validate(p("633")))
+	assert.NotNil(t, l.Validate(p("600.5")))
+	assert.NotNil(t, l.Validate(p("634")))
+	assert.NotNil(t, l.Validate(p("518")))
+}
+
+func TestLimits_Touch(t *testing.T) {
+	l := tick.LimitsOf(p("576.00"), tick.Stock)
+
+	assert.Equal(t, tick.Touch{}, l.Touch(p("600"), p("570"), p("590")))
+	assert.Equal(t, tick.Touch{TouchedUp: true, ClosedUp: true}, l.Touch(p("633"), p("600"), p("633")))
+	assert.Equal(t, tick.Touch{TouchedUp: true, TouchedDown: true}, l.Touch(p("633"), p("519"), p("580")))
+	assert.Equal(t, tick.Touch{}, l.Touch(0, 0, 0))
+	assert.True(t, l.Touch(p("633"), p("600"), p("610")).Any())
+}